@@ -0,0 +1,105 @@
+// Command apikey manages API keys for the server.
+//
+//	apikey create -name billing -roles reader,writer
+//	apikey list
+//	apikey revoke -name billing
+//
+// The key itself is printed once by create; only its hash is stored.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/config"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	name := flags.String("name", "", "API key name, used as the audit actor")
+	roles := flags.String("roles", auth.RoleReader, "comma-separated roles: checker, reader, writer, admin")
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	cfg, err := config.Load(flags, os.Args[2:], os.Getenv)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := repository.InitDB(cfg.Database.DSN())
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewAPIKeyRepository(db)
+
+	switch os.Args[1] {
+	case "create":
+		if *name == "" {
+			log.Fatal("-name is required")
+		}
+		roleList := strings.Split(*roles, ",")
+		for i, role := range roleList {
+			roleList[i] = strings.TrimSpace(role)
+			if !auth.ValidRole(roleList[i]) {
+				log.Fatalf("Unknown role %q", roleList[i])
+			}
+		}
+
+		key, err := auth.GenerateAPIKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := repo.Create(*name, auth.HashAPIKey(key), roleList); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(key)
+
+	case "list":
+		keys, err := repo.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, key := range keys {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked " + key.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-20s %-30s created %s, %s\n", key.Name, strings.Join(key.Roles, ","),
+				key.CreatedAt.Format("2006-01-02 15:04:05"), status)
+		}
+
+	case "revoke":
+		if *name == "" {
+			log.Fatal("-name is required")
+		}
+		if err := repo.Revoke(*name); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("API key %q revoked", *name)
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey create -name NAME [-roles ROLES] | list | revoke -name NAME")
+	os.Exit(2)
+}
@@ -0,0 +1,68 @@
+// Command reencrypt re-encrypts people with the active encryption key.
+//
+// Run it after adding a new key to ENCRYPTION_KEYS and making it
+// ENCRYPTION_ACTIVE_KEY; once it finishes, old keys can be removed. It also
+// encrypts rows stored before encryption was enabled. Pass -all to process
+// every row, for example after changing IIN_HMAC_KEY.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/toleubekov/check-iin-kaz/internal/config"
+	"github.com/toleubekov/check-iin-kaz/internal/fieldcrypt"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+func main() {
+	all := flag.Bool("all", false, "re-encrypt every row, not only rows on an old key")
+	batchSize := flag.Int("batch", 500, "rows per transaction")
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := repository.InitDB(cfg.Database.DSN())
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	keyring, err := fieldcrypt.LoadKeyring(cfg.Encryption.Keys, cfg.Encryption.ActiveKey, cfg.Encryption.IINHMACKey)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+
+	personRepo := repository.NewPersonRepository(db, keyring)
+	log.Printf("Re-encrypting people with key %q", keyring.ActiveKeyID())
+
+	total := 0
+	var lastID int64
+	for {
+		processed, nextID, err := personRepo.Reencrypt(context.Background(), lastID, *batchSize, *all)
+		if err != nil {
+			log.Fatalf("Re-encryption stopped after %d rows: %v", total, err)
+		}
+		if processed == 0 {
+			break
+		}
+
+		total += processed
+		lastID = nextID
+		log.Printf("Re-encrypted %d rows (last id %d)", total, lastID)
+	}
+
+	log.Printf("Re-encryption completed: %d rows", total)
+}
@@ -1,15 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/rsa"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/toleubekov/check-iin-kaz/iin"
 	"github.com/toleubekov/check-iin-kaz/internal/api"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+	"github.com/toleubekov/check-iin-kaz/internal/config"
+	"github.com/toleubekov/check-iin-kaz/internal/fieldcrypt"
+	grpcapi "github.com/toleubekov/check-iin-kaz/internal/grpc"
+	"github.com/toleubekov/check-iin-kaz/internal/jobs"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
 	"github.com/toleubekov/check-iin-kaz/internal/repository"
 	"github.com/toleubekov/check-iin-kaz/internal/service"
+	"github.com/toleubekov/check-iin-kaz/internal/tracing"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -17,42 +38,254 @@ func main() {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "qwerty")
-	dbName := getEnv("DB_NAME", "postgres")
-	dbHost := getEnv("DB_HOST", "postgres")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbSSLMode := getEnv("DB_SSLMODE", "disable")
-
-	dbConnectionString := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode,
-	)
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	printMigrateURL := flags.Bool("print-migrate-url", false, "print the database URL for golang-migrate, including the password, and exit")
+	cfg, err := config.Load(flags, os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *printConfig {
+		if err := cfg.PrintYAML(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *printMigrateURL {
+		fmt.Println(cfg.Database.MigrateURL())
+		return
+	}
 
-	db, err := repository.InitDB(dbConnectionString)
+	db, err := repository.ConnectDB(cfg.Database.DSN(), cfg.Database.ConnectAttempts)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	personRepo := repository.NewPersonRepository(db)
-	iinService := service.NewIINService()
-	handler := api.NewHandler(iinService, personRepo)
+	keyring, err := fieldcrypt.LoadKeyring(cfg.Encryption.Keys, cfg.Encryption.ActiveKey, cfg.Encryption.IINHMACKey)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+
+	personRepo := repository.NewPersonRepository(db, keyring)
+	if unencrypted, err := personRepo.CountUnencrypted(); err == nil && unencrypted > 0 {
+		log.Printf("Warning: %d people are stored unencrypted, run cmd/reencrypt to encrypt them", unencrypted)
+	}
+
+	jobRepo := repository.NewJobRepository(db)
+	iinService := service.NewIINServiceWithOptions(iin.Options{AllowNonCitizen: cfg.Validation.AllowNonCitizen})
+	auditLogger := audit.NewLogger(repository.NewAuditRepository(db), []byte(cfg.Audit.HashKey))
 
-	router := api.SetupRouter(handler)
+	// Workers get their own context: they are stopped only after the HTTP
+	// server has drained, and requeue whatever job they were running.
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	jobManager := jobs.NewManager(jobRepo, personRepo, iinService, auditLogger, cfg.Jobs.Workers)
+	jobManager.Start(jobCtx)
 
-	port := getEnv("SERVER_PORT", "8080")
+	idempotencyRepo := repository.NewIdempotencyRepository(db, cfg.Idempotency.TTL)
+	go purgeIdempotencyKeys(idempotencyRepo)
+
+	handler := api.NewHandler(iinService, personRepo, jobManager, idempotencyRepo, auditLogger)
+
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = newAuthenticator(cfg.Auth, repository.NewAPIKeyRepository(db))
+		if err != nil {
+			log.Fatalf("Failed to configure authentication: %v", err)
+		}
+	} else {
+		log.Println("Warning: AUTH_ENABLED=false, the API is open to anyone")
+	}
+
+	limits := map[string]ratelimit.Limit{}
+	if cfg.RateLimit.Limits != "off" {
+		limits, err = ratelimit.ParseLimits(cfg.RateLimit.Limits)
+		if err != nil {
+			log.Fatalf("Failed to parse RATE_LIMITS: %v", err)
+		}
+	}
+	quotaRepo := repository.NewQuotaRepository(db)
+	limiter := ratelimit.NewLimiter(limits, quotaRepo, cfg.RateLimit.DailyQuota)
+	go purgeQuotaUsage(quotaRepo)
+
+	tracer, closeTracer, err := newTracer(cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to configure tracing: %v", err)
+	}
+	defer closeTracer()
+
+	var accessLog *slog.Logger
+	if cfg.Logging.AccessLog {
+		accessLog = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+
+	var captureWriter *capture.Writer
+	if cfg.Capture.File != "" {
+		var captureFile *os.File
+		captureWriter, captureFile, err = capture.OpenFile(cfg.Capture.File)
+		if err != nil {
+			log.Fatalf("Failed to open capture file: %v", err)
+		}
+		defer captureFile.Close()
+		captureWriter.MaskIINs = cfg.Capture.MaskIIN
+		captureWriter.MaxBody = cfg.Capture.MaxBody
+		log.Printf("Capturing API requests to %s", cfg.Capture.File)
+	}
+
+	router := api.SetupRouter(handler, api.RouterOptions{
+		Authenticator: authenticator,
+		Limiter:       limiter,
+		AccessLog:     accessLog,
+		Tracer:        tracer,
+		Capture:       captureWriter,
+	})
+
+	health := api.NewHealthHandler(api.PostgresSchemaChecker(db))
+	metrics.RegisterDBStats(db.DB)
+	mux := api.NewServeMux(router, health)
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
 
-	log.Printf("Server is running on port %s...", port)
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server is running on %s...", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != 0 {
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.Server.GRPCPort))
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		grpcServer = grpcapi.NewServer(iinService, personRepo, auditLogger, grpcapi.Options{
+			Authenticator: authenticator,
+			Limiter:       limiter,
+		})
+		go func() {
+			log.Printf("gRPC server is running on %s...", listener.Addr())
+			serverErr <- grpcServer.Serve(listener)
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", shutdownTimeout)
+	health.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if grpcServer != nil {
+		go func() {
+			<-shutdownCtx.Done()
+			grpcServer.Stop()
+		}()
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: Graceful shutdown did not finish: %v", err)
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	stopJobs()
+	jobManager.Wait()
+	limiter.Flush()
+	auditLogger.Close()
+	log.Println("Server stopped")
+}
+
+// newAuthenticator accepts API keys and, when an HS256 secret or an RS256
+// public key is configured, JWT bearer tokens.
+func newAuthenticator(cfg config.Auth, apiKeys *repository.APIKeyRepository) (*auth.Authenticator, error) {
+	secret := cfg.JWTHS256Secret
+	publicKeyFile := cfg.JWTRS256PublicKeyFile
+	if secret == "" && publicKeyFile == "" {
+		return auth.NewAuthenticator(apiKeys, nil), nil
 	}
+
+	var publicKey *rsa.PublicKey
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		publicKey, err = auth.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	verifier, err := auth.NewJWTVerifier([]byte(secret), publicKey, cfg.JWTIssuer, cfg.JWTAudience)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAuthenticator(apiKeys, verifier), nil
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// newTracer creates the request tracer and a function that closes its output.
+func newTracer(cfg config.Tracing) (*tracing.Tracer, func(), error) {
+	switch cfg.Exporter {
+	case "stdout":
+		return tracing.NewTracer(tracing.NewJSONExporter(os.Stdout, cfg.ServiceName)), func() {}, nil
+	case "file":
+		exporter, file, err := tracing.OpenFileExporter(cfg.File, cfg.ServiceName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tracing.NewTracer(exporter), func() { file.Close() }, nil
+	}
+	return tracing.NewTracer(nil), func() {}, nil
+}
+
+// purgeIdempotencyKeys periodically removes expired Idempotency-Key records.
+func purgeIdempotencyKeys(repo *repository.IdempotencyRepository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := repo.PurgeExpired()
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d expired idempotency keys", purged)
+		}
+	}
+}
+
+// purgeQuotaUsage periodically removes daily quota counters older than a week.
+func purgeQuotaUsage(repo *repository.QuotaRepository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := repo.PurgeBefore(time.Now().UTC().AddDate(0, 0, -7))
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d old quota counters", purged)
+		}
 	}
-	return value
 }
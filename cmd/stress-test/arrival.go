@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage moves the arrival rate linearly from the previous stage's target
+// (or the schedule's start rate) to Target over Duration.
+type Stage struct {
+	Duration time.Duration
+	Target   float64
+}
+
+// ParseStages parses STAGES, a comma-separated list of duration:rate pairs
+// such as "30s:100,2m:100,30s:0" (ramp up, steady, ramp down).
+func ParseStages(value string) ([]Stage, error) {
+	var stages []Stage
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		durationStr, rateStr, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("stage %q: expected duration:rate", part)
+		}
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("stage %q: invalid duration", part)
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("stage %q: invalid rate", part)
+		}
+		stages = append(stages, Stage{Duration: duration, Target: rate})
+	}
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("no stages in %q", value)
+	}
+	return stages, nil
+}
+
+// Schedule is an open-model load profile: requests are sent at the planned
+// rate whether or not earlier ones have completed, so a slow server shows up
+// as latency instead of as fewer requests.
+type Schedule struct {
+	StartRate float64
+	Stages    []Stage
+}
+
+// ConstantSchedule sends rate requests per second for duration.
+func ConstantSchedule(rate float64, duration time.Duration) Schedule {
+	return Schedule{StartRate: rate, Stages: []Stage{{Duration: duration, Target: rate}}}
+}
+
+func (s Schedule) Duration() time.Duration {
+	var total time.Duration
+	for _, stage := range s.Stages {
+		total += stage.Duration
+	}
+	return total
+}
+
+// RateAt returns the planned requests per second at offset into the run.
+func (s Schedule) RateAt(offset time.Duration) float64 {
+	from := s.StartRate
+	for _, stage := range s.Stages {
+		if offset < stage.Duration {
+			progress := float64(offset) / float64(stage.Duration)
+			return from + (stage.Target-from)*progress
+		}
+		offset -= stage.Duration
+		from = stage.Target
+	}
+	return 0
+}
+
+// scheduleStep is the interval over which the rate is taken as constant.
+const scheduleStep = 10 * time.Millisecond
+
+// Each calls send with the planned offset of every request, in order, until
+// the schedule ends or send returns false.
+func (s Schedule) Each(send func(offset time.Duration) bool) {
+	total := s.Duration()
+	// pending is the fraction of the next request accumulated so far.
+	pending := 0.0
+	for t := time.Duration(0); t < total; t += scheduleStep {
+		step := scheduleStep
+		if t+step > total {
+			step = total - t
+		}
+		rate := s.RateAt(t + step/2)
+		if rate <= 0 {
+			continue
+		}
+
+		position := t
+		remaining := step.Seconds()
+		for pending+rate*remaining >= 1 {
+			wait := (1 - pending) / rate
+			position += time.Duration(wait * float64(time.Second))
+			remaining -= wait
+			pending = 0
+			if !send(position) {
+				return
+			}
+		}
+		pending += rate * remaining
+	}
+}
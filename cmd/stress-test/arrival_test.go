@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStages(t *testing.T) {
+	stages, err := ParseStages("30s:100, 2m:100,30s:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stage{{30 * time.Second, 100}, {2 * time.Minute, 100}, {30 * time.Second, 0}}
+	if len(stages) != len(want) {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Errorf("stage %d = %v, want %v", i, stages[i], want[i])
+		}
+	}
+
+	for _, invalid := range []string{"", "30s", "30s:fast", "soon:10", "-1s:10", "10s:-5"} {
+		if _, err := ParseStages(invalid); err == nil {
+			t.Errorf("ParseStages(%q): expected an error", invalid)
+		}
+	}
+}
+
+func count(s Schedule) (n int, last time.Duration) {
+	s.Each(func(offset time.Duration) bool {
+		if offset < last {
+			panic("offsets out of order")
+		}
+		n++
+		last = offset
+		return true
+	})
+	return n, last
+}
+
+func TestConstantSchedule(t *testing.T) {
+	n, last := count(ConstantSchedule(200, 5*time.Second))
+	if n != 1000 {
+		t.Errorf("sent %d requests, want 1000", n)
+	}
+	if last > 5*time.Second {
+		t.Errorf("last request at %v, after the end of the run", last)
+	}
+}
+
+func TestRampSchedule(t *testing.T) {
+	schedule := Schedule{Stages: []Stage{
+		{10 * time.Second, 100},
+		{10 * time.Second, 100},
+		{10 * time.Second, 0},
+	}}
+	if rate := schedule.RateAt(5 * time.Second); rate != 50 {
+		t.Errorf("rate halfway up the ramp = %v, want 50", rate)
+	}
+	// 500 on each ramp plus 1000 in between.
+	if n, _ := count(schedule); n < 1995 || n > 2000 {
+		t.Errorf("sent %d requests, want 2000", n)
+	}
+}
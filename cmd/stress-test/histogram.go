@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math/bits"
+	"time"
+)
+
+// subBuckets is the number of linear buckets per power of two. Recorded
+// values are accurate to within 1/subBuckets (under 1%).
+const subBuckets = 128
+
+// Histogram records latencies in microseconds in the manner of HdrHistogram:
+// values are grouped by power of two, and each power of two is split into
+// subBuckets equal buckets, so memory stays small while the relative error
+// is the same for 1ms and 10s.
+type Histogram struct {
+	counts []int64
+	total  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func bucketIndex(us uint64) int {
+	if us < 2*subBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - bits.Len64(2*subBuckets-1)
+	return shift*subBuckets + int(us>>shift)
+}
+
+// bucketUpperBound returns the highest value that falls into bucket i.
+func bucketUpperBound(i int) uint64 {
+	if i < 2*subBuckets {
+		return uint64(i)
+	}
+	shift := i/subBuckets - 1
+	m := uint64(i - shift*subBuckets)
+	return (m+1)<<shift - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := bucketIndex(uint64(d / time.Microsecond))
+	if i >= len(h.counts) {
+		grown := make([]int64, i+1)
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[i]++
+
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.total++
+	h.sum += d
+}
+
+// Merge adds the values recorded in other.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.total == 0 {
+		return
+	}
+	if len(other.counts) > len(h.counts) {
+		grown := make([]int64, len(other.counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	if h.total == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.total += other.total
+	h.sum += other.sum
+}
+
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// Percentile returns the value below which p percent of the recorded values
+// fall, rounded up to the bucket bound and capped at the maximum.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(p / 100 * float64(h.total))
+	if float64(rank) < p/100*float64(h.total) || rank == 0 {
+		rank++
+	}
+
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			value := time.Duration(bucketUpperBound(i)) * time.Microsecond
+			if value > h.max {
+				return h.max
+			}
+			return value
+		}
+	}
+	return h.max
+}
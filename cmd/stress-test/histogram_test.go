@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsAreContiguous(t *testing.T) {
+	for us := uint64(0); us < 1<<20; us++ {
+		i := bucketIndex(us)
+		if us > bucketUpperBound(i) || (i > 0 && us <= bucketUpperBound(i-1)) {
+			t.Fatalf("%dus is in bucket %d with bounds (%d, %d]", us, i, bucketUpperBound(i-1), bucketUpperBound(i))
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var h Histogram
+	for ms := 1; ms <= 1000; ms++ {
+		h.Record(time.Duration(ms) * time.Millisecond)
+	}
+
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	} {
+		got := h.Percentile(tc.p)
+		if got < tc.want || float64(got-tc.want) > float64(tc.want)/subBuckets {
+			t.Errorf("p%v = %s, want %s within 1%%", tc.p, got, tc.want)
+		}
+	}
+	if h.Min() != time.Millisecond || h.Max() != time.Second {
+		t.Errorf("min, max = %s, %s", h.Min(), h.Max())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var a, b Histogram
+	a.Record(time.Millisecond)
+	b.Record(time.Second)
+	a.Merge(&b)
+	if a.Count() != 2 || a.Min() != time.Millisecond || a.Max() != time.Second {
+		t.Errorf("merged count, min, max = %d, %s, %s", a.Count(), a.Min(), a.Max())
+	}
+}
@@ -1,8 +1,7 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
@@ -13,19 +12,52 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
 )
 
+// exitChecksFailed is the exit code of a completed run that failed its
+// thresholds or regressed against the baseline; other errors exit with 1.
+const exitChecksFailed = 2
+
 type Config struct {
 	ServerURL     string
 	NumGoroutines int
 	NumRequests   int
-}
-
-type Person struct {
-	Name  string `json:"name"`
-	IIN   string `json:"iin"`
-	Phone string `json:"phone"`
+	APIKey        string
+	// ReportFormat is "text" or "json"; the report goes to stdout and the
+	// log lines to stderr, so the JSON can be piped.
+	ReportFormat string
+	// ReportFile, if set, also receives the report as JSON.
+	ReportFile string
+	// Verbose logs every failed request.
+	Verbose bool
+	// ScenarioFile is a YAML or JSON Scenario; without it only people are
+	// created.
+	ScenarioFile string
+	// Duration, if set, runs the closed loop for this long instead of
+	// NumRequests per goroutine; it is also the length of a constant
+	// ArrivalRate run.
+	Duration time.Duration
+	// ArrivalRate switches to the open model: requests are started at this
+	// rate per second regardless of how fast the server answers. With Stages
+	// it is the rate the first stage starts from.
+	ArrivalRate float64
+	// Stages is a ramp profile for the open model, see ParseStages.
+	Stages string
+	// MaxInFlight caps concurrent requests in the open model; requests over
+	// the cap wait, and the wait counts as latency.
+	MaxInFlight int
+	// ThresholdsFile holds SLOs the run must meet, see Thresholds.
+	ThresholdsFile string
+	// BaselineFile is a JSON report of an earlier run to compare against;
+	// MaxRegression is how much worse a metric may get, 0.1 being 10%.
+	BaselineFile  string
+	MaxRegression float64
+	// ReplayFile is a capture file to replay instead of running a scenario,
+	// at ReplaySpeed times the original pace (0 for as fast as possible).
+	ReplayFile  string
+	ReplaySpeed float64
 }
 
 func main() {
@@ -38,110 +70,262 @@ func main() {
 		ServerURL:     getEnv("SERVER_URL", "http://server:8080"),
 		NumGoroutines: getEnvAsInt("NUM_GOROUTINES", 10),
 		NumRequests:   getEnvAsInt("NUM_REQUESTS", 100),
+		APIKey:        getEnv("API_KEY", ""),
+		ReportFormat:  getEnv("REPORT_FORMAT", "text"),
+		ReportFile:    getEnv("REPORT_FILE", ""),
+		Verbose:       getEnv("VERBOSE", "") == "true",
+		ScenarioFile:  getEnv("SCENARIO_FILE", ""),
+		Duration:      getEnvAsDuration("DURATION", 0),
+		ArrivalRate:   getEnvAsFloat("ARRIVAL_RATE", 0),
+		Stages:        getEnv("STAGES", ""),
+		MaxInFlight:   getEnvAsInt("MAX_IN_FLIGHT", 1000),
+
+		ThresholdsFile: getEnv("THRESHOLDS_FILE", ""),
+		BaselineFile:   getEnv("BASELINE_FILE", ""),
+		MaxRegression:  getEnvAsFloat("MAX_REGRESSION", 0.1),
+		ReplayFile:     getEnv("REPLAY_FILE", ""),
+		ReplaySpeed:    getEnvAsFloat("REPLAY_SPEED", 1),
+	}
+	if config.ReportFormat != "text" && config.ReportFormat != "json" {
+		log.Fatalf("Unknown REPORT_FORMAT %q, expected text or json", config.ReportFormat)
 	}
 
-	log.Printf("Starting stress test with %d goroutines, %d requests per goroutine",
-		config.NumGoroutines, config.NumRequests)
+	var schedule *Schedule
+	switch {
+	case config.ReplayFile != "":
+		if config.ReplaySpeed < 0 {
+			log.Fatal("REPLAY_SPEED must not be negative")
+		}
+		log.Printf("Starting replay of %s at %gx speed", config.ReplayFile, config.ReplaySpeed)
+	case config.Stages != "":
+		stages, err := ParseStages(config.Stages)
+		if err != nil {
+			log.Fatalf("Invalid STAGES: %v", err)
+		}
+		schedule = &Schedule{StartRate: config.ArrivalRate, Stages: stages}
+		log.Printf("Starting open-model stress test: %d stages over %s", len(stages), schedule.Duration())
+	case config.ArrivalRate > 0:
+		if config.Duration <= 0 {
+			log.Fatal("ARRIVAL_RATE requires DURATION")
+		}
+		constant := ConstantSchedule(config.ArrivalRate, config.Duration)
+		schedule = &constant
+		log.Printf("Starting open-model stress test: %.1f req/s for %s", config.ArrivalRate, config.Duration)
+	case config.Duration > 0:
+		log.Printf("Starting stress test with %d goroutines for %s", config.NumGoroutines, config.Duration)
+	default:
+		log.Printf("Starting stress test with %d goroutines, %d requests per goroutine",
+			config.NumGoroutines, config.NumRequests)
+	}
+	if (schedule != nil || config.ReplayFile != "") && config.MaxInFlight <= 0 {
+		log.Fatal("MAX_IN_FLIGHT must be positive")
+	}
 	log.Printf("Server URL: %s", config.ServerURL)
 
-	rand.Seed(time.Now().UnixNano())
-
-	var wg sync.WaitGroup
-	wg.Add(config.NumGoroutines)
-
-	for i := 0; i < config.NumGoroutines; i++ {
-		go func(goroutineID int) {
-			defer wg.Done()
-			runStressTest(goroutineID, config)
-		}(i)
+	scenario := DefaultScenario()
+	if config.ScenarioFile != "" {
+		var err error
+		if scenario, err = LoadScenario(config.ScenarioFile); err != nil {
+			log.Fatalf("Failed to load scenario: %v", err)
+		}
+	}
+	if config.ReplayFile == "" {
+		log.Printf("Scenario: %s", scenario.Name)
 	}
 
-	wg.Wait()
-	log.Println("Stress test completed")
-}
-
-func runStressTest(goroutineID int, config Config) {
-	for i := 0; i < config.NumRequests; i++ {
+	// Both are loaded before the run so that a typo does not waste it.
+	var thresholds *Thresholds
+	if config.ThresholdsFile != "" {
+		var err error
+		if thresholds, err = LoadThresholds(config.ThresholdsFile); err != nil {
+			log.Fatalf("Failed to load thresholds: %v", err)
+		}
+	}
+	var baseline *Report
+	if config.BaselineFile != "" {
+		var err error
+		if baseline, err = LoadReport(config.BaselineFile); err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+	}
 
-		iin := generateRandomIIN(i%10 != 0)
+	rand.Seed(time.Now().UnixNano())
 
-		person := Person{
-			Name:  fmt.Sprintf("Test Person %d-%d", goroutineID, i),
-			IIN:   iin,
-			Phone: fmt.Sprintf("+7%09d", rand.Intn(1000000000)),
-		}
+	apiClient := client.New(config.ServerURL, config.APIKey)
+	apiClient.HTTPClient.Timeout = 5 * time.Second
+	apiClient.HTTPClient.Transport = statusTransport{next: http.DefaultTransport}
 
-		err := createPerson(config.ServerURL, person)
+	recorder := NewRecorder()
+	var replayer *Replayer
+	if config.ReplayFile != "" {
+		records, err := capture.ReadFile(config.ReplayFile)
 		if err != nil {
-			log.Printf("Goroutine %d: Failed to create person: %v", goroutineID, err)
-		} else {
-			log.Printf("Goroutine %d: Created person with IIN: %s", goroutineID, iin)
+			log.Fatalf("Failed to load replay file: %v", err)
 		}
-
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(100)))
+		replayer = NewReplayer(records, config.ReplaySpeed, apiClient, recorder)
 	}
-}
+	runner := NewRunner(scenario, apiClient, recorder)
+	if replayer == nil && scenario.Seed > 0 {
+		log.Printf("Seeding %d people", scenario.Seed)
+		runner.Seed(context.Background())
+	}
+	start := time.Now()
 
-func createPerson(serverURL string, person Person) error {
+	if replayer != nil {
+		replayer.Run(start, config)
+	} else if schedule != nil {
+		runOpenModel(start, *schedule, config, runner)
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(config.NumGoroutines)
+
+		for i := 0; i < config.NumGoroutines; i++ {
+			go func(goroutineID int) {
+				defer wg.Done()
+				runStressTest(goroutineID, config, runner)
+			}(i)
+		}
 
-	jsonData, err := json.Marshal(person)
-	if err != nil {
-		return fmt.Errorf("failed to marshal person: %w", err)
+		wg.Wait()
 	}
+	log.Println("Stress test completed")
 
-	url := fmt.Sprintf("%s/people/info", serverURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	report := recorder.Report(time.Since(start))
+	if replayer != nil {
+		replayReport := replayer.Report()
+		report.Replay = &replayReport
+	}
+	if thresholds != nil {
+		report.Checks = append(report.Checks, report.CheckThresholds(thresholds)...)
+	}
+	if baseline != nil {
+		report.Checks = append(report.Checks, report.CompareBaseline(baseline, config.MaxRegression)...)
+	}
+	if config.ReportFormat == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if config.ReportFile != "" {
+		if err := writeReportFile(config.ReportFile, report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if !report.Passed() {
+		log.Println("SLO checks failed")
+		os.Exit(exitChecksFailed)
 	}
-	defer resp.Body.Close()
+}
 
-	var response model.PersonResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+func writeReportFile(path string, report Report) error {
+	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return err
 	}
-	if !response.Success {
-		return fmt.Errorf("failed to create person: %s", response.Errors)
+	if err := report.WriteJSON(file); err != nil {
+		file.Close()
+		return err
 	}
+	return file.Close()
+}
 
-	return nil
+// runStressTest is one worker of the closed model: it sends the next request
+// only after the previous one has completed and its think time has passed.
+func runStressTest(goroutineID int, config Config, runner *Runner) {
+	deadline := time.Now().Add(config.Duration)
+	for i := 0; ; i++ {
+		if config.Duration > 0 && !time.Now().Before(deadline) ||
+			config.Duration <= 0 && i >= config.NumRequests {
+			return
+		}
+		operation, err := runner.Run(context.Background(), fmt.Sprintf("%d-%d", goroutineID, i))
+		if err != nil && config.Verbose {
+			log.Printf("Goroutine %d: %s failed: %v", goroutineID, operation, err)
+		}
+	}
 }
 
-func generateRandomIIN(valid bool) string {
-	year := 1950 + rand.Intn(70)
-	month := 1 + rand.Intn(12)
-	day := 1 + rand.Intn(28)
+// runOpenModel starts every request of the schedule at its planned time,
+// without waiting for earlier ones, and returns once all have completed.
+// Latency is measured from the planned time, so a server that falls behind
+// is not hidden by the load generator slowing down with it.
+func runOpenModel(start time.Time, schedule Schedule, config Config, runner *Runner) {
+	openLoop(start, schedule.Each, config.MaxInFlight, func(requestID int, intended time.Time) {
+		operation, err := runner.RunAt(context.Background(), strconv.Itoa(requestID), intended)
+		if err != nil && config.Verbose {
+			log.Printf("Request %d: %s failed: %v", requestID, operation, err)
+		}
+	})
+}
 
-	var genderCentury int
-	if year >= 2000 {
-		genderCentury = 3 + rand.Intn(2)
-	} else {
-		genderCentury = 1 + rand.Intn(2)
-	}
+// openLoop calls send for each offset produced by each, at start plus the
+// offset, with at most maxInFlight sends running at a time.
+func openLoop(start time.Time, each func(func(time.Duration) bool), maxInFlight int, send func(requestID int, intended time.Time)) {
+	slots := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	n := 0
+	each(func(offset time.Duration) bool {
+		intended := start.Add(offset)
+		time.Sleep(time.Until(intended))
+		slots <- struct{}{}
+
+		wg.Add(1)
+		go func(requestID int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			send(requestID, intended)
+		}(n)
+		n++
+		return true
+	})
+	wg.Wait()
+}
+
+// generateRandomIIN returns an IIN that passes validation, or with valid
+// false one whose check digit is wrong, so that the server must reject it.
+func generateRandomIIN(valid bool) string {
+	for {
+		year := 1950 + rand.Intn(70)
+		month := 1 + rand.Intn(12)
+		day := 1 + rand.Intn(28)
+
+		var genderCentury int
+		if year >= 2000 {
+			genderCentury = 3 + rand.Intn(2)
+		} else {
+			genderCentury = 1 + rand.Intn(2)
+		}
 
-	regNumber := 1000 + rand.Intn(9000)
+		regNumber := 1000 + rand.Intn(9000)
 
-	iin := fmt.Sprintf("%02d%02d%02d%d%04d", year%100, month, day, genderCentury, regNumber)
+		iin := fmt.Sprintf("%02d%02d%02d%d%04d", year%100, month, day, genderCentury, regNumber)
 
-	if valid {
-		checksum := calculateChecksum(iin)
-		iin += strconv.Itoa(checksum)
-	} else {
-		iin += strconv.Itoa(rand.Intn(10))
+		// Numbers without a check digit are invalid whatever the last digit is.
+		checksum, ok := calculateChecksum(iin)
+		switch {
+		case valid && ok:
+			return iin + strconv.Itoa(checksum)
+		case !valid && ok:
+			return iin + strconv.Itoa((checksum+1+rand.Intn(9))%10)
+		case !valid:
+			return iin + strconv.Itoa(rand.Intn(10))
+		}
 	}
+}
+
+func generateRandomPhone() string {
+	operatorCodes := []string{"700", "701", "702", "705", "707", "708", "747", "771", "775", "777", "778"}
+	code := operatorCodes[rand.Intn(len(operatorCodes))]
 
-	return iin
+	return fmt.Sprintf("+7%s%07d", code, rand.Intn(10000000))
 }
 
-func calculateChecksum(iin11 string) int {
+// calculateChecksum returns the check digit of the first 11 digits of an
+// IIN, or false if there is none and no IIN starts with them.
+func calculateChecksum(iin11 string) (int, bool) {
 	weights1 := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
 
 	sum := 0
@@ -164,11 +348,11 @@ func calculateChecksum(iin11 string) int {
 		controlDigit = sum % 11
 
 		if controlDigit == 10 {
-			controlDigit = 0
+			return 0, false
 		}
 	}
 
-	return controlDigit
+	return controlDigit, true
 }
 
 func getEnv(key, defaultValue string) string {
@@ -179,6 +363,36 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		log.Printf("Warning: Could not parse %s=%s as number, using default %v", key, valueStr, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		log.Printf("Warning: Could not parse %s=%s as duration, using default %s", key, valueStr, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if valueStr == "" {
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
+)
+
+// maxMismatchExamples is how many mismatches the report lists.
+const maxMismatchExamples = 10
+
+// Replayer sends the requests of a capture file, as written by the server
+// with CAPTURE_FILE, and compares the responses with the captured ones.
+type Replayer struct {
+	records  []capture.Record
+	speed    float64
+	client   *client.Client
+	recorder *Recorder
+
+	mu     sync.Mutex
+	report ReplayReport
+	// iins maps each masked IIN to the valid one sent in its place, so that
+	// a person created under a masked IIN can be read back.
+	iins map[string]string
+}
+
+// NewReplayer replays records at speed times their original pace; a speed of
+// 0 sends them as fast as MAX_IN_FLIGHT allows.
+func NewReplayer(records []capture.Record, speed float64, apiClient *client.Client, recorder *Recorder) *Replayer {
+	records = append([]capture.Record(nil), records...)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return &Replayer{
+		records:  records,
+		speed:    speed,
+		client:   apiClient,
+		recorder: recorder,
+		report:   ReplayReport{Records: int64(len(records))},
+		iins:     map[string]string{},
+	}
+}
+
+// Run replays all records, starting the first at start, and returns once
+// all responses have arrived.
+func (r *Replayer) Run(start time.Time, config Config) {
+	// Records are indexed by the order each yields them in, which openLoop
+	// passes back as the request id.
+	each := func(send func(time.Duration) bool) {
+		for _, record := range r.records {
+			var offset time.Duration
+			if r.speed > 0 {
+				offset = time.Duration(float64(record.Time.Sub(r.records[0].Time)) / r.speed)
+			}
+			if !send(offset) {
+				return
+			}
+		}
+	}
+	openLoop(start, each, config.MaxInFlight, func(i int, intended time.Time) {
+		if err := r.replay(context.Background(), &r.records[i], intended); err != nil && config.Verbose {
+			log.Printf("Replay of %s %s failed: %v", r.records[i].Method, r.records[i].Path, err)
+		}
+	})
+}
+
+func (r *Replayer) replay(ctx context.Context, record *capture.Record, intended time.Time) error {
+	operation := record.Method + " " + record.Route
+	if record.BodyTruncated {
+		r.mu.Lock()
+		r.report.Skipped++
+		r.mu.Unlock()
+		return nil
+	}
+
+	body := strings.NewReader(r.unmask(string(record.Body)))
+	req, err := http.NewRequestWithContext(ctx, record.Method, r.client.BaseURL+r.unmask(record.Path), body)
+	if err != nil {
+		return err
+	}
+	for name, value := range record.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case r.client.APIKey != "":
+		req.Header.Set("X-API-Key", r.client.APIKey)
+	case r.client.Token != "":
+		req.Header.Set("Authorization", "Bearer "+r.client.Token)
+	}
+
+	httpClient := r.client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		r.recorder.Record(operation, time.Since(intended), 0, err)
+		return err
+	}
+	response, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	latency := time.Since(intended)
+	if err != nil {
+		r.recorder.Record(operation, latency, resp.StatusCode, err)
+		return err
+	}
+
+	message := ""
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message = http.StatusText(resp.StatusCode)
+	}
+	r.recorder.RecordStatus(operation, latency, resp.StatusCode, message)
+	r.compare(record, resp.StatusCode, response)
+	return nil
+}
+
+// compare counts a mismatch if the status differs from the captured one, or
+// the body does. Bodies are only compared for unmasked, complete captures,
+// since a masked IIN is replaced with a different person.
+func (r *Replayer) compare(record *capture.Record, status int, body []byte) {
+	mismatch := Mismatch{
+		RequestID:  record.RequestID,
+		Method:     record.Method,
+		Path:       record.Path,
+		WantStatus: record.Status,
+		GotStatus:  status,
+	}
+	if status != record.Status {
+		mismatch.Reason = "status"
+	} else if !record.Masked && !record.ResponseTruncated && !sameBody(record.Response, body) {
+		mismatch.Reason = "body"
+	} else {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mismatch.Reason == "status" {
+		r.report.StatusMismatches++
+	} else {
+		r.report.BodyMismatches++
+	}
+	if len(r.report.Examples) < maxMismatchExamples {
+		r.report.Examples = append(r.report.Examples, mismatch)
+	}
+}
+
+// sameBody compares JSON bodies as values, ignoring request ids, and other
+// bodies byte by byte.
+func sameBody(want, got []byte) bool {
+	var wantValue, gotValue interface{}
+	if json.Unmarshal(want, &wantValue) != nil || json.Unmarshal(got, &gotValue) != nil {
+		return bytes.Equal(want, got)
+	}
+	wantJSON, _ := json.Marshal(withoutRequestIDs(wantValue))
+	gotJSON, _ := json.Marshal(withoutRequestIDs(gotValue))
+	return bytes.Equal(wantJSON, gotJSON)
+}
+
+func withoutRequestIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		delete(v, "request_id")
+		for key, item := range v {
+			v[key] = withoutRequestIDs(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = withoutRequestIDs(item)
+		}
+	}
+	return value
+}
+
+// unmask replaces masked IINs with generated valid ones, the same one for
+// every occurrence of a masked value.
+func (r *Replayer) unmask(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return capture.MaskedIINPattern.ReplaceAllStringFunc(s, func(masked string) string {
+		if replacement, ok := r.iins[masked]; ok {
+			return replacement
+		}
+		replacement := generateRandomIIN(true)
+		r.iins[masked] = replacement
+		return replacement
+	})
+}
+
+// Report returns the comparison results so far.
+func (r *Replayer) Report() ReplayReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.Examples = append([]Mismatch(nil), r.report.Examples...)
+	return report
+}
+
+type Mismatch struct {
+	RequestID  string `json:"request_id"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Reason     string `json:"reason"`
+	WantStatus int    `json:"want_status"`
+	GotStatus  int    `json:"got_status"`
+}
+
+type ReplayReport struct {
+	Records          int64      `json:"records"`
+	Skipped          int64      `json:"skipped"`
+	StatusMismatches int64      `json:"status_mismatches"`
+	BodyMismatches   int64      `json:"body_mismatches"`
+	Examples         []Mismatch `json:"examples,omitempty"`
+}
+
+func (report ReplayReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Replay:      %d records (%d skipped), %d status mismatches, %d body mismatches\n",
+		report.Records, report.Skipped, report.StatusMismatches, report.BodyMismatches)
+	for _, m := range report.Examples {
+		fmt.Fprintf(w, "  %-6s %s %s: captured %d, got %d (request %s)\n",
+			m.Reason, m.Method, m.Path, m.WantStatus, m.GotStatus, m.RequestID)
+	}
+}
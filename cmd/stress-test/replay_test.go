@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
+)
+
+func TestReplayer(t *testing.T) {
+	var mu sync.Mutex
+	created := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "POST":
+			body, _ := io.ReadAll(r.Body)
+			created[strings.Trim(string(body), `"`)] = true
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"success":true,"request_id":"new"}`))
+		case created[strings.TrimPrefix(r.URL.Path, "/v1/people/info/iin/")]:
+			w.Write([]byte(`{"name":"A"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	base := time.Now()
+	records := []capture.Record{
+		// Out of order: the replayer sorts by time.
+		{Time: base.Add(20 * time.Millisecond), Method: "GET", Route: "/v1/people/info/iin/{iin}",
+			Path: "/v1/people/info/iin/03********26", Status: 200, Masked: true},
+		{Time: base, Method: "POST", Route: "/v1/people/info", Path: "/v1/people/info",
+			Body: []byte(`"03********26"`), Status: 201, Response: []byte(`{"success":true,"request_id":"old"}`)},
+		{Time: base.Add(30 * time.Millisecond), Method: "GET", Route: "/v1/people/info/iin/{iin}",
+			Path: "/v1/people/info/iin/860904350504", Status: 200, Response: []byte(`{"name":"B"}`)},
+		{Time: base.Add(40 * time.Millisecond), Method: "POST", Route: "/v1/people/import", Path: "/v1/people/import",
+			BodyTruncated: true, Status: 200},
+	}
+
+	recorder := NewRecorder()
+	replayer := NewReplayer(records, 0, client.New(server.URL, "key"), recorder)
+	replayer.Run(time.Now(), Config{MaxInFlight: 1})
+
+	report := replayer.Report()
+	if report.Records != 4 || report.Skipped != 1 {
+		t.Errorf("report = %+v, want 4 records with 1 skipped", report)
+	}
+	// The masked IIN is replaced consistently, so the created person is found
+	// and the request id difference is ignored; the last GET gets a 404.
+	if report.StatusMismatches != 1 || report.BodyMismatches != 0 {
+		t.Errorf("report = %+v, want 1 status mismatch", report)
+	}
+	if len(report.Examples) != 1 || report.Examples[0].Path != "/v1/people/info/iin/860904350504" || report.Examples[0].GotStatus != 404 {
+		t.Errorf("examples = %+v", report.Examples)
+	}
+
+	summary := recorder.Report(time.Second)
+	if summary.Requests != 3 || summary.Succeeded != 2 || summary.Outcomes["201"] != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestSameBody(t *testing.T) {
+	if !sameBody([]byte(`{"a":1,"request_id":"x"}`), []byte(`{"request_id":"y", "a":1}`)) {
+		t.Error("bodies differing in request id and formatting should match")
+	}
+	if sameBody([]byte(`{"a":1}`), []byte(`{"a":2}`)) {
+		t.Error("different bodies matched")
+	}
+}
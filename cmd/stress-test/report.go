@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
+)
+
+// outcomeTransportError is the outcome of requests that got no HTTP response.
+const outcomeTransportError = "transport_error"
+
+// Recorder collects the latency and outcome of every request. It is shared
+// by all workers.
+type Recorder struct {
+	mu         sync.Mutex
+	latency    Histogram
+	failed     int64
+	outcomes   map[string]int64
+	errors     map[string]int64
+	operations map[string]*operationStats
+}
+
+// operationStats is the part of a Recorder kept per scenario operation.
+type operationStats struct {
+	latency Histogram
+	failed  int64
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		outcomes:   map[string]int64{},
+		errors:     map[string]int64{},
+		operations: map[string]*operationStats{},
+	}
+}
+
+// Record adds one request of the given operation. status is the HTTP status
+// code of the response, or 0 if the request failed before getting one, in
+// which case the outcome is outcomeTransportError; the request failed if err
+// is not nil.
+func (r *Recorder) Record(operation string, latency time.Duration, status int, err error) {
+	outcome := outcomeTransportError
+	if status > 0 {
+		outcome = strconv.Itoa(status)
+	}
+	var message string
+	if err != nil {
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) {
+			if status == 0 {
+				outcome = strconv.Itoa(apiErr.StatusCode)
+			}
+			message = apiErr.Message
+		} else {
+			message = err.Error()
+		}
+	}
+	r.record(operation, latency, outcome, message, err != nil)
+}
+
+// RecordStatus adds one request that got a response with the given status;
+// message is recorded as the error of non-2xx responses.
+func (r *Recorder) RecordStatus(operation string, latency time.Duration, status int, message string) {
+	failed := status < 200 || status >= 300
+	if !failed {
+		message = ""
+	}
+	r.record(operation, latency, strconv.Itoa(status), message, failed)
+}
+
+func (r *Recorder) record(operation string, latency time.Duration, outcome, message string, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency.Record(latency)
+	r.outcomes[outcome]++
+	if message != "" {
+		r.errors[message]++
+	}
+	if failed {
+		r.failed++
+	}
+
+	stats := r.operations[operation]
+	if stats == nil {
+		stats = &operationStats{}
+		r.operations[operation] = stats
+	}
+	stats.latency.Record(latency)
+	if failed {
+		stats.failed++
+	}
+}
+
+// statusKey is the context key of the *int that statusTransport stores the
+// response status in.
+type statusKey struct{}
+
+// withStatus returns a context whose requests store their response status in
+// *status, for the typed client methods, which return only an error.
+func withStatus(ctx context.Context, status *int) context.Context {
+	return context.WithValue(ctx, statusKey{}, status)
+}
+
+// statusTransport stores the status of every response to a withStatus request.
+type statusTransport struct {
+	next http.RoundTripper
+}
+
+func (t statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if status, ok := req.Context().Value(statusKey{}).(*int); ok && resp != nil {
+		*status = resp.StatusCode
+	}
+	return resp, err
+}
+
+type LatencyReport struct {
+	Min  float64 `json:"min_ms"`
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	P999 float64 `json:"p999_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+func latencyReport(h *Histogram) LatencyReport {
+	return LatencyReport{
+		Min:  milliseconds(h.Min()),
+		Mean: milliseconds(h.Mean()),
+		P50:  milliseconds(h.Percentile(50)),
+		P90:  milliseconds(h.Percentile(90)),
+		P99:  milliseconds(h.Percentile(99)),
+		P999: milliseconds(h.Percentile(99.9)),
+		Max:  milliseconds(h.Max()),
+	}
+}
+
+type OperationReport struct {
+	Requests   int64         `json:"requests"`
+	Failed     int64         `json:"failed"`
+	Throughput float64       `json:"throughput_rps"`
+	Latency    LatencyReport `json:"latency"`
+}
+
+// ErrorRate returns the share of failed requests of the operation.
+func (op OperationReport) ErrorRate() float64 {
+	return errorRate(op.Requests, op.Failed)
+}
+
+type ErrorCount struct {
+	Message string `json:"message"`
+	Count   int64  `json:"count"`
+}
+
+// Report summarizes a run; its JSON form is meant to be stored by CI and
+// compared between runs.
+type Report struct {
+	Requests int64 `json:"requests"`
+	// Succeeded counts the requests that got the response their operation
+	// expects, which for invalid creates and duplicates is an error; Failed
+	// counts the others.
+	Succeeded  int64            `json:"succeeded"`
+	Failed     int64            `json:"failed"`
+	Duration   float64          `json:"duration_s"`
+	Throughput float64          `json:"throughput_rps"`
+	Latency    LatencyReport    `json:"latency"`
+	Outcomes   map[string]int64 `json:"outcomes"`
+	Errors     []ErrorCount     `json:"errors,omitempty"`
+	// Operations breaks the run down by scenario operation type.
+	Operations map[string]OperationReport `json:"operations"`
+	// Replay compares the responses of a REPLAY_FILE run with the captured
+	// ones.
+	Replay *ReplayReport `json:"replay,omitempty"`
+	// Checks are the results of THRESHOLDS_FILE and BASELINE_FILE.
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// ErrorRate returns the share of failed requests.
+func (report Report) ErrorRate() float64 {
+	return errorRate(report.Requests, report.Failed)
+}
+
+func errorRate(requests, failed int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(failed) / float64(requests)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Report builds the summary of a run that took elapsed.
+func (r *Recorder) Report(elapsed time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{
+		Requests:   r.latency.Count(),
+		Duration:   elapsed.Seconds(),
+		Latency:    latencyReport(&r.latency),
+		Outcomes:   make(map[string]int64, len(r.outcomes)),
+		Operations: make(map[string]OperationReport, len(r.operations)),
+	}
+	for outcome, count := range r.outcomes {
+		report.Outcomes[outcome] = count
+	}
+	report.Failed = r.failed
+	report.Succeeded = report.Requests - report.Failed
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+
+	for operation, stats := range r.operations {
+		op := OperationReport{
+			Requests: stats.latency.Count(),
+			Failed:   stats.failed,
+			Latency:  latencyReport(&stats.latency),
+		}
+		if elapsed > 0 {
+			op.Throughput = float64(op.Requests) / elapsed.Seconds()
+		}
+		report.Operations[operation] = op
+	}
+
+	for message, count := range r.errors {
+		report.Errors = append(report.Errors, ErrorCount{Message: message, Count: count})
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		if report.Errors[i].Count != report.Errors[j].Count {
+			return report.Errors[i].Count > report.Errors[j].Count
+		}
+		return report.Errors[i].Message < report.Errors[j].Message
+	})
+	return report
+}
+
+func (report Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Requests:    %d (%d as expected, %d failed)\n", report.Requests, report.Succeeded, report.Failed)
+	fmt.Fprintf(w, "Duration:    %.2fs\n", report.Duration)
+	fmt.Fprintf(w, "Throughput:  %.1f req/s\n", report.Throughput)
+	fmt.Fprintf(w, "Latency:     min %.2fms, mean %.2fms, max %.2fms\n", report.Latency.Min, report.Latency.Mean, report.Latency.Max)
+	fmt.Fprintf(w, "Percentiles: p50 %.2fms, p90 %.2fms, p99 %.2fms, p99.9 %.2fms\n",
+		report.Latency.P50, report.Latency.P90, report.Latency.P99, report.Latency.P999)
+
+	outcomes := make([]string, 0, len(report.Outcomes))
+	for outcome := range report.Outcomes {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	fmt.Fprintln(w, "Outcomes:")
+	for _, outcome := range outcomes {
+		fmt.Fprintf(w, "  %-16s %d\n", outcome, report.Outcomes[outcome])
+	}
+
+	operations := make([]string, 0, len(report.Operations))
+	for operation := range report.Operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	fmt.Fprintln(w, "Operations:")
+	for _, operation := range operations {
+		op := report.Operations[operation]
+		fmt.Fprintf(w, "  %-16s %d (%d failed), p50 %.2fms, p99 %.2fms\n",
+			operation, op.Requests, op.Failed, op.Latency.P50, op.Latency.P99)
+	}
+
+	if len(report.Errors) > 0 {
+		fmt.Fprintln(w, "Errors:")
+		for _, e := range report.Errors {
+			fmt.Fprintf(w, "  %6d  %s\n", e.Count, e.Message)
+		}
+	}
+
+	if report.Replay != nil {
+		report.Replay.WriteText(w)
+	}
+
+	if len(report.Checks) > 0 {
+		fmt.Fprintln(w, "Checks:")
+		for _, check := range report.Checks {
+			fmt.Fprintf(w, "  %s\n", check)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Operation types a scenario can mix.
+const (
+	OpCheckIIN  = "check_iin"
+	OpCreate    = "create"
+	OpGet       = "get"
+	OpSearch    = "search"
+	OpDuplicate = "duplicate"
+)
+
+// Scenario is a weighted mix of operations, loaded from SCENARIO_FILE:
+//
+//	name: read-heavy
+//	seed: 200
+//	invalid_ratio: 0.05
+//	operations:
+//	  - type: get
+//	    weight: 60
+//	    think_time: 20ms
+//	    think_time_jitter: 80ms
+//	  - type: create
+//	    weight: 10
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Seed is the number of people created before the measured run, so that
+	// get, search and duplicate have something to work with.
+	Seed int `yaml:"seed"`
+	// InvalidRatio is the share of generated IINs with a wrong check digit.
+	// Creating a person with one is expected to fail and is not counted as
+	// an error, nor is a duplicate.
+	InvalidRatio float64     `yaml:"invalid_ratio"`
+	Operations   []Operation `yaml:"operations"`
+}
+
+type Operation struct {
+	Type   string `yaml:"type"`
+	Weight int    `yaml:"weight"`
+	// ThinkTime plus a random part of up to ThinkTimeJitter is waited after
+	// the operation, as a user would between requests.
+	ThinkTime       time.Duration `yaml:"think_time"`
+	ThinkTimeJitter time.Duration `yaml:"think_time_jitter"`
+}
+
+// DefaultScenario is what the stress test did before scenarios existed:
+// create people, one in ten with an invalid IIN.
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Name:         "create",
+		InvalidRatio: 0.1,
+		Operations: []Operation{
+			{Type: OpCreate, Weight: 1, ThinkTimeJitter: 100 * time.Millisecond},
+		},
+	}
+}
+
+// LoadScenario reads a scenario from a YAML or JSON file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var scenario Scenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+func (s *Scenario) Validate() error {
+	var problems []string
+	if s.Seed < 0 {
+		problems = append(problems, "seed must not be negative")
+	}
+	if s.InvalidRatio < 0 || s.InvalidRatio > 1 {
+		problems = append(problems, "invalid_ratio must be between 0 and 1")
+	}
+
+	total := 0
+	for i, op := range s.Operations {
+		switch op.Type {
+		case OpCheckIIN, OpCreate, OpGet, OpSearch, OpDuplicate:
+		default:
+			problems = append(problems, fmt.Sprintf("operations[%d]: unknown type %q", i, op.Type))
+		}
+		if op.Weight < 0 {
+			problems = append(problems, fmt.Sprintf("operations[%d]: weight must not be negative", i))
+		}
+		if op.ThinkTime < 0 || op.ThinkTimeJitter < 0 {
+			problems = append(problems, fmt.Sprintf("operations[%d]: think times must not be negative", i))
+		}
+		total += op.Weight
+	}
+	if total <= 0 {
+		problems = append(problems, "operations must have a positive total weight")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Pick chooses an operation with probability proportional to its weight.
+func (s *Scenario) Pick() Operation {
+	total := 0
+	for _, op := range s.Operations {
+		total += op.Weight
+	}
+	n := rand.Intn(total)
+	for _, op := range s.Operations {
+		if n < op.Weight {
+			return op
+		}
+		n -= op.Weight
+	}
+	return s.Operations[len(s.Operations)-1]
+}
+
+// thinkTime returns how long to wait after op.
+func (op Operation) thinkTime() time.Duration {
+	wait := op.ThinkTime
+	if op.ThinkTimeJitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(op.ThinkTimeJitter)))
+	}
+	return wait
+}
+
+// peoplePool holds the people created so far, for operations that need
+// existing data.
+type peoplePool struct {
+	mu     sync.Mutex
+	people []client.Person
+}
+
+func (p *peoplePool) Add(person client.Person) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.people = append(p.people, person)
+}
+
+// Random returns a created person, or false if there are none yet.
+func (p *peoplePool) Random() (client.Person, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.people) == 0 {
+		return client.Person{}, false
+	}
+	return p.people[rand.Intn(len(p.people))], true
+}
+
+// Runner executes scenario operations against the API.
+type Runner struct {
+	scenario *Scenario
+	client   *client.Client
+	recorder *Recorder
+	pool     peoplePool
+}
+
+func NewRunner(scenario *Scenario, apiClient *client.Client, recorder *Recorder) *Runner {
+	return &Runner{scenario: scenario, client: apiClient, recorder: recorder}
+}
+
+// Seed creates the scenario's seed people without recording them.
+func (r *Runner) Seed(ctx context.Context) {
+	for i := 0; i < r.scenario.Seed; i++ {
+		person := newPerson(fmt.Sprintf("Seed %d", i), true)
+		if err := r.client.CreatePerson(ctx, person, ""); err == nil {
+			r.pool.Add(person)
+		}
+	}
+}
+
+// Run performs one randomly picked operation and waits its think time. It
+// returns the operation type and, if the response was not one the operation
+// expects, the error.
+func (r *Runner) Run(ctx context.Context, name string) (string, error) {
+	op := r.scenario.Pick()
+	opType, err := r.execute(ctx, op, name, time.Now())
+	time.Sleep(op.thinkTime())
+	return opType, err
+}
+
+// RunAt performs one randomly picked operation that was planned to start at
+// intended. Its latency is measured from intended, so time spent waiting for
+// a free slot counts; think times do not apply.
+func (r *Runner) RunAt(ctx context.Context, name string, intended time.Time) (string, error) {
+	return r.execute(ctx, r.scenario.Pick(), name, intended)
+}
+
+func (r *Runner) execute(ctx context.Context, op Operation, name string, start time.Time) (string, error) {
+	opType := op.Type
+
+	// Without created people yet, reads fall back to creating one.
+	existing, ok := r.pool.Random()
+	if !ok && (opType == OpGet || opType == OpSearch || opType == OpDuplicate) {
+		opType = OpCreate
+	}
+
+	valid := rand.Float64() >= r.scenario.InvalidRatio
+	var status int
+	ctx = withStatus(ctx, &status)
+	var err error
+	switch opType {
+	case OpCheckIIN:
+		_, err = r.client.CheckIIN(ctx, generateRandomIIN(valid))
+	case OpCreate:
+		person := newPerson(name, valid)
+		if err = r.client.CreatePerson(ctx, person, ""); err == nil {
+			r.pool.Add(person)
+		}
+	case OpGet:
+		_, err = r.client.GetPersonByIIN(ctx, existing.IIN)
+	case OpSearch:
+		fields := strings.Fields(existing.Name)
+		_, err = r.client.FindPeopleByNamePart(ctx, fields[len(fields)-1])
+	case OpDuplicate:
+		err = r.client.CreatePerson(ctx, existing, "")
+	}
+	err = checkResponse(status, err, expectedResponses(opType, valid))
+	r.recorder.Record(opType, time.Since(start), status, err)
+	return opType, err
+}
+
+// duplicateMessage is the error of a create whose IIN already exists.
+const duplicateMessage = "a person with this IIN already exists"
+
+// expectation is a response an operation is meant to get. An empty message
+// matches any error message.
+type expectation struct {
+	status  int
+	message string
+}
+
+// expectedResponses returns the responses of an operation that are not
+// errors. Creates with an invalid IIN and duplicates are meant to be
+// rejected; the server answers both with 500 and the reason as the message.
+// /iin_check answers 200 for invalid IINs as well.
+func expectedResponses(opType string, valid bool) []expectation {
+	switch {
+	case opType == OpCreate && !valid:
+		return []expectation{{status: 400}, {status: 422}, {status: 500, message: iin.ErrInvalidChecksum.Error()}}
+	case opType == OpDuplicate:
+		return []expectation{{status: 409}, {status: 500, message: duplicateMessage}}
+	default:
+		return []expectation{{status: 200}, {status: 201}}
+	}
+}
+
+// checkResponse returns nil if the response with the given status and error
+// is one of expected, and otherwise the error to record: err, or for an
+// unexpected success a new one.
+func checkResponse(status int, err error, expected []expectation) error {
+	var message string
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		message = apiErr.Message
+		if status == 0 {
+			status = apiErr.StatusCode
+		}
+	}
+	for _, e := range expected {
+		if status == e.status && (e.message == "" || e.message == message) {
+			return nil
+		}
+	}
+	if err == nil {
+		return fmt.Errorf("unexpected status %d", status)
+	}
+	return err
+}
+
+func newPerson(name string, valid bool) client.Person {
+	return client.Person{
+		Name:  "Test Person " + name,
+		IIN:   generateRandomIIN(valid),
+		Phone: generateRandomPhone(),
+	}
+}
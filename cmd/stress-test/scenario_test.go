@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/pkg/client"
+)
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("scenarios/read-heavy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scenario.Name != "read-heavy" || scenario.Seed != 200 || len(scenario.Operations) != 5 {
+		t.Errorf("scenario = %+v", scenario)
+	}
+	if got := scenario.Operations[0].ThinkTime; got != 20*time.Millisecond {
+		t.Errorf("think_time = %v, want 20ms", got)
+	}
+}
+
+func TestLoadScenarioInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":  "operations:\n  - type: delete\n    weight: 1\n",
+		"zero weight":   "operations:\n  - type: get\n    weight: 0\n",
+		"unknown field": "operations:\n  - type: get\n    weight: 1\n    wieght: 2\n",
+		"bad ratio":     "invalid_ratio: 2\noperations:\n  - type: get\n    weight: 1\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenario(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadScenarioJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	content := `{"name": "checks", "operations": [{"type": "check_iin", "weight": 1, "think_time": "10ms"}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scenario.Operations[0].Type != OpCheckIIN || scenario.Operations[0].ThinkTime != 10*time.Millisecond {
+		t.Errorf("scenario = %+v", scenario)
+	}
+}
+
+func TestPick(t *testing.T) {
+	scenario := &Scenario{Operations: []Operation{
+		{Type: OpGet, Weight: 3},
+		{Type: OpCreate, Weight: 1},
+		{Type: OpSearch, Weight: 0},
+	}}
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[scenario.Pick().Type]++
+	}
+	if counts[OpSearch] != 0 {
+		t.Errorf("picked a zero-weight operation %d times", counts[OpSearch])
+	}
+	if ratio := float64(counts[OpGet]) / float64(counts[OpCreate]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("get/create ratio = %.2f, want about 3 (%v)", ratio, counts)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	scenario := &Scenario{Seed: -1, Operations: []Operation{{Type: "x", Weight: 1}}}
+	err := scenario.Validate()
+	if err == nil || !strings.Contains(err.Error(), "seed") || !strings.Contains(err.Error(), "unknown type") {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestRunnerRecordsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, "")
+	apiClient.HTTPClient.Transport = statusTransport{next: http.DefaultTransport}
+	recorder := NewRecorder()
+	runner := NewRunner(&Scenario{Operations: []Operation{{Type: OpCreate, Weight: 1}}}, apiClient, recorder)
+	if _, err := runner.RunAt(context.Background(), "1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := recorder.Report(time.Second)
+	if report.Succeeded != 1 || report.Outcomes["201"] != 1 {
+		t.Errorf("report = %+v, want one 201", report)
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	checksum := &client.Error{StatusCode: 500, Message: iin.ErrInvalidChecksum.Error()}
+	duplicate := &client.Error{StatusCode: 500, Message: duplicateMessage}
+	database := &client.Error{StatusCode: 500, Message: "connection refused"}
+
+	tests := []struct {
+		name       string
+		opType     string
+		valid      bool
+		status     int
+		err        error
+		unexpected bool
+	}{
+		{"created", OpCreate, true, 200, nil, false},
+		{"create failed", OpCreate, true, 500, database, true},
+		{"invalid rejected", OpCreate, false, 500, checksum, false},
+		{"invalid rejected as 422", OpCreate, false, 422, &client.Error{StatusCode: 422}, false},
+		{"invalid rejected for another reason", OpCreate, false, 500, database, true},
+		{"invalid accepted", OpCreate, false, 200, nil, true},
+		{"duplicate rejected", OpDuplicate, true, 500, duplicate, false},
+		{"duplicate rejected as 409", OpDuplicate, true, 409, &client.Error{StatusCode: 409}, false},
+		{"duplicate accepted", OpDuplicate, true, 200, nil, true},
+		{"invalid check", OpCheckIIN, false, 200, nil, false},
+		{"get not found", OpGet, true, 404, &client.Error{StatusCode: 404}, true},
+		{"no response", OpGet, true, 0, errors.New("connection reset"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResponse(tt.status, tt.err, expectedResponses(tt.opType, tt.valid))
+			if (err != nil) != tt.unexpected {
+				t.Errorf("checkResponse() = %v, unexpected = %t", err, tt.unexpected)
+			}
+		})
+	}
+}
+
+func TestGenerateRandomIIN(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		if valid := generateRandomIIN(true); !iin.IsValid(valid) {
+			t.Fatalf("generateRandomIIN(true) = %s, which is invalid", valid)
+		}
+		invalid := generateRandomIIN(false)
+		if _, err := iin.Validate(invalid); !errors.Is(err, iin.ErrInvalidChecksum) {
+			t.Fatalf("generateRandomIIN(false) = %s: %v, want a checksum error", invalid, err)
+		}
+	}
+}
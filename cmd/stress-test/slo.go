@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metrics a check can apply to.
+const (
+	MetricP99        = "p99_ms"
+	MetricErrorRate  = "error_rate"
+	MetricThroughput = "throughput_rps"
+	MetricRequests   = "requests"
+)
+
+// scopeOverall is the Check scope of the whole run; other scopes are
+// operation types.
+const scopeOverall = "overall"
+
+// errorRateTolerance is how much the error rate may grow over the baseline
+// regardless of MAX_REGRESSION, so that 0.1% against 0% is not a failure.
+// Expected rejections are not errors, so a healthy run is close to 0%.
+const errorRateTolerance = 0.001
+
+// Limits are the SLOs of the run or of one operation. Zero P99 and
+// MinThroughput are not checked; MaxErrorRate is a pointer because zero
+// errors is a meaningful limit. The error rate is the share of unexpected
+// responses, so a duplicate rejected as such does not use it up.
+type Limits struct {
+	P99           float64  `yaml:"p99_ms"`
+	MaxErrorRate  *float64 `yaml:"max_error_rate"`
+	MinThroughput float64  `yaml:"min_throughput_rps"`
+}
+
+// Thresholds are loaded from THRESHOLDS_FILE:
+//
+//	overall:
+//	  p99_ms: 250
+//	  max_error_rate: 0.01
+//	operations:
+//	  get:
+//	    p99_ms: 50
+//	    min_throughput_rps: 100
+type Thresholds struct {
+	Overall    Limits            `yaml:"overall"`
+	Operations map[string]Limits `yaml:"operations"`
+}
+
+// LoadThresholds reads thresholds from a YAML or JSON file.
+func LoadThresholds(path string) (*Thresholds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thresholds: %w", err)
+	}
+
+	var thresholds Thresholds
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&thresholds); err != nil {
+		return nil, fmt.Errorf("failed to parse thresholds %s: %w", path, err)
+	}
+	if err := thresholds.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid thresholds %s: %w", path, err)
+	}
+	return &thresholds, nil
+}
+
+func (t *Thresholds) Validate() error {
+	var problems []string
+	check := func(scope string, limits Limits) {
+		if limits.P99 < 0 || limits.MinThroughput < 0 {
+			problems = append(problems, scope+": limits must not be negative")
+		}
+		if limits.MaxErrorRate != nil && (*limits.MaxErrorRate < 0 || *limits.MaxErrorRate > 1) {
+			problems = append(problems, scope+": max_error_rate must be between 0 and 1")
+		}
+	}
+
+	check(scopeOverall, t.Overall)
+	for operation, limits := range t.Operations {
+		switch operation {
+		case OpCheckIIN, OpCreate, OpGet, OpSearch, OpDuplicate:
+		default:
+			problems = append(problems, fmt.Sprintf("unknown operation %q", operation))
+		}
+		check(operation, limits)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Check is the result of comparing one metric with its limit.
+type Check struct {
+	Scope  string  `json:"scope"`
+	Metric string  `json:"metric"`
+	Actual float64 `json:"actual"`
+	Limit  float64 `json:"limit"`
+	// Baseline is the value in the baseline report that Limit was derived
+	// from; zero for a fixed threshold.
+	Baseline float64 `json:"baseline,omitempty"`
+	Passed   bool    `json:"passed"`
+}
+
+func newCheck(scope, metric string, actual, limit, baseline float64) Check {
+	passed := actual <= limit
+	if isMinimum(metric) {
+		passed = actual >= limit
+	}
+	return Check{Scope: scope, Metric: metric, Actual: actual, Limit: limit, Baseline: baseline, Passed: passed}
+}
+
+func (c Check) String() string {
+	result := "PASS"
+	if !c.Passed {
+		result = "FAIL"
+	}
+	comparison := "<="
+	if isMinimum(c.Metric) {
+		comparison = ">="
+	}
+	line := fmt.Sprintf("%s  %-10s %-14s %.4g (limit %s %.4g", result, c.Scope, c.Metric, c.Actual, comparison, c.Limit)
+	if c.Baseline != 0 {
+		line += fmt.Sprintf(", baseline %.4g", c.Baseline)
+	}
+	return line + ")"
+}
+
+// isMinimum reports whether the limit of metric is a lower bound.
+func isMinimum(metric string) bool {
+	return metric == MetricThroughput || metric == MetricRequests
+}
+
+// Passed reports whether all checks of the report passed.
+func (report Report) Passed() bool {
+	for _, check := range report.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckThresholds compares the report with fixed limits. An operation with
+// limits that did not run fails its checks, so a broken scenario does not
+// pass silently.
+func (report Report) CheckThresholds(thresholds *Thresholds) []Check {
+	checks := limitChecks(scopeOverall, thresholds.Overall, report.Latency.P99, report.ErrorRate(), report.Throughput)
+
+	operations := make([]string, 0, len(thresholds.Operations))
+	for operation := range thresholds.Operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		op, ok := report.Operations[operation]
+		if !ok {
+			checks = append(checks, newCheck(operation, MetricRequests, 0, 1, 0))
+			continue
+		}
+		checks = append(checks, limitChecks(operation, thresholds.Operations[operation], op.Latency.P99, op.ErrorRate(), op.Throughput)...)
+	}
+	return checks
+}
+
+func limitChecks(scope string, limits Limits, p99, errorRate, throughput float64) []Check {
+	var checks []Check
+	if limits.P99 > 0 {
+		checks = append(checks, newCheck(scope, MetricP99, p99, limits.P99, 0))
+	}
+	if limits.MaxErrorRate != nil {
+		checks = append(checks, newCheck(scope, MetricErrorRate, errorRate, *limits.MaxErrorRate, 0))
+	}
+	if limits.MinThroughput > 0 {
+		checks = append(checks, newCheck(scope, MetricThroughput, throughput, limits.MinThroughput, 0))
+	}
+	return checks
+}
+
+// LoadReport reads a JSON report written by an earlier run.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// CompareBaseline flags regressions against a previous report: p99 latency
+// more than maxRegression (0.1 is 10%) above the baseline, throughput more
+// than maxRegression below it, and an error rate that grew by more than
+// both maxRegression and errorRateTolerance. Operations missing from either
+// report are skipped, as are metrics the baseline has no value for.
+func (report Report) CompareBaseline(baseline *Report, maxRegression float64) []Check {
+	checks := baselineChecks(scopeOverall, maxRegression,
+		report.Latency.P99, report.ErrorRate(), report.Throughput,
+		baseline.Latency.P99, baseline.ErrorRate(), baseline.Throughput)
+
+	operations := make([]string, 0, len(report.Operations))
+	for operation := range report.Operations {
+		if _, ok := baseline.Operations[operation]; ok {
+			operations = append(operations, operation)
+		}
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		op, base := report.Operations[operation], baseline.Operations[operation]
+		checks = append(checks, baselineChecks(operation, maxRegression,
+			op.Latency.P99, op.ErrorRate(), op.Throughput,
+			base.Latency.P99, base.ErrorRate(), base.Throughput)...)
+	}
+	return checks
+}
+
+func baselineChecks(scope string, maxRegression, p99, errorRate, throughput, baseP99, baseErrorRate, baseThroughput float64) []Check {
+	var checks []Check
+	if baseP99 > 0 {
+		checks = append(checks, newCheck(scope, MetricP99, p99, baseP99*(1+maxRegression), baseP99))
+	}
+	errorLimit := baseErrorRate * (1 + maxRegression)
+	if errorLimit < baseErrorRate+errorRateTolerance {
+		errorLimit = baseErrorRate + errorRateTolerance
+	}
+	checks = append(checks, newCheck(scope, MetricErrorRate, errorRate, errorLimit, baseErrorRate))
+	if baseThroughput > 0 {
+		checks = append(checks, newCheck(scope, MetricThroughput, throughput, baseThroughput*(1-maxRegression), baseThroughput))
+	}
+	return checks
+}
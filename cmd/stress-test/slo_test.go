@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testReport() Report {
+	return Report{
+		Requests:   1000,
+		Failed:     10,
+		Throughput: 100,
+		Latency:    LatencyReport{P99: 40},
+		Operations: map[string]OperationReport{
+			OpGet:    {Requests: 800, Failed: 0, Throughput: 80, Latency: LatencyReport{P99: 20}},
+			OpCreate: {Requests: 200, Failed: 10, Throughput: 20, Latency: LatencyReport{P99: 60}},
+		},
+	}
+}
+
+func failedChecks(checks []Check) []string {
+	var failed []string
+	for _, check := range checks {
+		if !check.Passed {
+			failed = append(failed, check.Scope+" "+check.Metric)
+		}
+	}
+	return failed
+}
+
+func TestCheckThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	content := `
+overall:
+  p99_ms: 50
+  max_error_rate: 0.02
+  min_throughput_rps: 50
+operations:
+  create:
+    p99_ms: 50
+    max_error_rate: 0
+  get:
+    min_throughput_rps: 50
+  search:
+    p99_ms: 100
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	thresholds, err := LoadThresholds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := testReport()
+	report.Checks = report.CheckThresholds(thresholds)
+	if len(report.Checks) != 7 {
+		t.Errorf("got %d checks, want 7: %v", len(report.Checks), report.Checks)
+	}
+	want := []string{"create p99_ms", "create error_rate", "search requests"}
+	got := failedChecks(report.Checks)
+	if len(got) != len(want) {
+		t.Fatalf("failed checks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("failed checks = %v, want %v", got, want)
+		}
+	}
+	if report.Passed() {
+		t.Error("Passed() = true with failed checks")
+	}
+}
+
+func TestLoadThresholdsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	content := "operations:\n  delete:\n    p99_ms: 10\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadThresholds(path); err == nil {
+		t.Error("expected an error for an unknown operation")
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := testReport()
+
+	same := testReport()
+	if failed := failedChecks(same.CompareBaseline(&baseline, 0.1)); len(failed) != 0 {
+		t.Errorf("identical run failed %v", failed)
+	}
+
+	worse := testReport()
+	worse.Latency.P99 = 43 // within 10%
+	get := worse.Operations[OpGet]
+	get.Latency.P99 = 30
+	get.Throughput = 60
+	worse.Operations[OpGet] = get
+	create := worse.Operations[OpCreate]
+	create.Failed = 11 // 5.5%, within 10% of the baseline's 5%
+	worse.Operations[OpCreate] = create
+	worse.Operations[OpSearch] = OperationReport{Requests: 1, Failed: 1}
+
+	want := []string{"get p99_ms", "get throughput_rps"}
+	got := failedChecks(worse.CompareBaseline(&baseline, 0.1))
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("failed checks = %v, want %v", got, want)
+	}
+
+	// Against a baseline without errors, up to 0.1% of unexpected responses
+	// are within the tolerance.
+	tests := []struct {
+		failed int64
+		passed bool
+	}{
+		{0, true},
+		{2, true},  // 0.1%
+		{3, false}, // 0.15%
+	}
+	for _, tt := range tests {
+		run := testReport()
+		get := run.Operations[OpGet]
+		get.Requests = 2000
+		get.Failed = tt.failed
+		run.Operations[OpGet] = get
+		failed := failedChecks(run.CompareBaseline(&baseline, 0.1))
+		if (len(failed) == 0) != tt.passed {
+			t.Errorf("%d of 2000 gets failed: failed checks = %v, want passed = %t", tt.failed, failed, tt.passed)
+		}
+	}
+}
@@ -96,9 +96,8 @@ func exampleIntegration() {
 		fmt.Println("Пользователь родился в XXI веке")
 	}
 
-	if info.RegionCode >= 1000 && info.RegionCode <= 1999 {
-		fmt.Println("Региональный код указывает на центральные области")
-	}
+	// Цифры 8-11 - порядковый номер регистрации, региона они не указывают
+	fmt.Printf("Порядковый номер: %d\n", info.SerialNumber)
 
 	// Можно использовать в HTTP handler'е
 	handleUserRegistration(info)
@@ -110,5 +109,5 @@ func handleUserRegistration(info *iin.IINInfo) {
 	fmt.Printf("- Пол: %s\n", info.Sex)
 	fmt.Printf("- Дата рождения: %s\n", info.DateOfBirth)
 	fmt.Printf("- Век: %d\n", info.Century)
-	fmt.Printf("- Региональный код: %d\n", info.RegionCode)
+	fmt.Printf("- Порядковый номер: %d\n", info.SerialNumber)
 }
@@ -0,0 +1,186 @@
+package iin_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+)
+
+// Затравочный корпус лежит в testdata/fuzz/<имя цели>; найденные фаззером
+// входы, на которых тест упал, go test сохраняет туда же, и их стоит
+// коммитить вместе с исправлением.
+//
+// Запуск фаззинга:
+//
+//	go test -run='^$' -fuzz=FuzzValidate -fuzztime=1m ./iin/
+
+var validationErrors = []error{
+	iin.ErrInvalidLength,
+	iin.ErrNotDigits,
+	iin.ErrInvalidChecksum,
+	iin.ErrInvalidCentury,
+	iin.ErrInvalidMonth,
+	iin.ErrInvalidDay,
+	iin.ErrFutureBirthDate,
+	iin.ErrInvalidSerial,
+}
+
+func FuzzValidate(f *testing.F) {
+	f.Add("031231500126")
+	f.Add("000229500000")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		info, err := iin.Validate(value)
+		if info == nil {
+			t.Fatalf("Validate(%q) returned nil info", value)
+		}
+		if iin.IsValid(value) != (err == nil) {
+			t.Fatalf("IsValid(%q) disagrees with Validate error %v", value, err)
+		}
+		valid, sex, dateOfBirth, extractErr := iin.ValidateAndExtract(value)
+		if valid != (err == nil) || !errors.Is(extractErr, err) {
+			t.Fatalf("ValidateAndExtract(%q) = %t, %v; Validate error %v", value, valid, extractErr, err)
+		}
+		checkNonCitizen(t, value, info, err)
+
+		if err != nil {
+			known := false
+			for _, target := range validationErrors {
+				known = known || errors.Is(err, target)
+			}
+			if !known {
+				t.Fatalf("Validate(%q) returned an undocumented error: %v", value, err)
+			}
+			if info.Valid {
+				t.Fatalf("Validate(%q) returned Valid with error %v", value, err)
+			}
+			return
+		}
+
+		if !info.Valid || info.Category != iin.CategoryCitizen || sex != info.Sex || dateOfBirth != info.DateOfBirth {
+			t.Fatalf("Validate(%q) = %+v, ValidateAndExtract = %q, %q", value, info, sex, dateOfBirth)
+		}
+		checkDate(t, value, info.DateOfBirth)
+		if info.Century != parseYear(t, info.DateOfBirth)/100+1 {
+			t.Fatalf("Validate(%q): century %d for %s", value, info.Century, info.DateOfBirth)
+		}
+		if serial, _ := strconv.Atoi(value[7:11]); info.SerialNumber != serial || info.RegionCode != serial {
+			t.Fatalf("Validate(%q): serial number %d, region code %d", value, info.SerialNumber, info.RegionCode)
+		}
+		checkSex(t, value, info.Sex)
+
+		if digit, ok := controlDigit(value[:11]); !ok || strconv.Itoa(digit) != value[11:] {
+			t.Fatalf("Validate(%q) accepted a wrong check digit", value)
+		}
+	})
+}
+
+func FuzzExtractSex(f *testing.F) {
+	f.Add("031231500126")
+	f.Add("900101400000")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		sex, err := iin.ExtractSex(value)
+		if err != nil {
+			if sex != "" {
+				t.Fatalf("ExtractSex(%q) = %q with error %v", value, sex, err)
+			}
+			return
+		}
+		if len(value) != 12 {
+			t.Fatalf("ExtractSex(%q) accepted length %d", value, len(value))
+		}
+		checkSex(t, value, sex)
+	})
+}
+
+func FuzzExtractDateOfBirth(f *testing.F) {
+	f.Add("031231500126")
+	f.Add("90+101300000")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		date, err := iin.ExtractDateOfBirth(value)
+		if err != nil {
+			if date != "" {
+				t.Fatalf("ExtractDateOfBirth(%q) = %q with error %v", value, date, err)
+			}
+			return
+		}
+		if len(value) != 12 {
+			t.Fatalf("ExtractDateOfBirth(%q) accepted length %d", value, len(value))
+		}
+		checkDate(t, value, date)
+	})
+}
+
+// checkNonCitizen сравнивает Validate с ValidateWithOptions(AllowNonCitizen):
+// они расходятся только на ИИН, которые Validate отвергает из-за 7-й цифры.
+func checkNonCitizen(t *testing.T, value string, strict *iin.IINInfo, strictErr error) {
+	t.Helper()
+	info, err := iin.ValidateWithOptions(value, iin.Options{AllowNonCitizen: true})
+	if !errors.Is(strictErr, iin.ErrInvalidCentury) {
+		if *info != *strict || !errors.Is(err, strictErr) {
+			t.Fatalf("ValidateWithOptions(%q) = %+v, %v; Validate = %+v, %v", value, info, err, strict, strictErr)
+		}
+		return
+	}
+
+	if err != nil {
+		if !errors.Is(err, iin.ErrInvalidMonth) && !errors.Is(err, iin.ErrInvalidDay) {
+			t.Fatalf("ValidateWithOptions(%q) error = %v", value, err)
+		}
+		return
+	}
+	wantCategory := iin.CategoryUnknown
+	if value[6] == '0' {
+		wantCategory = iin.CategoryForeign
+	}
+	if !info.Valid || info.Category != wantCategory || info.Sex != "" || info.DateOfBirth != "" || info.Century != 0 {
+		t.Fatalf("ValidateWithOptions(%q) = %+v", value, info)
+	}
+}
+
+// checkDate проверяет, что date - существующая дата не позже сегодняшней
+// и что ее день, месяц и две последние цифры года взяты из value как есть.
+func checkDate(t *testing.T, value, date string) {
+	t.Helper()
+	parsed, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		t.Fatalf("%q: date of birth %q does not parse: %v", value, date, err)
+	}
+	if parsed.After(time.Now()) {
+		t.Fatalf("%q: date of birth %s is in the future", value, date)
+	}
+	if date[:2] != value[4:6] || date[3:5] != value[2:4] || date[8:] != value[:2] {
+		t.Fatalf("%q: date of birth %s does not match YYMMDD", value, date)
+	}
+}
+
+// checkSex проверяет, что пол соответствует четности 7-й цифры.
+func checkSex(t *testing.T, value, sex string) {
+	t.Helper()
+	switch value[6] {
+	case '1', '3', '5':
+		if sex != "male" {
+			t.Fatalf("%q: sex %q, want male", value, sex)
+		}
+	case '2', '4', '6':
+		if sex != "female" {
+			t.Fatalf("%q: sex %q, want female", value, sex)
+		}
+	default:
+		t.Fatalf("%q: sex %q for century digit %c", value, sex, value[6])
+	}
+}
+
+func parseYear(t *testing.T, date string) int {
+	t.Helper()
+	year, err := strconv.Atoi(date[6:])
+	if err != nil {
+		t.Fatalf("date of birth %q has no year", date)
+	}
+	return year
+}
@@ -8,6 +8,8 @@
 //	Validate(iin) - полная валидация с извлечением всей информации
 //	IsValid(iin)  - быстрая проверка корректности ИИН
 //	ValidateAndExtract(iin) - совместимая функция для миграции
+//	ValidateWithOptions(iin, opts) - валидация с настройками, например с приемом
+//	                                 номеров иностранцев
 //
 // Пример использования:
 //
@@ -21,8 +23,9 @@
 //   - YY: год рождения (00-99)
 //   - MM: месяц рождения (01-12)
 //   - DD: день рождения (01-31)
-//   - V: век и пол (1-6)
-//   - NNNN: порядковый номер (1000-9999)
+//   - V: век и пол (1-6); 0 и 7-9 встречаются у иностранцев и в старых
+//     номерах, см. Options
+//   - NNNN: порядковый номер регистрации (0000-9999), не код региона
 //   - K: контрольная цифра
 package iin
 
@@ -30,18 +33,94 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Ошибки, которые возвращает Validate. Их можно проверять через errors.Is,
+// чтобы различать причины некорректности ИИН.
+var (
+	ErrInvalidLength   = errors.New("длина ИИН должна быть равна 12 символам")
+	ErrNotDigits       = errors.New("ИИН должен состоять только из цифр")
+	ErrInvalidChecksum = errors.New("некорректная контрольная сумма ИИН")
+	ErrInvalidCentury  = errors.New("неверная цифра века/пола")
+	ErrInvalidMonth    = errors.New("неверный месяц рождения")
+	ErrInvalidDay      = errors.New("неверный день рождения")
+	ErrFutureBirthDate = errors.New("дата рождения не может быть в будущем")
+	ErrInvalidSerial   = errors.New("порядковый номер вне допустимого диапазона")
+	// ErrInvalidSerialRange означает ошибку в Options.SerialRanges, а не в ИИН.
+	ErrInvalidSerialRange = errors.New("неверный диапазон порядковых номеров")
+)
+
+// maxSerial - наибольший порядковый номер, который допускает формат.
+const maxSerial = 9999
+
+// Category показывает, кому выдан ИИН, по 7-й цифре.
+type Category string
+
+const (
+	// CategoryCitizen - 7-я цифра 1-6: известны пол и век рождения.
+	CategoryCitizen Category = "citizen"
+	// CategoryForeign - 7-я цифра 0: номер иностранного гражданина, пол
+	// и век в нем не указаны.
+	CategoryForeign Category = "foreign"
+	// CategoryUnknown - 7-я цифра 7-9: старые и нестандартные номера,
+	// пол и век неизвестны.
+	CategoryUnknown Category = "unknown"
+)
+
 // IINInfo содержит информацию, извлеченную из ИИН.
 //
-// Все поля заполняются только при успешной валидации ИИН.
+// Все поля заполняются только при успешной валидации ИИН. Для категорий
+// CategoryForeign и CategoryUnknown пол, дата рождения и век не заполняются:
+// без века год рождения определить нельзя.
 type IINInfo struct {
-	Valid       bool   `json:"valid"`
-	Sex         string `json:"sex,omitempty"`           // "male" или "female"
-	DateOfBirth string `json:"date_of_birth,omitempty"` // формат DD.MM.YYYY
-	Century     int    `json:"century,omitempty"`       // номер века рождения (19, 20, 21)
-	RegionCode  int    `json:"region_code,omitempty"`   // региональный код (1000-9999)
+	Valid       bool     `json:"valid"`
+	Category    Category `json:"category,omitempty"`
+	Sex         string   `json:"sex,omitempty"`           // "male" или "female"
+	DateOfBirth string   `json:"date_of_birth,omitempty"` // формат DD.MM.YYYY
+	Century     int      `json:"century,omitempty"`       // номер века рождения (19, 20, 21)
+	// SerialNumber - порядковый номер регистрации, цифры 8-11 (0-9999).
+	// Номер 0000 допустим, поэтому поле есть в JSON и при нулевом значении.
+	SerialNumber int `json:"serial_number"`
+	// RegionCode совпадает с SerialNumber: цифры 8-11 не кодируют регион.
+	//
+	// Deprecated: используйте SerialNumber.
+	RegionCode int `json:"region_code,omitempty"`
+}
+
+// SerialRange - диапазон порядковых номеров, границы включаются.
+type SerialRange struct {
+	Min int
+	Max int
+}
+
+// Contains проверяет, попадает ли номер в диапазон
+func (r SerialRange) Contains(serial int) bool {
+	return serial >= r.Min && serial <= r.Max
+}
+
+// Options настраивает ValidateWithOptions. Нулевое значение соответствует
+// Validate.
+type Options struct {
+	// AllowNonCitizen принимает ИИН с 7-й цифрой 0 или 7-9 вместо ошибки
+	// ErrInvalidCentury. У таких номеров проверяются контрольная сумма,
+	// месяц и день рождения; 29 февраля допускается, если год делится на 4
+	// хотя бы в одном из веков, а дата в будущем не проверяется.
+	AllowNonCitizen bool
+
+	// SerialRanges, если не пуст, ограничивает порядковый номер: он должен
+	// попасть хотя бы в один диапазон, иначе возвращается ErrInvalidSerial.
+	// Номер проверяется после контрольной суммы, века и даты рождения.
+	//
+	// Известно о номере только то, что формат допускает 0000-9999: номера
+	// меньше 1000 выдаются (у 031231500126 номер 0012), регион и дата
+	// в номере не закодированы, а официальных поддиапазонов не опубликовано.
+	// Поэтому сам пакет номер не ограничивает, а диапазоны задает вызывающий
+	// код, например чтобы отсечь тестовые номера своей системы. Границы
+	// должны лежать в 0-9999 и Min не может быть больше Max, иначе
+	// ValidateWithOptions возвращает ErrInvalidSerialRange для любого ИИН.
+	SerialRanges []SerialRange
 }
 
 // Validate проверяет корректность ИИН и извлекает всю доступную информацию.
@@ -50,7 +129,7 @@ type IINInfo struct {
 //   - Проверку длины (должна быть 12 символов)
 //   - Проверку формата (только цифры)
 //   - Валидацию контрольной суммы
-//   - Извлечение пола, даты рождения, века и регионального кода
+//   - Извлечение пола, даты рождения, века и порядкового номера
 //
 // Возвращает указатель на IINInfo и ошибку. При успешной валидации
 // поле Valid будет true, а остальные поля заполнены извлеченной информацией.
@@ -63,23 +142,65 @@ type IINInfo struct {
 //	}
 //	fmt.Printf("Пол: %s, Дата рождения: %s\n", info.Sex, info.DateOfBirth)
 func Validate(iin string) (*IINInfo, error) {
+	return ValidateWithOptions(iin, Options{})
+}
+
+// ValidateWithOptions работает как Validate, но с настройками opts.
+//
+// Пример:
+//
+//	info, err := iin.ValidateWithOptions(value, iin.Options{AllowNonCitizen: true})
+//	if err != nil {
+//	    return err
+//	}
+//	if info.Category == iin.CategoryForeign {
+//	    fmt.Println("ИИН иностранного гражданина")
+//	}
+func ValidateWithOptions(iin string, opts Options) (*IINInfo, error) {
 	info := &IINInfo{}
 
+	if err := validateSerialRanges(opts.SerialRanges); err != nil {
+		return info, err
+	}
+
 	// Проверка длины
 	if len(iin) != 12 {
-		return info, errors.New("длина ИИН должна быть равна 12 символам")
+		return info, ErrInvalidLength
 	}
 
 	// Проверка что все символы - цифры
-	for _, c := range iin {
-		if c < '0' || c > '9' {
-			return info, errors.New("ИИН должен состоять только из цифр")
-		}
+	if !isDigits(iin) {
+		return info, ErrNotDigits
 	}
 
 	// Проверка контрольной суммы
 	if !validateChecksum(iin) {
-		return info, errors.New("некорректная контрольная сумма ИИН")
+		return info, ErrInvalidChecksum
+	}
+
+	serial, _ := strconv.Atoi(iin[7:11])
+	category := classify(iin[6])
+	if category != CategoryCitizen {
+		if !opts.AllowNonCitizen {
+			return info, ErrInvalidCentury
+		}
+
+		// Век неизвестен, поэтому проверяются только месяц и день
+		yearTwoDigits, _ := strconv.Atoi(iin[:2])
+		month, _ := strconv.Atoi(iin[2:4])
+		day, _ := strconv.Atoi(iin[4:6])
+		if err := validateMonthDay(month, day, yearTwoDigits%4 == 0); err != nil {
+			return info, err
+		}
+		if !serialAllowed(serial, opts.SerialRanges) {
+			return info, ErrInvalidSerial
+		}
+
+		info.Valid = true
+		info.Category = category
+		info.SerialNumber = serial
+		info.RegionCode = serial
+		return info, nil
 	}
 
 	// Извлечение даты рождения
@@ -94,14 +215,17 @@ func Validate(iin string) (*IINInfo, error) {
 		return info, err
 	}
 
-	// Извлечение регионального кода
-	regionCode, _ := strconv.Atoi(iin[7:11])
+	if !serialAllowed(serial, opts.SerialRanges) {
+		return info, ErrInvalidSerial
+	}
 
 	info.Valid = true
+	info.Category = CategoryCitizen
 	info.Sex = sex
 	info.DateOfBirth = dateOfBirth
 	info.Century = century
-	info.RegionCode = regionCode
+	info.SerialNumber = serial
+	info.RegionCode = serial
 
 	return info, nil
 }
@@ -145,8 +269,9 @@ func ExtractSex(iin string) (string, error) {
 
 // ExtractDateOfBirth извлекает дату рождения из ИИН без полной валидации.
 //
-// Возвращает дату в формате DD.MM.YYYY. Функция проверяет корректность
-// даты (високосные годы, количество дней в месяце), но не проверяет контрольную сумму.
+// Возвращает дату в формате DD.MM.YYYY. Функция проверяет, что первые семь
+// символов - цифры, и корректность даты (високосные годы, количество дней
+// в месяце), но не проверяет контрольную сумму.
 //
 // Пример:
 //
@@ -160,6 +285,11 @@ func ExtractDateOfBirth(iin string) (string, error) {
 		return "", errors.New("некорректная длина ИИН")
 	}
 
+	// strconv.Atoi принимает знаки, так что без этой проверки "+1" сошло бы за месяц
+	if !isDigits(iin[:7]) {
+		return "", ErrNotDigits
+	}
+
 	dateOfBirth, _, err := extractDateOfBirth(iin)
 	return dateOfBirth, err
 }
@@ -193,6 +323,32 @@ func ValidateAndExtract(iin string) (bool, string, string, error) {
 	return info.Valid, info.Sex, info.DateOfBirth, nil
 }
 
+// Mask скрывает середину ИИН, оставляя видимыми первые две и последние две цифры.
+//
+// Функция предназначена для логов и выгрузок, где полный ИИН не должен
+// раскрываться. Строки короче пяти символов маскируются полностью.
+//
+// Пример:
+//
+//	fmt.Println(iin.Mask("031231500126")) // Выведет: 03********26
+func Mask(iin string) string {
+	if len(iin) < 5 {
+		return strings.Repeat("*", len(iin))
+	}
+
+	return iin[:2] + strings.Repeat("*", len(iin)-4) + iin[len(iin)-2:]
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // validateChecksum проверяет контрольную сумму ИИН
 func validateChecksum(iin string) bool {
 	weights1 := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
@@ -245,14 +401,31 @@ func extractDateOfBirth(iin string) (string, int, error) {
 		baseYear = 2000 // 2000-2099
 		century = 21    // XXI век
 	default:
-		return "", 0, errors.New("неверная цифра века/пола")
+		return "", 0, ErrInvalidCentury
 	}
 
 	fullYear := baseYear + yearTwoDigits
 
+	leap := (fullYear%4 == 0 && fullYear%100 != 0) || fullYear%400 == 0
+	if err := validateMonthDay(month, day, leap); err != nil {
+		return "", 0, err
+	}
+
+	// Проверка что дата не в будущем
+	now := time.Now()
+	birthDate := time.Date(fullYear, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if birthDate.After(now) {
+		return "", 0, ErrFutureBirthDate
+	}
+
+	return fmt.Sprintf("%02d.%02d.%04d", day, month, fullYear), century, nil
+}
+
+// validateMonthDay проверяет месяц и день рождения
+func validateMonthDay(month, day int, leap bool) error {
 	// Проверка месяца
 	if month < 1 || month > 12 {
-		return "", 0, errors.New("неверный месяц рождения")
+		return ErrInvalidMonth
 	}
 
 	// Проверка дня
@@ -260,7 +433,7 @@ func extractDateOfBirth(iin string) (string, int, error) {
 	if month == 4 || month == 6 || month == 9 || month == 11 {
 		maxDays = 30
 	} else if month == 2 {
-		if (fullYear%4 == 0 && fullYear%100 != 0) || fullYear%400 == 0 {
+		if leap {
 			maxDays = 29
 		} else {
 			maxDays = 28
@@ -268,17 +441,45 @@ func extractDateOfBirth(iin string) (string, int, error) {
 	}
 
 	if day < 1 || day > maxDays {
-		return "", 0, errors.New("неверный день рождения")
+		return ErrInvalidDay
 	}
+	return nil
+}
 
-	// Проверка что дата не в будущем
-	now := time.Now()
-	birthDate := time.Date(fullYear, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-	if birthDate.After(now) {
-		return "", 0, errors.New("дата рождения не может быть в будущем")
+// validateSerialRanges проверяет, что диапазоны лежат в пределах формата
+// и не перевернуты
+func validateSerialRanges(ranges []SerialRange) error {
+	for _, r := range ranges {
+		if r.Min < 0 || r.Max > maxSerial || r.Min > r.Max {
+			return fmt.Errorf("%w: %d-%d", ErrInvalidSerialRange, r.Min, r.Max)
+		}
+	}
+	return nil
+}
+
+// serialAllowed проверяет порядковый номер по диапазонам; пустой список допускает любой номер
+func serialAllowed(serial int, ranges []SerialRange) bool {
+	if len(ranges) == 0 {
+		return true
 	}
+	for _, r := range ranges {
+		if r.Contains(serial) {
+			return true
+		}
+	}
+	return false
+}
 
-	return fmt.Sprintf("%02d.%02d.%04d", day, month, fullYear), century, nil
+// classify определяет категорию ИИН по 7-й цифре
+func classify(centurySexDigit byte) Category {
+	switch centurySexDigit {
+	case '1', '2', '3', '4', '5', '6':
+		return CategoryCitizen
+	case '0':
+		return CategoryForeign
+	default:
+		return CategoryUnknown
+	}
 }
 
 // extractSex извлекает пол из ИИН
@@ -80,6 +80,31 @@ func ExampleValidateAndExtract() {
 	// Дата рождения: 31.12.2003
 }
 
+// ExampleMask показывает маскирование ИИН для логов и выгрузок
+func ExampleMask() {
+	fmt.Println(iin.Mask("031231500126"))
+	// Output:
+	// 03********26
+}
+
+// ExampleValidateWithOptions показывает прием ИИН иностранного гражданина
+func ExampleValidateWithOptions() {
+	// 7-я цифра 0: пол и век не указаны
+	_, err := iin.Validate("900101012349")
+	fmt.Printf("Validate: %v\n", err)
+
+	info, err := iin.ValidateWithOptions("900101012349", iin.Options{AllowNonCitizen: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Категория: %s\n", info.Category)
+	fmt.Printf("Дата рождения известна: %t\n", info.DateOfBirth != "")
+	// Output:
+	// Validate: неверная цифра века/пола
+	// Категория: foreign
+	// Дата рождения известна: false
+}
+
 // ExampleValidate_errorHandling показывает обработку ошибок
 func ExampleValidate_errorHandling() {
 	// Невалидный ИИН - слишком короткий
@@ -118,11 +143,11 @@ func ExampleIINInfo() {
 			fmt.Println("Родился в XXI веке")
 		}
 
-		fmt.Printf("Региональный код: %d\n", info.RegionCode)
+		fmt.Printf("Порядковый номер: %d\n", info.SerialNumber)
 	}
 	// Output:
 	// ИИН прошел валидацию
 	// Пол: мужской
 	// Родился в XXI веке
-	// Региональный код: 12
+	// Порядковый номер: 12
 }
@@ -0,0 +1,467 @@
+package iin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+)
+
+// controlDigit считает контрольную цифру по первым 11 цифрам независимо от
+// пакета. ok = false, если обе последовательности весов дают 10: такие
+// номера не выдаются.
+func controlDigit(prefix string) (digit int, ok bool) {
+	digit = weightedSum(prefix, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}) % 11
+	if digit == 10 {
+		digit = weightedSum(prefix, []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 1, 2}) % 11
+	}
+	return digit, digit != 10
+}
+
+func weightedSum(prefix string, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += int(prefix[i]-'0') * w
+	}
+	return sum
+}
+
+// makeIIN собирает ИИН с верной контрольной суммой из даты YYMMDD, цифры
+// века/пола и порядкового номера. Если для номера контрольной цифры не
+// существует, берется следующий номер. Дата не проверяется, так что можно
+// собрать ИИН с несуществующей датой.
+func makeIIN(t testing.TB, date string, centuryDigit, serial int) string {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		prefix := fmt.Sprintf("%s%d%04d", date, centuryDigit, (serial+i)%10000)
+		if digit, ok := controlDigit(prefix); ok {
+			return prefix + strconv.Itoa(digit)
+		}
+	}
+	t.Fatalf("no IIN with a valid checksum for %s/%d/%d", date, centuryDigit, serial)
+	return ""
+}
+
+// centuryDigit возвращает цифру века/пола для года рождения.
+func centuryDigit(year int, female bool) int {
+	digit := 1 + 2*(year/100-18)
+	if female {
+		digit++
+	}
+	return digit
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		iin         string
+		wantErr     error
+		wantSex     string
+		wantDate    string
+		wantCentury int
+	}{
+		{"known IIN", "031231500126", nil, "male", "31.12.2003", 21},
+		{"XX century female", makeIIN(t, "750615", 4, 1234), nil, "female", "15.06.1975", 20},
+		{"XIX century male", makeIIN(t, "500101", 1, 1234), nil, "male", "01.01.1850", 19},
+		{"XIX century female", makeIIN(t, "991231", 2, 1234), nil, "female", "31.12.1899", 19},
+		{"XXI century female", makeIIN(t, "100301", 6, 1234), nil, "female", "01.03.2010", 21},
+
+		{"empty", "", iin.ErrInvalidLength, "", "", 0},
+		{"too short", "03123150012", iin.ErrInvalidLength, "", "", 0},
+		{"too long", "0312315001260", iin.ErrInvalidLength, "", "", 0},
+		// Длина считается в байтах: 12 полноширинных цифр - это 36 байт.
+		{"full-width digits", "０３１２３１５００１２６", iin.ErrInvalidLength, "", "", 0},
+		{"letter", "03123150012a", iin.ErrNotDigits, "", "", 0},
+		{"sign", "+31231500126", iin.ErrNotDigits, "", "", 0},
+		{"space", "031231 00126", iin.ErrNotDigits, "", "", 0},
+		{"wrong check digit", "031231500127", iin.ErrInvalidChecksum, "", "", 0},
+
+		{"month 00", makeIIN(t, "900001", 3, 1234), iin.ErrInvalidMonth, "", "", 0},
+		{"month 13", makeIIN(t, "901301", 3, 1234), iin.ErrInvalidMonth, "", "", 0},
+		{"day 00", makeIIN(t, "900100", 3, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"day 32", makeIIN(t, "900132", 3, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"31 April", makeIIN(t, "900431", 3, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"30 April", makeIIN(t, "900430", 3, 1234), nil, "male", "30.04.1990", 20},
+		{"30 February", makeIIN(t, "000230", 5, 1234), iin.ErrInvalidDay, "", "", 0},
+
+		// Границы високосных лет: делится на 4, но не на 100, или делится на 400.
+		{"29 February 2000", makeIIN(t, "000229", 5, 1234), nil, "male", "29.02.2000", 21},
+		{"29 February 1900", makeIIN(t, "000229", 3, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"29 February 1800", makeIIN(t, "000229", 1, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"29 February 1896", makeIIN(t, "960229", 2, 1234), nil, "female", "29.02.1896", 19},
+		{"29 February 1904", makeIIN(t, "040229", 3, 1234), nil, "male", "29.02.1904", 20},
+		{"29 February 2024", makeIIN(t, "240229", 6, 1234), nil, "female", "29.02.2024", 21},
+		{"29 February 2023", makeIIN(t, "230229", 6, 1234), iin.ErrInvalidDay, "", "", 0},
+		{"28 February 2023", makeIIN(t, "230228", 6, 1234), nil, "female", "28.02.2023", 21},
+
+		{"future", makeIIN(t, "991231", 5, 1234), iin.ErrFutureBirthDate, "", "", 0},
+		{"century digit 0", makeIIN(t, "900101", 0, 1234), iin.ErrInvalidCentury, "", "", 0},
+		{"century digit 7", makeIIN(t, "900101", 7, 1234), iin.ErrInvalidCentury, "", "", 0},
+		{"century digit 8", makeIIN(t, "900101", 8, 1234), iin.ErrInvalidCentury, "", "", 0},
+		{"century digit 9", makeIIN(t, "900101", 9, 1234), iin.ErrInvalidCentury, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := iin.Validate(tt.iin)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate(%q) error = %v, want %v", tt.iin, err, tt.wantErr)
+			}
+			if info == nil {
+				t.Fatalf("Validate(%q) returned nil info", tt.iin)
+			}
+			if iin.IsValid(tt.iin) != (tt.wantErr == nil) {
+				t.Errorf("IsValid(%q) = %t", tt.iin, !(tt.wantErr == nil))
+			}
+			if tt.wantErr != nil {
+				if info.Valid || info.Sex != "" || info.DateOfBirth != "" {
+					t.Errorf("Validate(%q) filled info on error: %+v", tt.iin, info)
+				}
+				return
+			}
+
+			serial, _ := strconv.Atoi(tt.iin[7:11])
+			want := iin.IINInfo{
+				Valid:        true,
+				Category:     iin.CategoryCitizen,
+				Sex:          tt.wantSex,
+				DateOfBirth:  tt.wantDate,
+				Century:      tt.wantCentury,
+				SerialNumber: serial,
+				RegionCode:   serial,
+			}
+			if *info != want {
+				t.Errorf("Validate(%q) = %+v, want %+v", tt.iin, *info, want)
+			}
+		})
+	}
+}
+
+func TestExtractSex(t *testing.T) {
+	tests := []struct {
+		iin     string
+		want    string
+		wantErr bool
+	}{
+		{"031231500126", "male", false},
+		// Контрольная сумма не проверяется.
+		{"031231500127", "male", false},
+		{"900101200000", "female", false},
+		{"900101400000", "female", false},
+		{"900101600000", "female", false},
+		{"900101100000", "male", false},
+		{"900101300000", "male", false},
+		{"900101000000", "", true},
+		{"900101700000", "", true},
+		{"900101800000", "", true},
+		{"900101900000", "", true},
+		{"900101+00000", "", true},
+		{"90010130000", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		sex, err := iin.ExtractSex(tt.iin)
+		if (err != nil) != tt.wantErr || sex != tt.want {
+			t.Errorf("ExtractSex(%q) = %q, %v; want %q, error %t", tt.iin, sex, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExtractDateOfBirth(t *testing.T) {
+	tests := []struct {
+		iin     string
+		want    string
+		wantErr error
+	}{
+		{"031231500126", "31.12.2003", nil},
+		// Контрольная сумма и порядковый номер не проверяются.
+		{"031231500127", "31.12.2003", nil},
+		{"0312315abcde", "31.12.2003", nil},
+		{"000229500000", "29.02.2000", nil},
+		{"000229300000", "", iin.ErrInvalidDay},
+		{"901301300000", "", iin.ErrInvalidMonth},
+		{"991231500000", "", iin.ErrFutureBirthDate},
+		{"900101700000", "", iin.ErrInvalidCentury},
+		// strconv.Atoi разобрал бы "+1" как месяц 1.
+		{"90+101300000", "", iin.ErrNotDigits},
+		{"+90101300000", "", iin.ErrNotDigits},
+		{"90-101300000", "", iin.ErrNotDigits},
+	}
+
+	for _, tt := range tests {
+		date, err := iin.ExtractDateOfBirth(tt.iin)
+		if !errors.Is(err, tt.wantErr) || date != tt.want {
+			t.Errorf("ExtractDateOfBirth(%q) = %q, %v; want %q, %v", tt.iin, date, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := iin.ExtractDateOfBirth("0312315001"); err == nil {
+		t.Errorf("ExtractDateOfBirth accepted a short IIN")
+	}
+}
+
+// randomIIN генерирует валидный ИИН со случайными датой рождения (с 1800
+// года до вчерашнего дня), полом и порядковым номером.
+func randomIIN(t testing.TB, rng *rand.Rand) (value string, birth time.Time, female bool) {
+	t.Helper()
+	from := time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC)
+	days := int(time.Since(from).Hours()/24) - 1
+	birth = from.AddDate(0, 0, rng.Intn(days))
+	female = rng.Intn(2) == 1
+	value = makeIIN(t, birth.Format("060102"), centuryDigit(birth.Year(), female), rng.Intn(10000))
+	return value, birth, female
+}
+
+// TestGeneratedIINsValidate проверяет, что любой ИИН, собранный по правилам
+// формата, проходит валидацию, и из него извлекается то, что в него заложено.
+func TestGeneratedIINsValidate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		value, birth, female := randomIIN(t, rng)
+
+		info, err := iin.Validate(value)
+		if err != nil {
+			t.Fatalf("Validate(%q) for %s: %v", value, birth.Format("2006-01-02"), err)
+		}
+		wantSex := "male"
+		if female {
+			wantSex = "female"
+		}
+		if info.Sex != wantSex || info.DateOfBirth != birth.Format("02.01.2006") || info.Century != birth.Year()/100+1 {
+			t.Fatalf("Validate(%q) = %+v, want %s born %s", value, info, wantSex, birth.Format("02.01.2006"))
+		}
+	}
+}
+
+// TestSingleDigitMutations меняет в валидных ИИН по одной цифре.
+//
+// Контрольная сумма по модулю 11 с весами 1..11 ловит замену любой цифры,
+// кроме двух случаев, которые заложены в сам алгоритм:
+//   - 11-я цифра (последняя цифра порядкового номера) входит в первую
+//     сумму с весом 11, то есть не влияет на нее;
+//   - если у исходного номера или после замены первая сумма дает 10,
+//     контрольную цифру дает вторая сумма с другими весами, и тогда суммы
+//     до и после замены могут случайно совпасть.
+//
+// Замена самой контрольной цифры обнаруживается всегда.
+func TestSingleDigitMutations(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 2000; i++ {
+		value, _, _ := randomIIN(t, rng)
+		firstWeights := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+		secondWeights := weightedSum(value, firstWeights)%11 == 10
+
+		for pos := 0; pos < 12; pos++ {
+			for d := byte('0'); d <= '9'; d++ {
+				if d == value[pos] {
+					continue
+				}
+				mutated := value[:pos] + string(d) + value[pos+1:]
+				if !iin.IsValid(mutated) {
+					continue
+				}
+
+				if pos == 11 || pos != 10 && !secondWeights && weightedSum(mutated, firstWeights)%11 != 10 {
+					t.Fatalf("changing digit %d of %s to %c gives valid IIN %s", pos+1, value, d, mutated)
+				}
+			}
+		}
+	}
+}
+
+// TestLeapYears проверяет 29 февраля для каждого года с 1800 по текущий.
+func TestLeapYears(t *testing.T) {
+	for year := 1800; year < time.Now().Year(); year++ {
+		leap := year%4 == 0 && year%100 != 0 || year%400 == 0
+		value := makeIIN(t, fmt.Sprintf("%02d0229", year%100), centuryDigit(year, year%2 == 0), 1234)
+
+		_, err := iin.Validate(value)
+		if leap && err != nil {
+			t.Errorf("29 February %d (%s): %v", year, value, err)
+		}
+		if !leap && !errors.Is(err, iin.ErrInvalidDay) {
+			t.Errorf("29 February %d (%s): error = %v, want %v", year, value, err, iin.ErrInvalidDay)
+		}
+	}
+}
+
+// TestCenturyDigits проверяет все значения 7-й цифры при одной и той же дате.
+func TestCenturyDigits(t *testing.T) {
+	tests := []struct {
+		digit    int
+		category iin.Category
+		sex      string
+		century  int
+		year     int
+	}{
+		{0, iin.CategoryForeign, "", 0, 0},
+		{1, iin.CategoryCitizen, "male", 19, 1890},
+		{2, iin.CategoryCitizen, "female", 19, 1890},
+		{3, iin.CategoryCitizen, "male", 20, 1990},
+		{4, iin.CategoryCitizen, "female", 20, 1990},
+		{5, iin.CategoryCitizen, "male", 21, 2090},
+		{6, iin.CategoryCitizen, "female", 21, 2090},
+		{7, iin.CategoryUnknown, "", 0, 0},
+		{8, iin.CategoryUnknown, "", 0, 0},
+		{9, iin.CategoryUnknown, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		// 2090 год еще в будущем, поэтому для 5 и 6 берется 2010.
+		date := "900101"
+		if tt.century == 21 {
+			date, tt.year = "100101", 2010
+		}
+		value := makeIIN(t, date, tt.digit, 1234)
+
+		lenient, err := iin.ValidateWithOptions(value, iin.Options{AllowNonCitizen: true})
+		if err != nil {
+			t.Errorf("ValidateWithOptions(%q): %v", value, err)
+			continue
+		}
+		if lenient.Category != tt.category {
+			t.Errorf("ValidateWithOptions(%q) category = %q, want %q", value, lenient.Category, tt.category)
+		}
+
+		info, err := iin.Validate(value)
+		if tt.category != iin.CategoryCitizen {
+			if !errors.Is(err, iin.ErrInvalidCentury) {
+				t.Errorf("Validate(%q) error = %v, want %v", value, err, iin.ErrInvalidCentury)
+			}
+			if _, err := iin.ExtractSex(value); err == nil {
+				t.Errorf("ExtractSex(%q) accepted century digit %d", value, tt.digit)
+			}
+			if lenient.Sex != "" || lenient.DateOfBirth != "" || lenient.Century != 0 || lenient.SerialNumber != 1234 {
+				t.Errorf("ValidateWithOptions(%q) = %+v, want no sex, date or century", value, lenient)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Validate(%q): %v", value, err)
+			continue
+		}
+		if *lenient != *info {
+			t.Errorf("ValidateWithOptions(%q) = %+v, Validate = %+v", value, lenient, info)
+		}
+		if info.Sex != tt.sex || info.Century != tt.century || info.DateOfBirth != fmt.Sprintf("01.01.%d", tt.year) {
+			t.Errorf("Validate(%q) = %+v, want %s, century %d, year %d", value, info, tt.sex, tt.century, tt.year)
+		}
+	}
+}
+
+func TestValidateWithOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		iin          string
+		wantErr      error
+		wantCategory iin.Category
+	}{
+		{"foreign", makeIIN(t, "900101", 0, 1234), nil, iin.CategoryForeign},
+		{"unknown", makeIIN(t, "900101", 8, 1234), nil, iin.CategoryUnknown},
+		{"citizen", "031231500126", nil, iin.CategoryCitizen},
+		// 29 февраля 00 года существовало в 2000 году, 01 года - ни в одном веке.
+		{"foreign 29 February 00", makeIIN(t, "000229", 0, 1234), nil, iin.CategoryForeign},
+		{"foreign 29 February 96", makeIIN(t, "960229", 7, 1234), nil, iin.CategoryUnknown},
+		{"foreign 29 February 01", makeIIN(t, "010229", 0, 1234), iin.ErrInvalidDay, ""},
+		// Без века нельзя сказать, в будущем ли дата.
+		{"foreign 31 December 99", makeIIN(t, "991231", 0, 1234), nil, iin.CategoryForeign},
+		{"foreign month 13", makeIIN(t, "901301", 0, 1234), iin.ErrInvalidMonth, ""},
+		{"foreign 31 April", makeIIN(t, "900431", 9, 1234), iin.ErrInvalidDay, ""},
+		{"foreign wrong check digit", invalidCheckDigit(makeIIN(t, "900101", 0, 1234)), iin.ErrInvalidChecksum, ""},
+		{"citizen in the future", makeIIN(t, "991231", 5, 1234), iin.ErrFutureBirthDate, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := iin.ValidateWithOptions(tt.iin, iin.Options{AllowNonCitizen: true})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateWithOptions(%q) error = %v, want %v", tt.iin, err, tt.wantErr)
+			}
+			if info.Valid != (tt.wantErr == nil) || info.Category != tt.wantCategory {
+				t.Errorf("ValidateWithOptions(%q) = %+v, want category %q", tt.iin, info, tt.wantCategory)
+			}
+		})
+	}
+}
+
+// invalidCheckDigit заменяет контрольную цифру на следующую.
+func invalidCheckDigit(value string) string {
+	return value[:11] + string((value[11]-'0'+1)%10+'0')
+}
+
+func TestSerialRanges(t *testing.T) {
+	low := makeIIN(t, "900101", 3, 12)
+	high := makeIIN(t, "900101", 3, 9000)
+	foreign := makeIIN(t, "900101", 0, 12)
+	ranges := []iin.SerialRange{{Min: 1000, Max: 1999}, {Min: 9000, Max: 9999}}
+
+	tests := []struct {
+		name    string
+		iin     string
+		opts    iin.Options
+		wantErr error
+	}{
+		{"no ranges", low, iin.Options{}, nil},
+		{"in a range", high, iin.Options{SerialRanges: ranges}, nil},
+		{"outside the ranges", low, iin.Options{SerialRanges: ranges}, iin.ErrInvalidSerial},
+		{"bounds are included", makeIIN(t, "900101", 3, 1999), iin.Options{SerialRanges: []iin.SerialRange{{Min: 1999, Max: 1999}}}, nil},
+		{"foreign outside the ranges", foreign, iin.Options{AllowNonCitizen: true, SerialRanges: ranges}, iin.ErrInvalidSerial},
+		// Ошибочные диапазоны отвергаются для любого ИИН.
+		{"inverted range", high, iin.Options{SerialRanges: []iin.SerialRange{{Min: 10, Max: 1}}}, iin.ErrInvalidSerialRange},
+		{"negative bound", high, iin.Options{SerialRanges: []iin.SerialRange{{Min: -1, Max: 9999}}}, iin.ErrInvalidSerialRange},
+		{"bound above 9999", high, iin.Options{SerialRanges: []iin.SerialRange{{Min: 9000, Max: 10000}}}, iin.ErrInvalidSerialRange},
+		{"whole format", low, iin.Options{SerialRanges: []iin.SerialRange{{Min: 0, Max: 9999}}}, nil},
+		// Контрольная сумма, век и дата проверяются раньше номера.
+		{"wrong check digit", invalidCheckDigit(low), iin.Options{SerialRanges: ranges}, iin.ErrInvalidChecksum},
+		{"wrong century", foreign, iin.Options{SerialRanges: ranges}, iin.ErrInvalidCentury},
+		{"wrong month", makeIIN(t, "901301", 3, 12), iin.Options{SerialRanges: ranges}, iin.ErrInvalidMonth},
+		{"wrong day", makeIIN(t, "900230", 3, 12), iin.Options{SerialRanges: ranges}, iin.ErrInvalidDay},
+		{"foreign with a wrong day", makeIIN(t, "900132", 0, 12), iin.Options{AllowNonCitizen: true, SerialRanges: ranges}, iin.ErrInvalidDay},
+		{"future date", makeIIN(t, "991231", 5, 12), iin.Options{SerialRanges: ranges}, iin.ErrFutureBirthDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := iin.ValidateWithOptions(tt.iin, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateWithOptions(%q) error = %v, want %v", tt.iin, err, tt.wantErr)
+			}
+			if err != nil {
+				if info.Valid || info.SerialNumber != 0 {
+					t.Errorf("ValidateWithOptions(%q) filled info on error: %+v", tt.iin, info)
+				}
+				return
+			}
+			serial, _ := strconv.Atoi(tt.iin[7:11])
+			if info.SerialNumber != serial || info.RegionCode != serial {
+				t.Errorf("ValidateWithOptions(%q) = %+v, want serial number %d", tt.iin, info, serial)
+			}
+		})
+	}
+}
+
+func TestSerialNumberZeroInJSON(t *testing.T) {
+	value := makeIIN(t, "900101", 3, 0)
+	if value[7:11] != "0000" {
+		t.Skipf("no IIN with serial 0000 for this date: %s", value)
+	}
+	info, err := iin.Validate(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"serial_number":0`) {
+		t.Errorf("json.Marshal(%+v) = %s, want serial_number 0", *info, data)
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+func (h *Handler) FindAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := model.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Limit:  defaultAuditLimit,
+	}
+	if iin := query.Get("iin"); iin != "" {
+		filter.IINHash = h.audit.HashIIN(iin)
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(query.Get("from")); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid from, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return
+	}
+	if filter.To, err = parseAuditTime(query.Get("to")); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid to, expected RFC 3339 timestamp or YYYY-MM-DD")
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			sendErrorResponse(w, http.StatusBadRequest, "Invalid limit, expected 1-1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	events, err := h.audit.Find(filter)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func (h *Handler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	result, err := h.audit.Verify()
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !result.Valid {
+		log.Printf("ERROR: Audit log hash chain broken at event %d: %s", result.BrokenID, result.Errors)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
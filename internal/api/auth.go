@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+)
+
+// routeTemplate returns the path template of the matched route, such as
+// "/iin_check/{iin}", or the request path if there is none.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// requiredRole returns the role needed to call the matched route.
+func requiredRole(r *http.Request) string {
+	path := unversionedPath(routeTemplate(r))
+	switch {
+	case strings.HasPrefix(path, "/iin_check/"):
+		return auth.RoleChecker
+	case path == "/audit" || strings.HasPrefix(path, "/audit/"):
+		return auth.RoleAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return auth.RoleReader
+	default:
+		return auth.RoleWriter
+	}
+}
+
+// authMiddleware rejects requests without valid credentials or without the
+// role the route requires, and records the caller as the audit actor.
+// Rejected requests for person data are audited as denied.
+func authMiddleware(authenticator *auth.Authenticator, auditLogger *audit.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				if !errors.Is(err, auth.ErrNoCredentials) && !errors.Is(err, auth.ErrInvalidCredentials) {
+					log.Printf("ERROR: Authentication failed: %v", err)
+					sendErrorResponse(w, http.StatusInternalServerError, "Authentication failed")
+					return
+				}
+				log.Printf("Rejected unauthenticated request to %s: %v", r.URL.Path, err)
+				auditDenied(auditLogger, r, http.StatusUnauthorized)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="check-iin-kaz"`)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					sendErrorResponse(w, http.StatusUnauthorized, "Authentication required")
+				} else {
+					sendErrorResponse(w, http.StatusUnauthorized, "Invalid credentials")
+				}
+				return
+			}
+
+			role := requiredRole(r)
+			if !principal.HasRole(role) {
+				log.Printf("Rejected %s: role %s required for %s %s", principal.Actor(), role, r.Method, r.URL.Path)
+				auditDenied(auditLogger, r.WithContext(audit.WithActor(r.Context(), principal.Actor())), http.StatusForbidden)
+				sendErrorResponse(w, http.StatusForbidden, "Role "+role+" is required")
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			ctx = audit.WithActor(ctx, principal.Actor())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// auditDenied records a rejected request for person data. IIN checks touch
+// no stored data and are not audited.
+func auditDenied(auditLogger *audit.Logger, r *http.Request, status int) {
+	if auditLogger == nil || requiredRole(r) == auth.RoleChecker {
+		return
+	}
+	entry := audit.FromRequest(r, audit.ActionDenied)
+	entry.Details = map[string]interface{}{
+		"status": status,
+		"method": r.Method,
+		"route":  routeTemplate(r),
+	}
+	var iins []string
+	if iin := mux.Vars(r)["iin"]; iin != "" {
+		iins = append(iins, iin)
+	}
+	auditLogger.Log(entry, iins...)
+}
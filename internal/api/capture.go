@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+)
+
+// captureWriter keeps the start of the response body for the capture file.
+type captureWriter struct {
+	*statusWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if keep := cw.limit - cw.body.Len(); keep < len(b) {
+		cw.truncated = true
+		cw.body.Write(b[:max(keep, 0)])
+	} else {
+		cw.body.Write(b)
+	}
+	return cw.statusWriter.Write(b)
+}
+
+// captureMiddleware writes every request and its response to the capture
+// file for cmd/stress-test to replay. Only the first MaxBody bytes of each
+// body are kept; the request body is still passed on whole.
+func captureMiddleware(writer *capture.Writer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			record := &capture.Record{
+				Time:      start,
+				RequestID: r.Header.Get("X-Request-ID"),
+				Method:    r.Method,
+				Path:      r.URL.RequestURI(),
+				Route:     routeTemplate(r),
+			}
+			for _, name := range capture.ReplayedHeaders {
+				if value := r.Header.Get(name); value != "" {
+					if record.Headers == nil {
+						record.Headers = map[string]string{}
+					}
+					record.Headers[name] = value
+				}
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				body, _ := io.ReadAll(io.LimitReader(r.Body, int64(writer.MaxBody)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				if len(body) > writer.MaxBody {
+					record.BodyTruncated = true
+				} else {
+					record.Body = body
+				}
+			}
+
+			cw := &captureWriter{statusWriter: &statusWriter{ResponseWriter: w}, limit: writer.MaxBody}
+			next.ServeHTTP(cw, r)
+
+			record.Status = cw.statusCode
+			if record.Status == 0 {
+				record.Status = http.StatusOK
+			}
+			record.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+			record.Response = cw.body.Bytes()
+			record.ResponseTruncated = cw.truncated
+			if err := writer.Write(record); err != nil {
+				log.Printf("ERROR: Failed to capture request %s: %v", record.RequestID, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+)
+
+func TestCaptureMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	writer := capture.NewWriter(&out)
+	writer.MaxBody = 32
+
+	r := mux.NewRouter()
+	r.Use(captureMiddleware(writer))
+	r.HandleFunc("/v1/people/info", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}).Methods("POST")
+
+	small := `{"iin":"031231500126"}`
+	large := `{"name":"` + strings.Repeat("a", 40) + `"}`
+	for _, body := range []string{small, large} {
+		req := httptest.NewRequest("POST", "/v1/people/info?dry_run=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Body.String() != body {
+			t.Errorf("handler got %q, want the whole body %q", rec.Body.String(), body)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("captured %d records, want 2", len(lines))
+	}
+	var first, second capture.Record
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+
+	if first.Route != "/v1/people/info" || first.Path != "/v1/people/info?dry_run=1" || first.Status != http.StatusCreated {
+		t.Errorf("first record = %+v", first)
+	}
+	if string(first.Body) != `{"iin":"03********26"}` || string(first.Response) != `{"iin":"03********26"}` {
+		t.Errorf("first record body = %s, response = %s, want masked IINs", first.Body, first.Response)
+	}
+	if first.Headers["Content-Type"] != "application/json" || first.Headers["X-API-Key"] != "" {
+		t.Errorf("first record headers = %v", first.Headers)
+	}
+	if !second.BodyTruncated || second.Body != nil || !second.ResponseTruncated || len(second.Response) != 32 {
+		t.Errorf("second record = %+v, want truncated bodies", second)
+	}
+}
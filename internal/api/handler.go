@@ -2,24 +2,47 @@ package api
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/jobs"
 	"github.com/toleubekov/check-iin-kaz/internal/model"
 	"github.com/toleubekov/check-iin-kaz/internal/repository"
 	"github.com/toleubekov/check-iin-kaz/internal/service"
 )
 
+const (
+	maxImportBodySize = 32 << 20
+	exportFlushEvery  = 500
+	// importReadTimeout replaces the server read timeout for import uploads,
+	// which can be much larger than ordinary requests.
+	importReadTimeout = 5 * time.Minute
+)
+
 type Handler struct {
-	iinService *service.IINService
-	repo       *repository.PersonRepository
+	iinService  *service.IINService
+	repo        repository.PersonStore
+	importer    *service.PersonImporter
+	jobs        *jobs.Manager
+	idempotency repository.IdempotencyStore
+	audit       *audit.Logger
 }
 
-func NewHandler(iinService *service.IINService, repo *repository.PersonRepository) *Handler {
+func NewHandler(iinService *service.IINService, repo repository.PersonStore, jobManager *jobs.Manager, idempotency repository.IdempotencyStore, auditLogger *audit.Logger) *Handler {
 	return &Handler{
-		iinService: iinService,
-		repo:       repo,
+		iinService:  iinService,
+		repo:        repo,
+		importer:    service.NewPersonImporter(iinService, repo),
+		jobs:        jobManager,
+		idempotency: idempotency,
+		audit:       auditLogger,
 	}
 }
 
@@ -45,20 +68,30 @@ func (h *Handler) CheckIIN(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CheckIINInfo is the /v1 check: besides sex and date of birth it returns
+// the century, serial number and age, and for an invalid IIN the reason.
+func (h *Handler) CheckIINInfo(w http.ResponseWriter, r *http.Request) {
+	response := h.iinService.Check(mux.Vars(r)["iin"], time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // Modify the CreatePerson function in internal/api/handler.go
 
 func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
-	log.Println("Received CreatePerson request")
+	logger := requestLogger(r)
+	logger.Println("Received CreatePerson request")
 
 	var person model.Person
 	err := json.NewDecoder(r.Body).Decode(&person)
 	if err != nil {
-		log.Printf("ERROR: Failed to decode request body: %v", err)
+		logger.Printf("ERROR: Failed to decode request body: %v", err)
 		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
-	log.Printf("Attempting to create person: Name=%s, IIN=%s", person.Name, person.IIN)
+	logger.Printf("Attempting to create person: Name=%s, IIN=%s", person.Name, iin.Mask(person.IIN))
 
 	correct, _, _, err := h.iinService.ValidateIIN(person.IIN)
 	if !correct || err != nil {
@@ -66,21 +99,30 @@ func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			errorMsg = err.Error()
 		}
-		log.Printf("ERROR: IIN validation failed: %s", errorMsg)
+		logger.Printf("ERROR: IIN validation failed: %s", errorMsg)
 		sendErrorResponse(w, http.StatusInternalServerError, errorMsg)
 		return
 	}
 
-	log.Printf("IIN validated successfully, proceeding to database insertion")
+	normalizedPhone, err := service.NormalizePhone(person.Phone)
+	if err != nil {
+		logger.Printf("ERROR: Phone validation failed: %v", err)
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid phone: "+err.Error())
+		return
+	}
+	person.Phone = normalizedPhone
+
+	logger.Printf("IIN and phone validated successfully, proceeding to database insertion")
 
 	err = h.repo.Create(&person)
 	if err != nil {
-		log.Printf("ERROR: Database insertion failed: %v", err)
+		logger.Printf("ERROR: Database insertion failed: %v", err)
 		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	log.Printf("SUCCESS: Person created in database with IIN: %s", person.IIN)
+	logger.Printf("SUCCESS: Person created in database with IIN: %s", iin.Mask(person.IIN))
+	h.audit.LogRequest(r, audit.ActionCreate, nil, person.IIN)
 
 	response := model.PersonResponse{
 		Success: true,
@@ -100,6 +142,7 @@ func (h *Handler) GetPersonByIIN(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			errorMsg = err.Error()
 		}
+		h.audit.LogFailure(r, audit.ActionRead, http.StatusInternalServerError, iin)
 		sendErrorResponse(w, http.StatusInternalServerError, errorMsg)
 		return
 	}
@@ -107,13 +150,17 @@ func (h *Handler) GetPersonByIIN(w http.ResponseWriter, r *http.Request) {
 	person, err := h.repo.GetByIIN(iin)
 	if err != nil {
 		if err.Error() == "person not found" {
+			h.audit.LogFailure(r, audit.ActionRead, http.StatusNotFound, iin)
 			sendErrorResponse(w, http.StatusNotFound, "Person not found")
 		} else {
+			h.audit.LogFailure(r, audit.ActionRead, http.StatusInternalServerError, iin)
 			sendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
+	h.audit.LogRequest(r, audit.ActionRead, nil, person.IIN)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
 }
@@ -124,21 +171,200 @@ func (h *Handler) FindPeopleByNamePart(w http.ResponseWriter, r *http.Request) {
 
 	people, err := h.repo.FindByNamePart(namePart)
 	if err != nil {
+		h.audit.LogFailure(r, audit.ActionSearch, http.StatusInternalServerError)
 		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	iins := make([]string, len(people))
+	for i, person := range people {
+		iins[i] = person.IIN
+	}
+	h.audit.LogRequest(r, audit.ActionSearch, map[string]interface{}{"results": len(people)}, iins...)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(people)
 }
 
+func (h *Handler) ImportPeople(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+	format := importFormat(r)
+	if format == "" {
+		sendErrorResponse(w, http.StatusUnsupportedMediaType, "Import must be text/csv or application/x-ndjson")
+		return
+	}
+
+	mode := repository.ImportMode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = repository.ImportModeSkip
+	}
+	if mode != repository.ImportModeSkip && mode != repository.ImportModeUpsert && mode != repository.ImportModeFail {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid import mode, expected skip, upsert or fail")
+		return
+	}
+
+	if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(importReadTimeout)); err != nil {
+		logger.Printf("Warning: Could not extend import read deadline: %v", err)
+	}
+
+	records, err := service.ParseImport(http.MaxBytesReader(w, r.Body, maxImportBodySize), format)
+	if err != nil {
+		logger.Printf("ERROR: Failed to parse import: %v", err)
+		sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	logger.Printf("Importing %d people in %s mode", len(records), mode)
+
+	report, err := h.importer.Import(r.Context(), records, mode, nil)
+	if err != nil {
+		logger.Printf("ERROR: Import failed: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	logger.Printf("Import finished: created=%d updated=%d skipped=%d invalid=%d",
+		report.Created, report.Updated, report.Skipped, report.Invalid)
+	h.audit.LogImport(audit.FromRequest(r, ""), report)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Success {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) ExportPeople(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = service.ExportFormatNDJSON
+	}
+	if format != service.ExportFormatCSV && format != service.ExportFormatNDJSON {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid export format, expected csv or ndjson")
+		return
+	}
+
+	maskIIN := false
+	if value := query.Get("mask_iin"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "Invalid mask_iin value, expected true or false")
+			return
+		}
+		maskIIN = parsed
+	}
+
+	filter := model.PersonFilter{NamePart: query.Get("name")}
+
+	out := &sentWriter{w: w}
+	writer, err := service.NewExportWriter(out, format, maskIIN)
+	if err != nil {
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("people-%s.%s", time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", service.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// An export streams for as long as the table takes to read, so the server
+	// write timeout does not apply to it.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Printf("Warning: Could not clear export write deadline: %v", err)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	err = h.repo.ExportPeople(r.Context(), filter, func(person model.Person) error {
+		if err := writer.Write(person); err != nil {
+			return err
+		}
+		if writer.Count()%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	details := map[string]interface{}{
+		"format":   format,
+		"name":     filter.NamePart,
+		"mask_iin": maskIIN,
+		"rows":     writer.Count(),
+	}
+	if err != nil {
+		// Rows that reached the client before the failure were still disclosed.
+		details["status"] = http.StatusInternalServerError
+		h.audit.LogRequest(r, audit.ActionExport, details)
+	}
+	if err != nil && !out.sent {
+		// Nothing has been sent yet, so the client can still get a proper error.
+		logger.Printf("ERROR: Export failed: %v", err)
+		w.Header().Del("Content-Disposition")
+		sendErrorResponse(w, http.StatusInternalServerError, "Export failed")
+		return
+	}
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated download.
+		logger.Printf("ERROR: Export failed after %d rows: %v", writer.Count(), err)
+		return
+	}
+
+	logger.Printf("Exported %d people as %s", writer.Count(), format)
+	h.audit.LogRequest(r, audit.ActionExport, details)
+}
+
+// sentWriter records whether anything has been written to the response.
+// The export buffers flush by themselves when full, so the row count alone
+// does not tell whether the status and headers are already sent.
+type sentWriter struct {
+	w    io.Writer
+	sent bool
+}
+
+func (s *sentWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		s.sent = true
+	}
+	return s.w.Write(p)
+}
+
+// importFormat picks the import format from the format query parameter or the Content-Type header.
+func importFormat(r *http.Request) string {
+	switch r.URL.Query().Get("format") {
+	case service.ImportFormatCSV:
+		return service.ImportFormatCSV
+	case service.ImportFormatNDJSON:
+		return service.ImportFormatNDJSON
+	}
+
+	contentType := strings.ToLower(r.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return service.ImportFormatCSV
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/ndjson"):
+		return service.ImportFormatNDJSON
+	}
+	return ""
+}
+
 func sendErrorResponse(w http.ResponseWriter, statusCode int, errorMsg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
 	response := model.PersonResponse{
-		Success: false,
-		Errors:  errorMsg,
+		Success:   false,
+		Errors:    errorMsg,
+		RequestID: w.Header().Get("X-Request-ID"), // set by requestMiddleware
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/jobs"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+)
+
+// Valid IINs that differ only in the serial number.
+const (
+	testIIN1 = "900101300017"
+	testIIN2 = "900101300027"
+	testIIN3 = "900101300037"
+)
+
+// newTestHandler returns a Handler on in-memory stores. The job manager is
+// not started.
+func newTestHandler(t *testing.T, people repository.PersonStore) *Handler {
+	t.Helper()
+	return newTestHandlerWithService(t, people, service.NewIINService())
+}
+
+// newTestHandlerWithService is newTestHandler with the given IIN service.
+func newTestHandlerWithService(t *testing.T, people repository.PersonStore, iinService *service.IINService) *Handler {
+	t.Helper()
+	auditLogger := audit.NewLogger(repository.NewMemoryAuditRepository(), []byte("test"))
+	jobManager := jobs.NewManager(repository.NewMemoryJobRepository(), people, iinService, auditLogger, 1)
+	return NewHandler(iinService, people, jobManager, repository.NewMemoryIdempotencyRepository(0), auditLogger)
+}
+
+// serve sends a request through the router without authentication.
+func serve(handler http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImportPeople(t *testing.T) {
+	existing := model.Person{Name: "Existing", IIN: testIIN1, Phone: "+77011234567"}
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		status      int
+		error       string
+		report      model.ImportReport
+		stored      []model.Person
+	}{
+		{
+			name:        "created and skipped",
+			target:      "/v1/people/import",
+			contentType: "text/csv",
+			body:        "phone,name,iin\n87017654321,Changed,%1\n+77011234567,New,%2\n",
+			status:      http.StatusOK,
+			report: model.ImportReport{Success: true, Mode: "skip", Total: 2, Created: 1, Skipped: 1, Rows: []model.ImportRowResult{
+				{Row: 1, IIN: testIIN1, Status: model.ImportStatusSkipped, Error: "a person with this IIN already exists"},
+				{Row: 2, IIN: testIIN2, Status: model.ImportStatusCreated},
+			}},
+			stored: []model.Person{existing, {Name: "New", IIN: testIIN2, Phone: "+77011234567"}},
+		},
+		{
+			name:        "upsert",
+			target:      "/v1/people/import?mode=upsert",
+			contentType: "application/x-ndjson",
+			body:        `{"name":"Changed","iin":"%1","phone":"87017654321"}` + "\n",
+			status:      http.StatusOK,
+			report: model.ImportReport{Success: true, Mode: "upsert", Total: 1, Updated: 1, Rows: []model.ImportRowResult{
+				{Row: 1, IIN: testIIN1, Status: model.ImportStatusUpdated},
+			}},
+			stored: []model.Person{{Name: "Changed", IIN: testIIN1, Phone: "+77017654321"}},
+		},
+		{
+			name:   "malformed rows",
+			target: "/v1/people/import?format=csv",
+			body: "name,iin,phone\n" +
+				"Too,Many,Columns,Here\n" +
+				"Bad IIN,900101300018,\n" +
+				"Bad Phone,%2,12345\n" +
+				",%3,\n" +
+				"\"Unclosed,%3,\n",
+			status: http.StatusOK,
+			report: model.ImportReport{Success: true, Mode: "skip", Total: 5, Invalid: 5, Rows: []model.ImportRowResult{
+				{Row: 1, Status: model.ImportStatusInvalid, Error: "expected 3 columns, got 4"},
+				{Row: 2, IIN: "900101300018", Status: model.ImportStatusInvalid, Error: "invalid IIN: некорректная контрольная сумма ИИН"},
+				{Row: 3, IIN: testIIN2, Status: model.ImportStatusInvalid, Error: "invalid phone: "},
+				{Row: 4, IIN: testIIN3, Status: model.ImportStatusInvalid, Error: "name is required"},
+				{Row: 5, Status: model.ImportStatusInvalid, Error: "malformed CSV row: "},
+			}},
+			stored: []model.Person{existing},
+		},
+		{
+			name:        "duplicate rows",
+			target:      "/v1/people/import",
+			contentType: "application/x-ndjson",
+			body:        `{"name":"First","iin":"%2"}` + "\n{\n" + `{"name":"Second","iin":"%2"}` + "\n",
+			status:      http.StatusOK,
+			report: model.ImportReport{Success: true, Mode: "skip", Total: 3, Created: 1, Skipped: 1, Invalid: 1, Rows: []model.ImportRowResult{
+				{Row: 1, IIN: testIIN2, Status: model.ImportStatusCreated},
+				{Row: 2, Status: model.ImportStatusInvalid, Error: "malformed JSON row: "},
+				{Row: 3, IIN: testIIN2, Status: model.ImportStatusDuplicate, Error: "IIN already appears in row 1"},
+			}},
+			stored: []model.Person{existing, {Name: "First", IIN: testIIN2}},
+		},
+		{
+			name:        "fail mode with an invalid row",
+			target:      "/v1/people/import?mode=fail",
+			contentType: "text/csv",
+			body:        "name,iin,phone\nNew,%2,\nBad IIN,123,\n",
+			status:      http.StatusUnprocessableEntity,
+			report: model.ImportReport{Mode: "fail", Total: 2, Invalid: 1, Errors: "import contains invalid or duplicate rows", Rows: []model.ImportRowResult{
+				{Row: 1, IIN: testIIN2, Status: model.ImportStatusFailed},
+				{Row: 2, IIN: "123", Status: model.ImportStatusInvalid, Error: "invalid IIN: длина ИИН должна быть равна 12 символам"},
+			}},
+			stored: []model.Person{existing},
+		},
+		{
+			name:        "fail mode with a stored IIN",
+			target:      "/v1/people/import?mode=fail",
+			contentType: "text/csv",
+			body:        "name,iin,phone\nNew,%2,\nChanged,%1,\n",
+			status:      http.StatusUnprocessableEntity,
+			report: model.ImportReport{Mode: "fail", Total: 2, Errors: "a person with this IIN already exists", Rows: []model.ImportRowResult{
+				{Row: 1, IIN: testIIN2, Status: model.ImportStatusFailed},
+				{Row: 2, IIN: testIIN1, Status: model.ImportStatusDuplicate, Error: "a person with this IIN already exists"},
+			}},
+			stored: []model.Person{existing},
+		},
+		{
+			name:        "unknown mode",
+			target:      "/v1/people/import?mode=replace",
+			contentType: "text/csv",
+			body:        "name,iin,phone\nNew,%2,\n",
+			status:      http.StatusBadRequest,
+			error:       "Invalid import mode, expected skip, upsert or fail",
+			stored:      []model.Person{existing},
+		},
+		{
+			name:        "unknown format",
+			target:      "/v1/people/import",
+			contentType: "application/json",
+			body:        `[]`,
+			status:      http.StatusUnsupportedMediaType,
+			error:       "Import must be text/csv or application/x-ndjson",
+			stored:      []model.Person{existing},
+		},
+		{
+			name:        "missing column",
+			target:      "/people/import",
+			contentType: "text/csv",
+			body:        "name,iin\nNew,%2\n",
+			status:      http.StatusBadRequest,
+			error:       `CSV header must contain column "phone"`,
+			stored:      []model.Person{existing},
+		},
+		{
+			name:        "empty file",
+			target:      "/people/import",
+			contentType: "application/x-ndjson",
+			body:        "\n\n",
+			status:      http.StatusBadRequest,
+			error:       "import file is empty",
+			stored:      []model.Person{existing},
+		},
+	}
+
+	iins := strings.NewReplacer("%1", testIIN1, "%2", testIIN2, "%3", testIIN3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			people := repository.NewMemoryPersonRepository()
+			stored := existing
+			if err := people.Create(&stored); err != nil {
+				t.Fatal(err)
+			}
+			router := SetupRouter(newTestHandler(t, people), RouterOptions{})
+
+			rec := serve(router, http.MethodPost, tt.target, tt.contentType, iins.Replace(tt.body))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			if tt.error != "" {
+				var response model.PersonResponse
+				json.Unmarshal(rec.Body.Bytes(), &response)
+				if response.Success || response.Errors != tt.error {
+					t.Errorf("response = %+v, want error %q", response, tt.error)
+				}
+			} else {
+				var report model.ImportReport
+				if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+					t.Fatalf("report is not JSON: %v: %s", err, rec.Body)
+				}
+				// Parser and validator messages are checked by prefix only.
+				for i := range report.Rows {
+					if i < len(tt.report.Rows) && strings.HasPrefix(report.Rows[i].Error, tt.report.Rows[i].Error) {
+						report.Rows[i].Error = tt.report.Rows[i].Error
+					}
+				}
+				if !reflect.DeepEqual(report, tt.report) {
+					t.Errorf("report = %+v\nwant     %+v", report, tt.report)
+				}
+			}
+
+			all, _ := people.FindByNamePart("")
+			if !reflect.DeepEqual(all, tt.stored) {
+				t.Errorf("stored people = %+v, want %+v", all, tt.stored)
+			}
+		})
+	}
+}
+
+func TestNonCitizenIIN(t *testing.T) {
+	const (
+		foreignIIN = "900101000107"
+		legacyIIN  = "900101700101"
+	)
+
+	for _, allow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allowed %t", allow), func(t *testing.T) {
+			people := repository.NewMemoryPersonRepository()
+			iinService := service.NewIINServiceWithOptions(iin.Options{AllowNonCitizen: allow})
+			router := SetupRouter(newTestHandlerWithService(t, people, iinService), RouterOptions{})
+
+			rec := serve(router, http.MethodPost, "/v1/people/info", "application/json", `{"name":"Foreign","iin":"`+foreignIIN+`"}`)
+			if created := rec.Code == http.StatusOK; created != allow {
+				t.Errorf("create: status = %d: %s", rec.Code, rec.Body)
+			}
+
+			rec = serve(router, http.MethodPost, "/v1/people/import", "application/x-ndjson", `{"name":"Legacy","iin":"`+legacyIIN+`"}`+"\n")
+			var report model.ImportReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("import: status = %d: %s", rec.Code, rec.Body)
+			}
+			if imported := report.Created == 1; imported != allow {
+				t.Errorf("import report = %+v", report)
+			}
+
+			// Every check agrees with create and import.
+			for _, target := range []string{"/iin_check/", "/v1/iin_check/"} {
+				var response struct{ Correct bool }
+				rec = serve(router, http.MethodGet, target+foreignIIN, "", "")
+				json.Unmarshal(rec.Body.Bytes(), &response)
+				if response.Correct != allow {
+					t.Errorf("%s: %s", target, rec.Body)
+				}
+			}
+			rec = serve(router, http.MethodGet, "/v1/people/info/iin/"+foreignIIN, "", "")
+			if found := rec.Code == http.StatusOK; found != allow {
+				t.Errorf("get: status = %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+// failingExport is a person store whose export fails after a number of rows.
+type failingExport struct {
+	*repository.MemoryPersonRepository
+	rows int
+}
+
+func (f failingExport) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(model.Person) error) error {
+	for i := 0; i < f.rows; i++ {
+		person := model.Person{Name: fmt.Sprintf("Person %d", i), IIN: testIIN1, Phone: "+77011234567"}
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+	return errors.New("pq: connection reset by peer")
+}
+
+func TestExportPeopleFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		rows int
+		// sent is whether the buffered rows reached the client before the error.
+		sent bool
+	}{
+		{"before the first row", 0, false},
+		{"before the buffer fills", 10, false},
+		// More than the export buffers hold but fewer than exportFlushEvery.
+		{"after the buffer flushed", 300, true},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{service.ExportFormatCSV, service.ExportFormatNDJSON} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				people := failingExport{repository.NewMemoryPersonRepository(), tt.rows}
+				router := SetupRouter(newTestHandler(t, people), RouterOptions{})
+
+				rec := serve(router, http.MethodGet, "/v1/people/export?format="+format, "", "")
+				body := rec.Body.String()
+				if strings.Contains(body, "connection reset") {
+					t.Errorf("response leaks the database error: %s", body)
+				}
+
+				if !tt.sent {
+					var response model.PersonResponse
+					if rec.Code != http.StatusInternalServerError || json.Unmarshal(rec.Body.Bytes(), &response) != nil || response.Errors != "Export failed" {
+						t.Fatalf("status = %d, body = %s, want a JSON error", rec.Code, body)
+					}
+					if rec.Header().Get("Content-Disposition") != "" {
+						t.Errorf("error response has Content-Disposition %q", rec.Header().Get("Content-Disposition"))
+					}
+					return
+				}
+
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want the already sent 200", rec.Code)
+				}
+				if strings.Contains(body, `"success"`) {
+					t.Errorf("a JSON error was appended to the %s export", format)
+				}
+				if !strings.HasPrefix(rec.Header().Get("Content-Type"), service.ExportContentType(format)) {
+					t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// SchemaChecker reports whether the store is reachable and its schema up to
+// date, see repository.CheckSchema.
+type SchemaChecker func(ctx context.Context) (*model.SchemaStatus, error)
+
+// PostgresSchemaChecker checks the schema of db with repository.CheckSchema.
+func PostgresSchemaChecker(db *sqlx.DB) SchemaChecker {
+	return func(ctx context.Context) (*model.SchemaStatus, error) {
+		return repository.CheckSchema(ctx, db)
+	}
+}
+
+// HealthHandler serves the liveness and readiness probes. They are mounted
+// outside the API router, so they need no credentials and are not rate limited.
+type HealthHandler struct {
+	checkSchema  SchemaChecker
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(checkSchema SchemaChecker) *HealthHandler {
+	return &HealthHandler{checkSchema: checkSchema}
+}
+
+// SetShuttingDown makes the readiness probe fail so that load balancers stop
+// sending new requests while in-flight ones are drained.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live reports that the process is running; it does not touch the database.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	sendHealthResponse(w, http.StatusOK, model.HealthResponse{Status: "ok"})
+}
+
+// Ready reports whether the server can handle requests: it is not shutting
+// down, the database answers and its schema is up to date.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		sendHealthResponse(w, http.StatusServiceUnavailable, model.HealthResponse{Status: "unavailable", Errors: "Server is shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	schema, err := h.checkSchema(ctx)
+	if err != nil {
+		log.Printf("ERROR: Readiness check failed: %v", err)
+		sendHealthResponse(w, http.StatusServiceUnavailable, model.HealthResponse{Status: "unavailable", Schema: schema, Errors: err.Error()})
+		return
+	}
+
+	sendHealthResponse(w, http.StatusOK, model.HealthResponse{Status: "ok", Schema: schema})
+}
+
+func sendHealthResponse(w http.ResponseWriter, statusCode int, response model.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
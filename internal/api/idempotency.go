@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// responseRecorder passes the response through to the client while keeping
+// a copy of the status code and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// withIdempotency makes a handler safe to retry with an Idempotency-Key header.
+// The first request with a key runs normally and its response is stored; a
+// retry with the same key and body, on the /v1 or the unversioned route,
+// gets the stored response replayed, while
+// reusing the key with a different body is rejected with 422. Keys belong to
+// the authenticated caller, so two clients picking the same key never see
+// each other's responses.
+func (h *Handler) withIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			sendErrorResponse(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// /v1 and the unversioned alias are one endpoint, so a retry on
+		// either mount replays the same response.
+		hash := sha256.New()
+		io.WriteString(hash, r.Method+" "+unversionedPath(r.URL.Path)+"\n")
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		client := audit.Actor(r.Context())
+		record, reserved, err := h.idempotency.Reserve(client, key, requestHash)
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				sendErrorResponse(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+			case record.StatusCode == nil:
+				sendErrorResponse(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+			default:
+				log.Printf("Replaying stored response for Idempotency-Key %s", key)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(*record.StatusCode)
+				w.Write(record.ResponseBody)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rec, r)
+
+		// Server errors are not stored so the client can retry them with the same key.
+		if rec.statusCode >= http.StatusInternalServerError {
+			if err := h.idempotency.Release(client, key); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+		}
+		if err := h.idempotency.Save(client, key, rec.statusCode, rec.body.Bytes()); err != nil {
+			log.Printf("ERROR: %v", err)
+		}
+	}
+}
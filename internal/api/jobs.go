@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/jobs"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	jobType := query.Get("type")
+
+	params := model.JobParams{
+		Mode: query.Get("mode"),
+		Name: query.Get("name"),
+	}
+	switch jobType {
+	case model.JobTypeImport:
+		params.Format = importFormat(r)
+	case model.JobTypeExport:
+		params.Format = query.Get("format")
+		if value := query.Get("mask_iin"); value != "" {
+			maskIIN, err := strconv.ParseBool(value)
+			if err != nil {
+				sendErrorResponse(w, http.StatusBadRequest, "Invalid mask_iin value, expected true or false")
+				return
+			}
+			params.MaskIIN = maskIIN
+		}
+	}
+
+	input, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBodySize))
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	job, err := h.jobs.Submit(audit.Actor(r.Context()), jobType, params, input)
+	if err != nil {
+		if errors.Is(err, jobs.ErrInvalidJob) {
+			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("ERROR: Failed to submit job: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("Job %d (%s) queued", job.ID, job.Type)
+
+	w.Header().Set("Content-Type", "application/json")
+	// The job is at /jobs/{id} next to the route it was created through, /v1 or not.
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.callerJob(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *Handler) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.callerJob(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.jobs.Result(job.ID)
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+
+	extension := "json"
+	switch result.ContentType {
+	case "application/x-ndjson":
+		extension = "ndjson"
+	case "text/csv; charset=utf-8":
+		extension = "csv"
+	}
+
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="job-%d.%s"`, job.ID, extension))
+	w.Write(result.Data)
+}
+
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.callerJob(w, r)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.jobs.Cancel(job.ID)
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+
+	log.Printf("Job %d cancelled", job.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelled)
+}
+
+// callerJob returns the job of the request if the caller created it or is an
+// admin. Jobs of other clients are reported as not found, like missing ones.
+func (h *Handler) callerJob(w http.ResponseWriter, r *http.Request) (*model.Job, bool) {
+	id, ok := jobID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		sendJobError(w, err)
+		return nil, false
+	}
+	principal := auth.PrincipalFromContext(r.Context())
+	if job.CreatedBy != audit.Actor(r.Context()) && (principal == nil || !principal.HasRole(auth.RoleAdmin)) {
+		sendErrorResponse(w, http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+	return job, true
+}
+
+func jobID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid job id")
+		return 0, false
+	}
+	return id, true
+}
+
+func sendJobError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "job not found":
+		sendErrorResponse(w, http.StatusNotFound, "Job not found")
+	case "job already finished", "job result not available":
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
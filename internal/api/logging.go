@@ -0,0 +1,106 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/tracing"
+)
+
+const maxRequestIDLength = 128
+
+// validRequestID accepts caller-supplied ids that are safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// requestLogger returns a logger that prefixes lines with the request id, so
+// that handler logs can be matched to the access log.
+func requestLogger(r *http.Request) *log.Logger {
+	return log.New(log.Writer(), "request_id="+r.Header.Get("X-Request-ID")+" ", log.Flags()|log.Lmsgprefix)
+}
+
+// requestMiddleware gives every request an X-Request-ID (keeping a valid one
+// sent by the caller), runs it in a server span that continues the caller's
+// traceparent, and writes a JSON access log line when it finishes. The id is
+// also set on the request headers, where the audit log and error responses
+// pick it up.
+func requestMiddleware(accessLog *slog.Logger, tracer *tracing.Tracer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get("X-Request-ID")
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+				r.Header.Set("X-Request-ID", requestID)
+			}
+			w.Header().Set("X-Request-ID", requestID)
+
+			route := routeTemplate(r)
+			remote, _ := tracing.ParseTraceparent(r.Header.Get("traceparent"))
+			ctx, span := tracer.Start(r.Context(), r.Method+" "+route, remote)
+			span.Kind = tracing.SpanKindServer
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("http.route", route)
+			span.SetAttribute("request.id", requestID)
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			if sw.statusCode == 0 {
+				sw.statusCode = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", sw.statusCode)
+			if sw.statusCode >= 500 {
+				span.SetError(http.StatusText(sw.statusCode))
+			}
+			span.Finish()
+
+			if accessLog == nil {
+				return
+			}
+			clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				clientIP = r.RemoteAddr
+			}
+			attrs := []slog.Attr{
+				slog.String("request_id", requestID),
+				slog.String("trace_id", span.Context.TraceIDString()),
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", sw.statusCode),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int("bytes", sw.bytes),
+				slog.String("client_ip", clientIP),
+			}
+			// Routes address people by IIN; log it only masked.
+			if value, ok := mux.Vars(r)["iin"]; ok {
+				attrs = append(attrs, slog.String("iin", iin.Mask(value)))
+			}
+			accessLog.LogAttrs(ctx, slog.LevelInfo, r.Method+" "+route+" "+strconv.Itoa(sw.statusCode), attrs...)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/tracing"
+)
+
+// recordingExporter keeps exported spans.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (e *recordingExporter) Export(span *tracing.Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// accessLogLine is the part of an access log line the tests look at.
+type accessLogLine struct {
+	RequestID string `json:"request_id"`
+	TraceID   string `json:"trace_id"`
+}
+
+// tracedRequest sends a GET for a person that does not exist, so that the
+// response is an error envelope and the read is audited, and returns what
+// was recorded about it.
+func tracedRequest(t *testing.T, headers map[string]string) (*httptest.ResponseRecorder, model.PersonResponse, accessLogLine, []*tracing.Span, model.AuditEvent) {
+	t.Helper()
+	var logs bytes.Buffer
+	exporter := &recordingExporter{}
+	handler := newTestHandler(t, repository.NewMemoryPersonRepository())
+	router := SetupRouter(handler, RouterOptions{
+		AccessLog: slog.New(slog.NewJSONHandler(&logs, nil)),
+		Tracer:    tracing.NewTracer(exporter),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/people/info/iin/"+testIIN1, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var response model.PersonResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	var line accessLogLine
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", logs.String(), err)
+	}
+	events, err := handler.audit.Find(model.AuditFilter{Limit: 10})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("audit events = %+v, %v", events, err)
+	}
+	return rec, response, line, exporter.spans, events[0]
+}
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name string
+		sent string
+		kept bool
+	}{
+		{"none", "", false},
+		{"valid", "req-42_retry.1", true},
+		{"longest", strings.Repeat("a", maxRequestIDLength), true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"space", "req 42", false},
+		{"header injection", "req\r\nSet-Cookie: a=b", false},
+		{"markup", "<script>", false},
+		{"non-ASCII", "запрос-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.sent != "" {
+				headers["X-Request-ID"] = tt.sent
+			}
+			rec, response, line, spans, event := tracedRequest(t, headers)
+
+			id := rec.Header().Get("X-Request-ID")
+			if tt.kept && id != tt.sent {
+				t.Errorf("X-Request-ID = %q, want the caller's %q", id, tt.sent)
+			}
+			if !tt.kept && !generated.MatchString(id) {
+				t.Errorf("X-Request-ID = %q, want a generated id", id)
+			}
+
+			// The same id reaches the error body, the access log, the span
+			// and the audit log.
+			if response.RequestID != id || line.RequestID != id || event.RequestID != id {
+				t.Errorf("request ids: response %q, access log %q, audit %q; want %q", response.RequestID, line.RequestID, event.RequestID, id)
+			}
+			if len(spans) != 1 || spans[0].Attributes["request.id"] != id {
+				t.Errorf("spans = %+v, want one with request.id %q", spans, id)
+			}
+		})
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		continued   bool
+		// exported is whether the server span is recorded: the caller's
+		// sampling decision is kept, and new traces are always sampled.
+		exported bool
+	}{
+		{"none", "", false, true},
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"malformed", "00-" + traceID + "-" + spanID, false, true},
+		{"zero trace id", "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01", false, true},
+		{"forbidden version", "ff-" + traceID + "-" + spanID + "-01", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.traceparent != "" {
+				headers["traceparent"] = tt.traceparent
+			}
+			_, _, line, spans, _ := tracedRequest(t, headers)
+
+			if tt.continued != (line.TraceID == traceID) {
+				t.Errorf("access log trace_id = %q, continued = %t", line.TraceID, tt.continued)
+			}
+			if len(line.TraceID) != 32 || line.TraceID == strings.Repeat("0", 32) {
+				t.Errorf("access log trace_id = %q, want a trace id", line.TraceID)
+			}
+			if !tt.exported {
+				if len(spans) != 0 {
+					t.Errorf("exported %d spans of an unsampled trace", len(spans))
+				}
+				return
+			}
+
+			if len(spans) != 1 {
+				t.Fatalf("exported %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			parent := hex.EncodeToString(span.Parent[:])
+			if span.Context.TraceIDString() != line.TraceID || span.Kind != tracing.SpanKindServer {
+				t.Errorf("span = %+v, want a server span in trace %s", span, line.TraceID)
+			}
+			if tt.continued && parent != spanID {
+				t.Errorf("span parent = %s, want the caller's %s", parent, spanID)
+			}
+			if !tt.continued && span.Parent != [8]byte{} {
+				t.Errorf("span parent = %s, want a root span", parent)
+			}
+			if span.Name != "GET /v1/people/info/iin/{iin}" || span.Attributes["http.response.status_code"] != http.StatusNotFound {
+				t.Errorf("span = %s %v", span.Name, span.Attributes)
+			}
+		})
+	}
+}
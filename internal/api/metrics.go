@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+)
+
+// statusWriter remembers the status code and body size written to the client. It keeps
+// http.Flusher and http.ResponseController working for streamed exports.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	if sw.statusCode == 0 {
+		sw.statusCode = statusCode
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.statusCode == 0 {
+		sw.statusCode = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// metricsMiddleware counts requests and records their latency per route
+// template, so that /people/info/iin/{iin} is one series, not one per IIN.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.statusCode == 0 {
+			sw.statusCode = http.StatusOK
+		}
+		route := routeTemplate(r)
+		status := strconv.Itoa(sw.statusCode)
+		metrics.HTTPRequests.Inc(route, r.Method, status)
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, r.Method, status)
+	})
+}
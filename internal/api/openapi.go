@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISpec is the OpenAPI 3 description of the routes in SetupRouter.
+// Keep it in step with the router and the model types; openapi_test.go
+// checks both.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// ServeOpenAPI serves OpenAPISpec. Like the health probes it is mounted
+// outside the API router, so reading the spec needs no credentials.
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPISpec)
+}
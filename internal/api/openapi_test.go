@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Schemas    map[string]struct {
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	OperationID string             `json:"operationId"`
+	Parameters  []openAPIParameter `json:"parameters"`
+}
+
+type openAPIParameter struct {
+	Ref  string `json:"$ref"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+func loadOpenAPISpec(t *testing.T) openAPIDocument {
+	t.Helper()
+	var doc openAPIDocument
+	if err := json.Unmarshal(OpenAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+
+	routes := map[string]bool{}
+	router := SetupRouter(&Handler{}, RouterOptions{})
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil // a subrouter prefix
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes[strings.ToLower(method)+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := map[string]bool{}
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			key := method + " " + path
+			documented[key] = true
+			if !routes[key] {
+				t.Errorf("%s is documented but not routed", key)
+			}
+			if operation.OperationID == "" {
+				t.Errorf("%s has no operationId", key)
+			}
+
+			var pathParams []string
+			for _, param := range operation.Parameters {
+				if param.Ref != "" {
+					param = doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+				}
+				if param.In == "path" {
+					pathParams = append(pathParams, param.Name)
+				}
+			}
+			var vars []string
+			for _, match := range regexp.MustCompile(`\{([^}]+)\}`).FindAllStringSubmatch(path, -1) {
+				vars = append(vars, match[1])
+			}
+			sort.Strings(pathParams)
+			sort.Strings(vars)
+			if !reflect.DeepEqual(pathParams, vars) {
+				t.Errorf("%s documents path parameters %v, want %v", key, pathParams, vars)
+			}
+		}
+	}
+	for key := range routes {
+		if !documented[key] {
+			t.Errorf("%s is routed but not documented", key)
+		}
+	}
+}
+
+func TestOpenAPIReferencesResolve(t *testing.T) {
+	var components map[string]map[string]json.RawMessage
+	var doc struct {
+		Components json.RawMessage `json:"components"`
+	}
+	if err := json.Unmarshal(OpenAPISpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(doc.Components, &components); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, match := range regexp.MustCompile(`"\$ref": "#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(OpenAPISpec), -1) {
+		if _, ok := components[match[1]][match[2]]; !ok {
+			t.Errorf("unresolved reference #/components/%s/%s", match[1], match[2])
+		}
+	}
+}
+
+func TestOpenAPISchemasMatchModel(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+
+	types := map[string]interface{}{
+		"Person":            model.Person{},
+		"IINResponse":       model.IINResponse{},
+		"IINInfoResponse":   model.IINInfoResponse{},
+		"PersonResponse":    model.PersonResponse{},
+		"ImportRowResult":   model.ImportRowResult{},
+		"ImportReport":      model.ImportReport{},
+		"JobParams":         model.JobParams{},
+		"Job":               model.Job{},
+		"IINCheckResult":    model.IINCheckResult{},
+		"AuditEvent":        model.AuditEvent{},
+		"AuditVerification": model.AuditVerification{},
+	}
+
+	for name := range doc.Components.Schemas {
+		if _, ok := types[name]; !ok {
+			t.Errorf("schema %s has no model type in this test", name)
+		}
+	}
+
+	for name, value := range types {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("model.%s has no schema", name)
+			continue
+		}
+
+		var fields, required []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			tag := typ.Field(i).Tag.Get("json")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			field, options, _ := strings.Cut(tag, ",")
+			fields = append(fields, field)
+			if options != "omitempty" {
+				required = append(required, field)
+			}
+		}
+
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		schemaRequired := append([]string(nil), schema.Required...)
+		sort.Strings(fields)
+		sort.Strings(required)
+		sort.Strings(properties)
+		sort.Strings(schemaRequired)
+
+		if !reflect.DeepEqual(properties, fields) {
+			t.Errorf("schema %s has properties %v, model has %v", name, properties, fields)
+		}
+		if !reflect.DeepEqual(schemaRequired, required) {
+			t.Errorf("schema %s requires %v, model always sends %v", name, schemaRequired, required)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+)
+
+// routeGroup returns the rate limit group of a request: the first segment of
+// the route template, e.g. "iin_check" or "people".
+func routeGroup(r *http.Request) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(unversionedPath(routeTemplate(r)), "/"), "/")
+	return group
+}
+
+// rateLimitClient identifies the caller: the authenticated principal if there
+// is one, otherwise the client IP.
+func rateLimitClient(r *http.Request) string {
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		return principal.Actor()
+	}
+	return "ip:" + remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// ipRateLimitMiddleware rejects requests over the per-IP limit with 429. It
+// runs before authentication, so failed logins are throttled as well.
+func ipRateLimitMiddleware(limiter *ratelimit.Limiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			decision := limiter.AllowIP(ip)
+			if !decision.Allowed {
+				log.Printf("Rejected ip:%s: IP rate limit exceeded for %s", ip, r.URL.Path)
+				w.Header().Set("Retry-After", ceilSeconds(decision.RetryAfter))
+				sendErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitMiddleware rejects requests over the client's rate limit or daily
+// quota with 429 and reports the bucket state in RateLimit-* headers.
+func rateLimitMiddleware(limiter *ratelimit.Limiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := rateLimitClient(r)
+			decision := limiter.Allow(client, routeGroup(r))
+
+			if decision.Limit > 0 {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+				w.Header().Set("RateLimit-Reset", ceilSeconds(decision.Reset))
+			}
+
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(decision.RetryAfter))
+				if decision.QuotaExceeded {
+					log.Printf("Rejected %s: daily quota exceeded", client)
+					sendErrorResponse(w, http.StatusTooManyRequests,
+						fmt.Sprintf("Daily quota of %d requests exceeded", decision.DailyQuota))
+				} else {
+					log.Printf("Rejected %s: rate limit exceeded for %s", client, r.URL.Path)
+					sendErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
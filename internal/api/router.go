@@ -1,19 +1,107 @@
 package api
 
 import (
+	"log/slog"
+	"net/http"
+
 	"github.com/gorilla/mux"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/capture"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+	"github.com/toleubekov/check-iin-kaz/internal/tracing"
 )
 
-func SetupRouter(handler *Handler) *mux.Router {
+// RouterOptions holds the optional middleware dependencies of SetupRouter.
+type RouterOptions struct {
+	// Authenticator checks credentials; nil disables authentication, which
+	// is only meant for local development.
+	Authenticator *auth.Authenticator
+	// Limiter enforces rate limits and quotas; nil disables them.
+	Limiter *ratelimit.Limiter
+	// AccessLog receives one line per request; nil disables access logs.
+	AccessLog *slog.Logger
+	// Tracer records request spans; nil still propagates trace ids but
+	// records nothing.
+	Tracer *tracing.Tracer
+	// Capture records requests and responses for replay; nil disables it.
+	Capture *capture.Writer
+}
+
+func SetupRouter(handler *Handler, opts RouterOptions) *mux.Router {
+	tracer := opts.Tracer
+	if tracer == nil {
+		tracer = tracing.NewTracer(nil)
+	}
+
 	r := mux.NewRouter()
+	r.Use(requestMiddleware(opts.AccessLog, tracer))
+	if opts.Capture != nil {
+		// Before authentication and rate limiting, so that rejected requests
+		// are replayed too.
+		r.Use(captureMiddleware(opts.Capture))
+	}
+	r.Use(metricsMiddleware)
+	if opts.Limiter != nil {
+		// Before authentication, so that invalid credentials are throttled too.
+		r.Use(ipRateLimitMiddleware(opts.Limiter))
+	}
+	if opts.Authenticator != nil {
+		r.Use(authMiddleware(opts.Authenticator, handler.audit))
+	}
+	if opts.Limiter != nil {
+		// After authentication, so that clients are limited per API key rather than per IP.
+		r.Use(rateLimitMiddleware(opts.Limiter))
+	}
+
+	v1 := r.PathPrefix(apiVersionPrefix).Subrouter()
+	v1.HandleFunc("/iin_check/{iin}", handler.CheckIINInfo).Methods("GET")
+	registerRoutes(v1, handler)
+
+	// The unversioned routes predate /v1 and are kept for existing clients.
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(deprecationMiddleware)
+	legacy.HandleFunc("/iin_check/{iin}", handler.CheckIIN).Methods("GET")
+	registerRoutes(legacy, handler)
+
+	return r
+}
 
-	r.HandleFunc("/iin_check/{iin}", handler.CheckIIN).Methods("GET")
+// registerRoutes adds the routes that are the same in every API version.
+func registerRoutes(r *mux.Router, handler *Handler) {
+	r.HandleFunc("/people/info", handler.withIdempotency(handler.CreatePerson)).Methods("POST")
 
-	r.HandleFunc("/people/info", handler.CreatePerson).Methods("POST")
+	r.HandleFunc("/people/import", handler.ImportPeople).Methods("POST")
+
+	r.HandleFunc("/people/export", handler.ExportPeople).Methods("GET")
 
 	r.HandleFunc("/people/info/iin/{iin}", handler.GetPersonByIIN).Methods("GET")
 
 	r.HandleFunc("/people/info/name/{name_part}", handler.FindPeopleByNamePart).Methods("GET")
 
-	return r
+	r.HandleFunc("/jobs", handler.CreateJob).Methods("POST")
+
+	r.HandleFunc("/jobs/{id}", handler.GetJob).Methods("GET")
+
+	r.HandleFunc("/jobs/{id}/result", handler.GetJobResult).Methods("GET")
+
+	r.HandleFunc("/jobs/{id}", handler.CancelJob).Methods("DELETE")
+
+	r.HandleFunc("/audit", handler.FindAuditEvents).Methods("GET")
+
+	r.HandleFunc("/audit/verify", handler.VerifyAuditLog).Methods("GET")
+}
+
+// NewServeMux serves the API router together with the probes, /metrics and
+// the API description. Those are mounted outside the router so that
+// orchestrators and Prometheus need no API key; keep the port off the public
+// network.
+func NewServeMux(router http.Handler, health *HealthHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", health.Live)
+	mux.HandleFunc("GET /readyz", health.Ready)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.HandleFunc("GET /openapi.json", ServeOpenAPI)
+	mux.Handle("/", router)
+	return mux
 }
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const apiVersionPrefix = "/v1"
+
+// unversionedPath strips the version prefix from a route template, so that
+// roles and rate limit groups are the same for a route in every version.
+func unversionedPath(path string) string {
+	if rest := strings.TrimPrefix(path, apiVersionPrefix); rest != path && strings.HasPrefix(rest, "/") {
+		return rest
+	}
+	return path
+}
+
+// deprecationMiddleware marks responses of the unversioned routes as
+// deprecated and links to the /v1 route that replaces them.
+var deprecationMiddleware mux.MiddlewareFunc = func(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiVersionPrefix+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
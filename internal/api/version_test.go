@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+func TestDeprecationHeaders(t *testing.T) {
+	router := SetupRouter(newTestHandler(t, repository.NewMemoryPersonRepository()), RouterOptions{})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		// link is the expected Link header, empty for routes that are not deprecated.
+		link string
+	}{
+		{http.MethodGet, "/iin_check/" + testIIN1, "", http.StatusOK, `</v1/iin_check/` + testIIN1 + `>; rel="successor-version"`},
+		{http.MethodGet, "/people/info/iin/" + testIIN1, "", http.StatusNotFound, `</v1/people/info/iin/` + testIIN1 + `>; rel="successor-version"`},
+		{http.MethodPost, "/people/info", `{"name":"A","iin":"` + testIIN2 + `"}`, http.StatusOK, `</v1/people/info>; rel="successor-version"`},
+		{http.MethodGet, "/jobs/1", "", http.StatusNotFound, `</v1/jobs/1>; rel="successor-version"`},
+
+		{http.MethodGet, "/v1/iin_check/" + testIIN1, "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/people/info/iin/" + testIIN1, "", http.StatusNotFound, ""},
+		{http.MethodPost, "/v1/people/info", `{"name":"B","iin":"` + testIIN3 + `"}`, http.StatusOK, ""},
+		{http.MethodGet, "/v1/jobs/1", "", http.StatusNotFound, ""},
+		// Not a route at all.
+		{http.MethodGet, "/nowhere", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		contentType := ""
+		if tt.body != "" {
+			contentType = "application/json"
+		}
+		rec := serve(router, tt.method, tt.path, contentType, tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+		}
+
+		deprecation, link := rec.Header().Get("Deprecation"), rec.Header().Get("Link")
+		if tt.link == "" {
+			if deprecation != "" || link != "" {
+				t.Errorf("%s %s: Deprecation = %q, Link = %q, want neither", tt.method, tt.path, deprecation, link)
+			}
+			continue
+		}
+		if deprecation != "true" || link != tt.link {
+			t.Errorf("%s %s: Deprecation = %q, Link = %q, want true and %q", tt.method, tt.path, deprecation, link, tt.link)
+		}
+	}
+}
+
+func TestUnversionedPath(t *testing.T) {
+	tests := map[string]string{
+		"/v1/people/info":     "/people/info",
+		"/v1/iin_check/{iin}": "/iin_check/{iin}",
+		"/people/info":        "/people/info",
+		"/v1":                 "/v1",
+		"/v10/people":         "/v10/people",
+		"/api/v1/people":      "/api/v1/people",
+	}
+	for path, want := range tests {
+		if got := unversionedPath(path); got != want {
+			t.Errorf("unversionedPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// Package audit records who read or changed person data.
+//
+// Events are appended to the audit_log table, which is append-only and hash
+// chained (see repository.AuditRepository). IINs are never stored in the log;
+// only their keyed hash is, so auditors can still look up the history of a
+// given IIN.
+//
+// Requests never write to the table themselves: Log queues the event and a
+// single writer goroutine appends queued events in batches, so auditing adds
+// no database round trip to the request path. A batch that cannot be appended
+// is retried with backoff and then kept for the next attempt; events are only
+// dropped when more than maxPending are waiting or the logger is closed, and
+// are then counted in audit_events_total{outcome="dropped"}.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+const (
+	ActionCreate = "create"
+	ActionRead   = "read"
+	ActionSearch = "search"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionExport = "export"
+	// ActionDenied records a request for person data that was rejected for
+	// missing credentials or a missing role.
+	ActionDenied = "denied"
+)
+
+const (
+	// queueSize is how many events may wait for the writer before Log blocks.
+	queueSize = 1024
+	// maxBatch is the most events appended in one transaction.
+	maxBatch = 256
+	// maxPending is the most events kept for another attempt after their
+	// append failed; beyond it the oldest are dropped.
+	maxPending = 64 * maxBatch
+	// retryInterval is how often events kept after a failed append are
+	// retried while no new events arrive.
+	retryInterval = 10 * time.Second
+)
+
+// appendBackoff are the pauses between attempts to append a batch before the
+// writer keeps it for later.
+var appendBackoff = []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second}
+
+const anonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the identity of the caller.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the caller stored by WithActor, or "anonymous".
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// Entry describes who performed an action and from where; the IINs it
+// applies to are passed to Logger.Log separately.
+type Entry struct {
+	Actor     string
+	Action    string
+	RequestID string
+	ClientIP  string
+	Details   map[string]interface{}
+}
+
+// queued is an event waiting for the writer, or a Flush marker when flushed is set.
+type queued struct {
+	event   model.AuditEvent
+	flushed chan struct{}
+}
+
+type Logger struct {
+	repo    repository.AuditStore
+	hashKey []byte
+	backoff []time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan queued
+	stopped chan struct{}
+}
+
+// NewLogger creates a Logger and starts its writer; call Close to write the
+// remaining events and stop it. IINs are hashed with HMAC-SHA256 under
+// hashKey; without a key a plain SHA-256 is used, which is easy to reverse
+// for 12-digit numbers and should only be used in development.
+func NewLogger(repo repository.AuditStore, hashKey []byte) *Logger {
+	if len(hashKey) == 0 {
+		log.Println("Warning: AUDIT_HASH_KEY is not set, audit log IIN hashes are unkeyed")
+	}
+	l := &Logger{
+		repo:    repo,
+		hashKey: hashKey,
+		backoff: appendBackoff,
+		queue:   make(chan queued, queueSize),
+		stopped: make(chan struct{}),
+	}
+	go l.write()
+	return l
+}
+
+// HashIIN returns the value stored in audit_log.iin_hash for an IIN.
+func (l *Logger) HashIIN(iin string) string {
+	if len(l.hashKey) == 0 {
+		sum := sha256.Sum256([]byte(iin))
+		return hex.EncodeToString(sum[:])
+	}
+
+	mac := hmac.New(sha256.New, l.hashKey)
+	mac.Write([]byte(iin))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FromRequest fills an Entry with the actor, request id and client IP of r.
+func FromRequest(r *http.Request, action string) Entry {
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	return Entry{
+		Actor:     Actor(r.Context()),
+		Action:    action,
+		RequestID: r.Header.Get("X-Request-ID"),
+		ClientIP:  clientIP,
+	}
+}
+
+// Log queues one event for the request. A single IIN is recorded in
+// iin_hash, several (a search result, for example) in iin_hashes. Failures
+// are logged rather than returned so that auditing never changes the outcome
+// of the request being audited.
+func (l *Logger) Log(entry Entry, iins ...string) {
+	var details string
+	if len(entry.Details) > 0 {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			log.Printf("ERROR: Failed to encode audit details: %v", err)
+		}
+		details = string(encoded)
+	}
+
+	event := model.AuditEvent{
+		OccurredAt: time.Now(),
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		RequestID:  entry.RequestID,
+		ClientIP:   entry.ClientIP,
+		Details:    details,
+	}
+	switch len(iins) {
+	case 0:
+	case 1:
+		event.IINHash = l.HashIIN(iins[0])
+	default:
+		event.IINHashes = make([]string, len(iins))
+		for i, iin := range iins {
+			event.IINHashes[i] = l.HashIIN(iin)
+		}
+	}
+
+	if !l.enqueue(queued{event: event}) {
+		metrics.AuditEvents.Inc("dropped")
+		log.Printf("ERROR: Audit log is closed, dropped %s event of %s", event.Action, event.Actor)
+	}
+}
+
+// LogFailure records a failed or denied request with its HTTP status.
+func (l *Logger) LogFailure(r *http.Request, action string, status int, iins ...string) {
+	l.LogRequest(r, action, map[string]interface{}{"status": status}, iins...)
+}
+
+// LogRequest is a shortcut for Log(FromRequest(r, action)) with details.
+func (l *Logger) LogRequest(r *http.Request, action string, details map[string]interface{}, iins ...string) {
+	entry := FromRequest(r, action)
+	entry.Details = details
+	l.Log(entry, iins...)
+}
+
+// LogImport records the people an import created or updated; entry.Action is
+// set per event.
+func (l *Logger) LogImport(entry Entry, report *model.ImportReport) {
+	var created, updated []string
+	for _, row := range report.Rows {
+		switch row.Status {
+		case model.ImportStatusCreated:
+			created = append(created, row.IIN)
+		case model.ImportStatusUpdated:
+			updated = append(updated, row.IIN)
+		}
+	}
+
+	if len(created) > 0 {
+		entry.Action = ActionCreate
+		l.Log(entry, created...)
+	}
+	if len(updated) > 0 {
+		entry.Action = ActionUpdate
+		l.Log(entry, updated...)
+	}
+}
+
+// Find returns matching events, including every event logged before the call.
+func (l *Logger) Find(filter model.AuditFilter) ([]model.AuditEvent, error) {
+	l.Flush()
+	return l.repo.Find(filter)
+}
+
+func (l *Logger) Verify() (*model.AuditVerification, error) {
+	l.Flush()
+	return l.repo.Verify()
+}
+
+// Flush waits until every event logged before the call has been written, or
+// has failed to be written and is kept for a later attempt.
+func (l *Logger) Flush() {
+	flushed := make(chan struct{})
+	if l.enqueue(queued{flushed: flushed}) {
+		<-flushed
+	}
+}
+
+// Close writes the queued events and stops the writer. Events that still
+// cannot be written and events logged after Close are dropped.
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	<-l.stopped
+}
+
+func (l *Logger) enqueue(item queued) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return false
+	}
+	l.queue <- item
+	return true
+}
+
+// write is the only goroutine that appends to the audit log. It takes
+// whatever is queued, up to maxBatch events, and appends it in one call, so
+// under load the hash chain lock is taken once per batch, not per request.
+func (l *Logger) write() {
+	defer close(l.stopped)
+
+	// pending holds events whose append failed. They are retried ahead of
+	// newer events, so the chain keeps the order in which they were logged.
+	var pending []model.AuditEvent
+	retry := time.NewTicker(retryInterval)
+	defer retry.Stop()
+
+	for {
+		var item queued
+		var ok bool
+		select {
+		case item, ok = <-l.queue:
+		case <-retry.C:
+			if len(pending) > 0 {
+				pending = l.append(pending)
+			}
+			continue
+		}
+		if !ok {
+			break
+		}
+
+		var batch []model.AuditEvent
+		var flushed []chan struct{}
+		add := func(item queued) {
+			if item.flushed != nil {
+				flushed = append(flushed, item.flushed)
+			} else {
+				batch = append(batch, item.event)
+			}
+		}
+
+		add(item)
+		for more := true; more && len(batch) < maxBatch; {
+			select {
+			case item, ok := <-l.queue:
+				if ok {
+					add(item)
+				} else {
+					more = false
+				}
+			default:
+				more = false
+			}
+		}
+
+		if len(pending) > 0 || len(batch) > 0 {
+			pending = l.append(append(pending, batch...))
+		}
+		for _, done := range flushed {
+			close(done)
+		}
+	}
+
+	if len(pending) > 0 {
+		pending = l.append(pending)
+	}
+	if len(pending) > 0 {
+		metrics.AuditEvents.Add(float64(len(pending)), "dropped")
+		log.Printf("ERROR: Audit log is closed, dropped %d events that could not be written", len(pending))
+	}
+}
+
+// append writes events in batches of up to maxBatch and returns the ones it
+// could not write, at most maxPending of them.
+func (l *Logger) append(events []model.AuditEvent) []model.AuditEvent {
+	for len(events) > 0 {
+		batch := events[:min(len(events), maxBatch)]
+		if err := l.appendBatch(batch); err != nil {
+			log.Printf("ERROR: Failed to write %d audit events, keeping them for the next attempt: %v", len(events), err)
+			if dropped := len(events) - maxPending; dropped > 0 {
+				metrics.AuditEvents.Add(float64(dropped), "dropped")
+				log.Printf("ERROR: Dropped %d audit events, more than %d are waiting to be written", dropped, maxPending)
+				events = events[dropped:]
+			}
+			return events
+		}
+		metrics.AuditEvents.Add(float64(len(batch)), "written")
+		events = events[len(batch):]
+	}
+	return nil
+}
+
+// appendBatch appends batch, pausing l.backoff between failed attempts.
+func (l *Logger) appendBatch(batch []model.AuditEvent) error {
+	for attempt := 0; ; attempt++ {
+		err := l.repo.Append(batch)
+		if err == nil {
+			return nil
+		}
+		metrics.AuditAppendErrors.Inc()
+		if attempt == len(l.backoff) {
+			return err
+		}
+		log.Printf("ERROR: Failed to write %d audit events, retrying in %s: %v", len(batch), l.backoff[attempt], err)
+		time.Sleep(l.backoff[attempt])
+	}
+}
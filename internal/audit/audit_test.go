@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+// blockingStore records appended batches and holds each Append until release is closed.
+type blockingStore struct {
+	*repository.MemoryAuditRepository
+	release chan struct{}
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (s *blockingStore) Append(events []model.AuditEvent) error {
+	<-s.release
+	s.mu.Lock()
+	s.batches = append(s.batches, len(events))
+	s.mu.Unlock()
+	return s.MemoryAuditRepository.Append(events)
+}
+
+// failingStore fails the first failures appends.
+type failingStore struct {
+	*repository.MemoryAuditRepository
+
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *failingStore) Append(events []model.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("connection refused")
+	}
+	return s.MemoryAuditRepository.Append(events)
+}
+
+func TestLogDoesNotWaitForTheDatabase(t *testing.T) {
+	store := &blockingStore{MemoryAuditRepository: repository.NewMemoryAuditRepository(), release: make(chan struct{})}
+	logger := NewLogger(store, []byte("key"))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			logger.Log(Entry{Actor: "api_key:reader", Action: ActionRead}, "031231500126")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked on a slow audit store")
+	}
+
+	close(store.release)
+	events, err := logger.Find(model.AuditFilter{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 10 {
+		t.Fatalf("Find returned %d events after Flush, want 10", len(events))
+	}
+	// Whatever the writer took before the store blocked went in the first
+	// batch; everything queued behind it went in the second.
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.batches) > 2 {
+		t.Errorf("batches = %v, want at most two", store.batches)
+	}
+}
+
+func TestLogRecordsOneEventPerRequest(t *testing.T) {
+	repo := repository.NewMemoryAuditRepository()
+	logger := NewLogger(repo, []byte("key"))
+
+	logger.Log(Entry{Actor: "a", Action: ActionSearch})
+	logger.Log(Entry{Actor: "a", Action: ActionRead}, "031231500126")
+	logger.Log(Entry{Actor: "a", Action: ActionSearch, Details: map[string]interface{}{"results": 2}}, "031231500126", "900101300017")
+
+	events, err := logger.Find(model.AuditFilter{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("events = %+v, want 3", events)
+	}
+	first, second := logger.HashIIN("031231500126"), logger.HashIIN("900101300017")
+	if events[0].IINHash != "" || len(events[0].IINHashes) != 0 {
+		t.Errorf("event without IINs = %+v", events[0])
+	}
+	if events[1].IINHash != first || len(events[1].IINHashes) != 0 {
+		t.Errorf("event with one IIN = %+v", events[1])
+	}
+	if events[2].IINHash != "" || len(events[2].IINHashes) != 2 || events[2].IINHashes[0] != first || events[2].IINHashes[1] != second {
+		t.Errorf("event with two IINs = %+v", events[2])
+	}
+
+	byIIN, _ := logger.Find(model.AuditFilter{IINHash: second, Limit: 100})
+	if len(byIIN) != 1 || byIIN[0].ID != events[2].ID {
+		t.Errorf("events for the second IIN = %+v", byIIN)
+	}
+	if verification, _ := logger.Verify(); !verification.Valid || verification.Checked != 3 {
+		t.Errorf("verification = %+v", verification)
+	}
+}
+
+func TestCloseWritesQueuedEvents(t *testing.T) {
+	repo := repository.NewMemoryAuditRepository()
+	logger := NewLogger(repo, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Log(Entry{Actor: "a", Action: ActionRead})
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Close()
+	logger.Close()
+
+	// Dropped, not a panic on the closed queue.
+	logger.Log(Entry{Actor: "a", Action: ActionRead})
+
+	verification, err := repo.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid || verification.Checked != 800 {
+		t.Errorf("verification = %+v, want 800 chained events", verification)
+	}
+}
+
+func TestFailedAppendsAreRetried(t *testing.T) {
+	store := &failingStore{MemoryAuditRepository: repository.NewMemoryAuditRepository(), failures: 3}
+	logger := NewLogger(store, nil)
+	logger.backoff = []time.Duration{time.Millisecond}
+
+	// Two attempts fail, so the first event is kept for later.
+	logger.Log(Entry{Actor: "a", Action: ActionCreate})
+	logger.Flush()
+	if events, _ := store.Find(model.AuditFilter{Limit: 100}); len(events) != 0 {
+		t.Fatalf("events after two failed attempts = %+v", events)
+	}
+
+	// The next batch fails once more and then writes both, oldest first.
+	logger.Log(Entry{Actor: "a", Action: ActionRead})
+	events, err := logger.Find(model.AuditFilter{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Action != ActionCreate || events[1].Action != ActionRead {
+		t.Fatalf("events = %+v, want the create and then the read", events)
+	}
+	if verification, _ := logger.Verify(); !verification.Valid || verification.Checked != 2 {
+		t.Errorf("verification = %+v", verification)
+	}
+}
+
+func TestCloseDropsEventsThatCannotBeWritten(t *testing.T) {
+	store := &failingStore{MemoryAuditRepository: repository.NewMemoryAuditRepository(), failures: 1 << 30}
+	logger := NewLogger(store, nil)
+	logger.backoff = nil
+
+	logger.Log(Entry{Actor: "a", Action: ActionRead})
+	done := make(chan struct{})
+	go func() {
+		logger.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not give up on a failing audit store")
+	}
+}
@@ -0,0 +1,159 @@
+// Package auth authenticates API callers and checks their roles.
+//
+// Two kinds of credentials are accepted: static API keys, stored hashed in
+// the api_keys table and managed with cmd/apikey, and JWT bearer tokens signed
+// with HS256 or RS256 keys configured locally. Both carry a list of roles:
+//
+//	checker - IIN validation (/iin_check)
+//	reader  - read-only access to people, exports and jobs
+//	writer  - creating and changing people and jobs
+//	admin   - everything, including the audit log
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+const (
+	RoleChecker = "checker"
+	RoleReader  = "reader"
+	RoleWriter  = "writer"
+	RoleAdmin   = "admin"
+)
+
+// apiKeyPrefix marks API keys so they can also be sent as bearer tokens.
+const apiKeyPrefix = "ik_"
+
+var (
+	// ErrNoCredentials means the request carried neither an API key nor a bearer token.
+	ErrNoCredentials = errors.New("authentication required")
+	// ErrInvalidCredentials means the credentials were present but not accepted.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Method  string // "api_key" or "jwt"
+	Subject string // API key name or JWT subject
+	Roles   []string
+}
+
+// Actor returns the identity recorded in the audit log, e.g. "api_key:billing".
+func (p *Principal) Actor() string {
+	return p.Method + ":" + p.Subject
+}
+
+// HasRole reports whether the principal has role; admins have every role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleChecker, RoleReader, RoleWriter, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, or nil if the request
+// was not authenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+type Authenticator struct {
+	apiKeys repository.APIKeyStore
+	jwt     *JWTVerifier
+}
+
+// NewAuthenticator creates an Authenticator. jwt may be nil to accept API keys only.
+func NewAuthenticator(apiKeys repository.APIKeyStore, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{apiKeys: apiKeys, jwt: jwt}
+}
+
+// Authenticate reads credentials from the X-API-Key header or an
+// "Authorization: Bearer" header holding either an API key or a JWT.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	return a.AuthenticateHeaders(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// AuthenticateHeaders is Authenticate for callers that are not HTTP
+// requests, such as gRPC calls carrying the same values as metadata.
+func (a *Authenticator) AuthenticateHeaders(apiKey, authorization string) (*Principal, error) {
+	if apiKey != "" {
+		return a.authenticateAPIKey(apiKey)
+	}
+
+	header := authorization
+	if header == "" {
+		return nil, ErrNoCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return a.authenticateAPIKey(token)
+	}
+	if a.jwt == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims, err := a.jwt.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return &Principal{Method: "jwt", Subject: claims.Subject, Roles: claims.Roles}, nil
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	stored, err := a.apiKeys.GetActiveByHash(HashAPIKey(key))
+	if err != nil {
+		if err.Error() == "API key not found" {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	return &Principal{Method: "api_key", Subject: stored.Name, Roles: stored.Roles}, nil
+}
+
+// GenerateAPIKey returns a new random API key; only its HashAPIKey is stored.
+func GenerateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashAPIKey returns the value stored in api_keys.key_hash. API keys are long
+// random strings, so an unsalted SHA-256 is enough to make a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far exp and nbf may be off to tolerate unsynchronized clocks.
+const clockSkew = 30 * time.Second
+
+// Claims are the JWT claims the service understands.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// audience accepts both forms of the aud claim: a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+// JWTVerifier checks HS256 and RS256 signed tokens. Only algorithms with a
+// configured key are accepted, so a token cannot choose a weaker algorithm.
+type JWTVerifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	now        func() time.Time
+}
+
+// NewJWTVerifier creates a verifier. hmacSecret enables HS256 and rsaKey
+// enables RS256; at least one is required. Empty issuer or audience are not checked.
+func NewJWTVerifier(hmacSecret []byte, rsaKey *rsa.PublicKey, issuer, audience string) (*JWTVerifier, error) {
+	if len(hmacSecret) == 0 && rsaKey == nil {
+		return nil, errors.New("JWT verification needs an HS256 secret or an RS256 public key")
+	}
+	if len(hmacSecret) > 0 && len(hmacSecret) < 32 {
+		return nil, errors.New("HS256 secret must be at least 32 bytes")
+	}
+	return &JWTVerifier{
+		hmacSecret: hmacSecret,
+		rsaKey:     rsaKey,
+		issuer:     issuer,
+		audience:   audience,
+		now:        time.Now,
+	}, nil
+}
+
+// ParseRSAPublicKey reads an RSA public key from PEM (PKIX or PKCS #1).
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in RS256 public key")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RS256 public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("RS256 public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Verify checks the token signature and its exp, nbf, iss and aud claims.
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	if err := v.verifySignature(header.Algorithm, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *JWTVerifier) verifySignature(algorithm, signingInput string, signature []byte) error {
+	switch algorithm {
+	case "HS256":
+		if len(v.hmacSecret) == 0 {
+			break
+		}
+		mac := hmac.New(sha256.New, v.hmacSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case "RS256":
+		if v.rsaKey == nil {
+			break
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported token algorithm %q", algorithm)
+}
+
+func (v *JWTVerifier) validateClaims(claims *Claims) error {
+	now := v.now()
+	if claims.Subject == "" {
+		return errors.New("token has no subject")
+	}
+	if claims.ExpiresAt == 0 {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return errors.New("token issuer is not accepted")
+	}
+	if v.audience != "" {
+		found := false
+		for _, aud := range claims.Audience {
+			if aud == v.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("token audience is not accepted")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/auth/authtest"
+)
+
+var testSecret = []byte(strings.Repeat("s", 32))
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "billing",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{RoleReader},
+		"iss":   "issuer",
+		"aud":   []string{"other", "check-iin"},
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	verifier, err := NewJWTVerifier(testSecret, nil, "issuer", "check-iin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifier.Verify(authtest.SignHS256(t, testSecret, validClaims()))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "billing" || len(claims.Roles) != 1 || claims.Roles[0] != RoleReader {
+		t.Errorf("Verify returned %+v", claims)
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		modify func(map[string]interface{})
+	}{
+		{"wrong secret", []byte(strings.Repeat("x", 32)), nil},
+		{"expired", testSecret, func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"no expiry", testSecret, func(c map[string]interface{}) { delete(c, "exp") }},
+		{"not yet valid", testSecret, func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Minute).Unix() }},
+		{"wrong issuer", testSecret, func(c map[string]interface{}) { c["iss"] = "someone" }},
+		{"wrong audience", testSecret, func(c map[string]interface{}) { c["aud"] = "other" }},
+		{"no subject", testSecret, func(c map[string]interface{}) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		claims := validClaims()
+		if tt.modify != nil {
+			tt.modify(claims)
+		}
+		if _, err := verifier.Verify(authtest.SignHS256(t, tt.secret, claims)); err == nil {
+			t.Errorf("%s: Verify accepted the token", tt.name)
+		}
+	}
+}
+
+func TestVerifyRejectsUnconfiguredAlgorithms(t *testing.T) {
+	verifier, err := NewJWTVerifier(testSecret, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned := authtest.EncodeSegment(t, map[string]string{"alg": "none"}) + "." + authtest.EncodeSegment(t, validClaims()) + "."
+	if _, err := verifier.Verify(unsigned); err == nil {
+		t.Error("Verify accepted an unsigned token")
+	}
+
+	rs256 := authtest.EncodeSegment(t, map[string]string{"alg": "RS256"}) + "." + authtest.EncodeSegment(t, validClaims()) + ".c2ln"
+	if _, err := verifier.Verify(rs256); err == nil {
+		t.Error("Verify accepted RS256 without a configured public key")
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewJWTVerifier(nil, &key.PublicKey, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := authtest.EncodeSegment(t, map[string]string{"alg": "RS256"}) + "." + authtest.EncodeSegment(t, validClaims())
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := input + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// An HS256 token signed with the public key must not be accepted.
+	if _, err := verifier.Verify(authtest.SignHS256(t, key.PublicKey.N.Bytes(), validClaims())); err == nil {
+		t.Error("Verify accepted an HS256 token when only RS256 is configured")
+	}
+}
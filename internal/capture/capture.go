@@ -0,0 +1,144 @@
+// Package capture records API traffic to a JSONL file, one Record per line,
+// for cmd/stress-test to replay.
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+)
+
+// Record is one captured request and the response it got.
+type Record struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	// Path includes the query string.
+	Path string `json:"path"`
+	// Route is the route template, e.g. /v1/people/info/iin/{iin}.
+	Route string `json:"route"`
+	// Headers holds the replayable request headers, see ReplayedHeaders.
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    []byte            `json:"body,omitempty"`
+	// BodyTruncated is set when the request body was larger than the capture
+	// limit; such requests cannot be replayed.
+	BodyTruncated bool    `json:"body_truncated,omitempty"`
+	Status        int     `json:"status"`
+	DurationMS    float64 `json:"duration_ms"`
+	Response      []byte  `json:"response,omitempty"`
+	// ResponseTruncated is set when only the start of the response was kept.
+	ResponseTruncated bool `json:"response_truncated,omitempty"`
+	// Masked is set when IINs were replaced with iin.Mask.
+	Masked bool `json:"masked,omitempty"`
+}
+
+// ReplayedHeaders are the request headers worth capturing. Credentials are
+// deliberately left out; the replaying side brings its own.
+var ReplayedHeaders = []string{"Content-Type", "Idempotency-Key"}
+
+// MaskedIINPattern matches an IIN masked by iin.Mask.
+var MaskedIINPattern = regexp.MustCompile(`[0-9]{2}\*{8}[0-9]{2}`)
+
+// MaskIINs replaces every twelve-digit number in data with iin.Mask of it.
+// Longer and shorter runs of digits are left alone.
+func MaskIINs(data []byte) []byte {
+	masked := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		j := i
+		for j < len(data) && data[j] >= '0' && data[j] <= '9' {
+			j++
+		}
+		if j == i {
+			masked = append(masked, data[i])
+			i++
+			continue
+		}
+		if j-i == 12 {
+			masked = append(masked, iin.Mask(string(data[i:j]))...)
+		} else {
+			masked = append(masked, data[i:j]...)
+		}
+		i = j
+	}
+	return masked
+}
+
+// DefaultMaxBody is the default number of body bytes kept per request and
+// response.
+const DefaultMaxBody = 64 << 10
+
+// Writer appends records to a JSONL stream; it is safe for concurrent use.
+// Set the exported fields before the first Write.
+type Writer struct {
+	// MaskIINs replaces IINs in paths and bodies with iin.Mask before
+	// writing.
+	MaskIINs bool
+	// MaxBody is the number of bytes kept of each request and response body.
+	MaxBody int
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, MaskIINs: true, MaxBody: DefaultMaxBody}
+}
+
+// OpenFile appends records to the file at path; close the returned file on
+// shutdown.
+func OpenFile(path string) (*Writer, *os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	return NewWriter(file), file, nil
+}
+
+func (w *Writer) Write(record *Record) error {
+	if w.MaskIINs {
+		record.Path = string(MaskIINs([]byte(record.Path)))
+		record.Body = MaskIINs(record.Body)
+		record.Response = MaskIINs(record.Response)
+		record.Masked = true
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(line)
+	return err
+}
+
+// ReadFile reads all records of a capture file.
+func ReadFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	decoder := json.NewDecoder(file)
+	for {
+		var record Record
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse capture file %s, record %d: %w", path, len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
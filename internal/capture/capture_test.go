@@ -0,0 +1,59 @@
+package capture
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaskIINs(t *testing.T) {
+	tests := map[string]string{
+		`/v1/people/info/iin/031231500126`:              `/v1/people/info/iin/03********26`,
+		`{"iin":"031231500126","phone":"+77011234567"}`: `{"iin":"03********26","phone":"+77011234567"}`,
+		`031231500126,860904350504`:                     `03********26,86********04`,
+		`too long 0312315001260`:                        `too long 0312315001260`,
+	}
+	for input, want := range tests {
+		if got := string(MaskIINs([]byte(input))); got != want {
+			t.Errorf("MaskIINs(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestWriteAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	writer, file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []*Record{
+		{Method: "GET", Path: "/v1/iin_check/031231500126", Status: 200},
+		{Method: "POST", Path: "/v1/people/info", Body: []byte(`{"iin":"031231500126"}`), Status: 201},
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("read %d records, want 2", len(read))
+	}
+	if read[0].Path != "/v1/iin_check/03********26" || !read[0].Masked {
+		t.Errorf("record 0 = %+v, want a masked path", read[0])
+	}
+	if !bytes.Equal(read[1].Body, []byte(`{"iin":"03********26"}`)) {
+		t.Errorf("record 1 body = %s", read[1].Body)
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("031231500126")) {
+		t.Error("capture file contains an unmasked IIN")
+	}
+}
@@ -0,0 +1,303 @@
+// Package config loads the service configuration.
+//
+// Settings are resolved in this order, later sources overriding earlier ones:
+//
+//  1. built-in defaults (see Default);
+//  2. a YAML or JSON file named by the -config flag or CONFIG_FILE;
+//  3. environment variables, e.g. DB_HOST, where any variable can instead be
+//     read from a file named by the same variable with a _FILE suffix
+//     (DB_PASSWORD_FILE=/run/secrets/db_password);
+//  4. command-line flags named after the file keys, e.g. -database.host.
+//
+// Every setting is described by the struct tags of Config: yaml is the file
+// key (and flag name), env the environment variable, and secret marks values
+// that PrintYAML redacts.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Database    Database    `yaml:"database"`
+	Server      Server      `yaml:"server"`
+	Jobs        Jobs        `yaml:"jobs"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Audit       Audit       `yaml:"audit"`
+	Encryption  Encryption  `yaml:"encryption"`
+	Auth        Auth        `yaml:"auth"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	Logging     Logging     `yaml:"logging"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Capture     Capture     `yaml:"capture"`
+	Validation  Validation  `yaml:"validation"`
+}
+
+type Database struct {
+	URL             string `yaml:"url" env:"DATABASE_URL" secret:"true" usage:"PostgreSQL connection URL, overrides the other database settings"`
+	Host            string `yaml:"host" env:"DB_HOST" usage:"database host"`
+	Port            int    `yaml:"port" env:"DB_PORT" usage:"database port"`
+	User            string `yaml:"user" env:"DB_USER" usage:"database user"`
+	Password        string `yaml:"password" env:"DB_PASSWORD" secret:"true" usage:"database password"`
+	Name            string `yaml:"name" env:"DB_NAME" usage:"database name"`
+	SSLMode         string `yaml:"sslmode" env:"DB_SSLMODE" usage:"sslmode: disable, require, verify-ca or verify-full"`
+	ConnectAttempts int    `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS" usage:"connection attempts at startup"`
+}
+
+type Server struct {
+	Port            int           `yaml:"port" env:"SERVER_PORT" usage:"HTTP port"`
+	GRPCPort        int           `yaml:"grpc_port" env:"GRPC_PORT" usage:"gRPC port, 0 to disable gRPC"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"maximum time to read a request"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"maximum time to write a response"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"keep-alive idle timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"time to drain requests on shutdown"`
+}
+
+type Jobs struct {
+	Workers int `yaml:"workers" env:"JOB_WORKERS" usage:"background job workers"`
+}
+
+type Idempotency struct {
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" usage:"how long Idempotency-Key responses are kept"`
+}
+
+type Audit struct {
+	HashKey string `yaml:"hash_key" env:"AUDIT_HASH_KEY" secret:"true" usage:"HMAC key for IIN hashes in the audit log"`
+}
+
+type Encryption struct {
+	Keys       string `yaml:"keys" env:"ENCRYPTION_KEYS" secret:"true" usage:"encryption keys as id:base64,..."`
+	ActiveKey  string `yaml:"active_key" env:"ENCRYPTION_ACTIVE_KEY" usage:"id of the key new values are encrypted with"`
+	IINHMACKey string `yaml:"iin_hmac_key" env:"IIN_HMAC_KEY" secret:"true" usage:"base64 HMAC key for IIN lookups"`
+}
+
+type Auth struct {
+	Enabled               bool   `yaml:"enabled" env:"AUTH_ENABLED" usage:"require API keys or JWTs"`
+	JWTHS256Secret        string `yaml:"jwt_hs256_secret" env:"JWT_HS256_SECRET" secret:"true" usage:"HS256 JWT secret"`
+	JWTRS256PublicKeyFile string `yaml:"jwt_rs256_public_key_file" env:"JWT_RS256_PUBLIC_KEY_FILE" usage:"PEM file with the RS256 JWT public key"`
+	JWTIssuer             string `yaml:"jwt_issuer" env:"JWT_ISSUER" usage:"required JWT issuer"`
+	JWTAudience           string `yaml:"jwt_audience" env:"JWT_AUDIENCE" usage:"required JWT audience"`
+}
+
+type RateLimit struct {
+	Limits     string `yaml:"limits" env:"RATE_LIMITS" usage:"token buckets as route=rate:burst,..., or off"`
+	DailyQuota int    `yaml:"daily_quota" env:"DAILY_QUOTA" usage:"requests per client per day, 0 for no quota"`
+}
+
+type Logging struct {
+	AccessLog bool `yaml:"access_log" env:"ACCESS_LOG" usage:"write JSON access logs to stdout"`
+}
+
+type Tracing struct {
+	Exporter    string `yaml:"exporter" env:"TRACING_EXPORTER" usage:"span exporter: none, stdout or file"`
+	File        string `yaml:"file" env:"TRACING_FILE" usage:"OTLP/JSON file for the file exporter"`
+	ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME" usage:"service.name of exported spans"`
+}
+
+type Capture struct {
+	File    string `yaml:"file" env:"CAPTURE_FILE" usage:"JSONL file to record API requests to for replay, empty to disable"`
+	MaskIIN bool   `yaml:"mask_iin" env:"CAPTURE_MASK_IIN" usage:"mask IINs in captured requests"`
+	MaxBody int    `yaml:"max_body" env:"CAPTURE_MAX_BODY" usage:"bytes kept of each captured request and response body"`
+}
+
+type Validation struct {
+	AllowNonCitizen bool `yaml:"allow_non_citizen" env:"IIN_ALLOW_NON_CITIZEN" usage:"accept IINs of foreigners and legacy numbers (7th digit 0 or 7-9) everywhere IINs are validated"`
+}
+
+// Default returns the built-in defaults. There is deliberately no default
+// database password or key material.
+func Default() *Config {
+	return &Config{
+		Database: Database{
+			Host:            "postgres",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "postgres",
+			SSLMode:         "disable",
+			ConnectAttempts: 10,
+		},
+		Server: Server{
+			Port:            8080,
+			GRPCPort:        9090,
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    60 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Jobs:        Jobs{Workers: 2},
+		Idempotency: Idempotency{TTL: 24 * time.Hour},
+		Auth:        Auth{Enabled: true},
+		RateLimit:   RateLimit{Limits: "ip=50:200,iin_check=5:20,default=20:100", DailyQuota: 10000},
+		Logging:     Logging{AccessLog: true},
+		Tracing:     Tracing{Exporter: "none", ServiceName: "check-iin-kaz"},
+		Capture:     Capture{MaskIIN: true, MaxBody: 64 << 10},
+	}
+}
+
+// DSN returns the connection string for lib/pq.
+func (d Database) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(d.Host), d.Port, quoteDSN(d.User), quoteDSN(d.Password), quoteDSN(d.Name), quoteDSN(d.SSLMode))
+}
+
+// MigrateURL returns the connection settings as a postgres:// URL, the only
+// form golang-migrate accepts.
+func (d Database) MigrateURL() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// quoteDSN quotes a key=value connection string value so that passwords with
+// spaces or quotes survive.
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// Validate checks the settings every command needs. Key material is checked
+// where it is used, by fieldcrypt and auth, which give more precise errors.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			problems = append(problems, "database.url must be a postgres:// URL")
+		}
+	} else {
+		if c.Database.Password == "" {
+			problems = append(problems, "database.password (DB_PASSWORD) or database.url (DATABASE_URL) is required")
+		}
+		if c.Database.Host == "" || c.Database.Name == "" || c.Database.User == "" {
+			problems = append(problems, "database.host, database.user and database.name must not be empty")
+		}
+	}
+	if c.Database.ConnectAttempts < 1 {
+		problems = append(problems, "database.connect_attempts must be at least 1")
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, "server.port must be between 1 and 65535")
+	}
+	if c.Server.GRPCPort < 0 || c.Server.GRPCPort > 65535 {
+		problems = append(problems, "server.grpc_port must be between 0 and 65535")
+	} else if c.Server.GRPCPort == c.Server.Port {
+		problems = append(problems, "server.grpc_port must differ from server.port")
+	}
+	if c.Jobs.Workers < 1 {
+		problems = append(problems, "jobs.workers must be at least 1")
+	}
+	if c.Idempotency.TTL <= 0 {
+		problems = append(problems, "idempotency.ttl must be positive")
+	}
+	if c.RateLimit.DailyQuota < 0 {
+		problems = append(problems, "rate_limit.daily_quota must not be negative")
+	}
+	if c.Capture.MaxBody < 0 {
+		problems = append(problems, "capture.max_body must not be negative")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			problems = append(problems, "tracing.file is required for the file exporter")
+		}
+	default:
+		problems = append(problems, "tracing.exporter must be none, stdout or file")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Load resolves the configuration from defaults, the config file, the
+// environment and args. The config flags, plus -config, are added to fs,
+// which may already hold command-specific flags; fs is parsed by Load.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	fields := map[string]setting{}
+	for _, field := range settings(cfg) {
+		fields[field.key] = field
+	}
+
+	configFile := fs.String("config", "", "YAML or JSON config file (default $CONFIG_FILE)")
+	flagValues := map[string]*string{}
+	for _, field := range fields {
+		value := new(string)
+		flagValues[field.key] = value
+		fs.Var((*rawFlag)(value), field.key, field.usage+" ($"+field.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range fields {
+		value, ok, err := lookupEnv(getenv, field.env)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := field.set(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", field.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		field, ok := fields[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := field.set(*flagValues[f.Name]); err != nil {
+			flagErr = fmt.Errorf("-%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	return cfg, nil
+}
+
+// rawFlag stores the flag text; it is converted only after the file and the
+// environment have been applied, so that flags win.
+type rawFlag string
+
+func (f *rawFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *rawFlag) Set(value string) error {
+	*f = rawFlag(value)
+	return nil
+}
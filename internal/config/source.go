@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// setting is one leaf field of Config.
+type setting struct {
+	key    string // dotted file key and flag name, e.g. database.host
+	env    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists the leaf fields of cfg in declaration order.
+func settings(cfg *Config) []setting {
+	var result []setting
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i)
+		sectionValue := root.Field(i)
+		for j := 0; j < sectionValue.NumField(); j++ {
+			field := section.Type.Field(j)
+			result = append(result, setting{
+				key:    section.Tag.Get("yaml") + "." + field.Tag.Get("yaml"),
+				env:    field.Tag.Get("env"),
+				usage:  field.Tag.Get("usage"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  sectionValue.Field(j),
+			})
+		}
+	}
+	return result
+}
+
+func (s setting) set(text string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(text)
+	case int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		s.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		s.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		s.value.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// format returns the value as a YAML scalar.
+func (s setting) format() string {
+	switch v := s.value.Interface().(type) {
+	case string:
+		if s.secret && v != "" {
+			return strconv.Quote(redacted)
+		}
+		return strconv.Quote(v)
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// lookupEnv reads name from the environment, or the contents of the file
+// named by name_FILE. Setting both is an error.
+func lookupEnv(getenv func(string) string, name string) (string, bool, error) {
+	value := getenv(name)
+	file := getenv(name + "_FILE")
+	switch {
+	case file != "" && value != "":
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", name, name)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	case value != "":
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// loadFile applies a YAML file over cfg; JSON files are valid YAML. Unknown
+// keys are rejected so that typos do not go unnoticed.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// PrintYAML writes the effective configuration as YAML, with secrets redacted.
+func (c *Config) PrintYAML(w io.Writer) error {
+	var buf bytes.Buffer
+	section := ""
+	for _, s := range settings(c) {
+		name, key, _ := strings.Cut(s.key, ".")
+		if name != section {
+			section = name
+			fmt.Fprintf(&buf, "%s:\n", name)
+		}
+		fmt.Fprintf(&buf, "  %s: %s\n", key, s.format())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
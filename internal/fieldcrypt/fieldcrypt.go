@@ -0,0 +1,150 @@
+// Package fieldcrypt encrypts individual database fields with AES-256-GCM.
+//
+// A Keyring holds several named keys so they can be rotated: new values are
+// always encrypted with the active key and the key id is stored next to the
+// ciphertext, while older keys stay available for decryption until every row
+// has been re-encrypted. A separate HMAC key produces deterministic lookup
+// hashes for values that must be searchable by equality, such as the IIN.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const keySize = 32
+
+type Keyring struct {
+	aeads   map[string]cipher.AEAD
+	active  string
+	hmacKey []byte
+}
+
+// NewKeyring creates a Keyring from 32-byte AES keys indexed by key id.
+// activeKeyID selects the key used for encryption; hmacKey is used by LookupHash.
+func NewKeyring(keys map[string][]byte, activeKeyID string, hmacKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one encryption key is required")
+	}
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not configured", activeKeyID)
+	}
+	if len(hmacKey) < keySize {
+		return nil, fmt.Errorf("HMAC key must be at least %d bytes", keySize)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aeads[id] = aead
+	}
+
+	return &Keyring{aeads: aeads, active: activeKeyID, hmacKey: hmacKey}, nil
+}
+
+// ParseKeys parses a comma-separated list of id:base64key pairs, for example
+// "2024-01:3q2+7w...,2025-01:q83vEj...".
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid encryption key entry %q, expected id:base64key", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in encryption key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// LoadKeyring builds a Keyring from its textual configuration: keysSpec in the
+// ParseKeys format, the active key id (optional when only one key is
+// configured) and the base64-encoded HMAC key.
+func LoadKeyring(keysSpec, activeKeyID, hmacKeyBase64 string) (*Keyring, error) {
+	keys, err := ParseKeys(keysSpec)
+	if err != nil {
+		return nil, err
+	}
+	if activeKeyID == "" && len(keys) == 1 {
+		for id := range keys {
+			activeKeyID = id
+		}
+	}
+
+	hmacKey, err := base64.StdEncoding.DecodeString(hmacKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 in HMAC key: %w", err)
+	}
+
+	return NewKeyring(keys, activeKeyID, hmacKey)
+}
+
+// ActiveKeyID returns the id of the key new values are encrypted with.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt seals plaintext with the active key. The field name is bound as
+// additional data, so a ciphertext copied into another column fails to decrypt.
+// The result is the random nonce followed by the sealed value.
+func (k *Keyring) Encrypt(field, plaintext string) ([]byte, error) {
+	aead := k.aeads[k.active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, []byte(plaintext), []byte(field)), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the key it was encrypted with.
+func (k *Keyring) Decrypt(keyID, field string, ciphertext []byte) (string, error) {
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("encryption key %q is not configured", keyID)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+// LookupHash returns a keyed HMAC-SHA256 of value for equality lookups and
+// unique constraints on encrypted fields.
+func (k *Keyring) LookupHash(value string) []byte {
+	mac := hmac.New(sha256.New, k.hmacKey)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
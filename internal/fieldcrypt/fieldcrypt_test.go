@@ -0,0 +1,69 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func TestEncryptDecryptAcrossRotation(t *testing.T) {
+	hmacKey := testKey(9)
+	oldRing, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := oldRing.Encrypt("people.iin", "031231500126")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRing, err := NewKeyring(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2", hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := newRing.Decrypt("k1", "people.iin", ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt with rotated keyring: %v", err)
+	}
+	if plaintext != "031231500126" {
+		t.Errorf("Decrypt = %q, want %q", plaintext, "031231500126")
+	}
+
+	if _, err := newRing.Decrypt("k1", "people.phone", ciphertext); err == nil {
+		t.Error("Decrypt succeeded for a ciphertext moved to another field")
+	}
+	if _, err := newRing.Decrypt("k2", "people.iin", ciphertext); err == nil {
+		t.Error("Decrypt succeeded with the wrong key")
+	}
+	if !bytes.Equal(oldRing.LookupHash("031231500126"), newRing.LookupHash("031231500126")) {
+		t.Error("LookupHash changed although the HMAC key did not")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+	hmacKey := encode(testKey(9))
+
+	ring, err := LoadKeyring("k1:"+encode(testKey(1)), "", hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.ActiveKeyID() != "k1" {
+		t.Errorf("ActiveKeyID = %q, want k1", ring.ActiveKeyID())
+	}
+
+	for name, spec := range map[string][2]string{
+		"ambiguous active key": {"k1:" + encode(testKey(1)) + ",k2:" + encode(testKey(2)), ""},
+		"unknown active key":   {"k1:" + encode(testKey(1)), "k3"},
+		"short key":            {"k1:" + encode([]byte("short")), "k1"},
+		"missing id":           {encode(testKey(1)), ""},
+	} {
+		if _, err := LoadKeyring(spec[0], spec[1], hmacKey); err == nil {
+			t.Errorf("%s: LoadKeyring succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,534 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: iinpb/iin.proto
+
+// The gRPC counterpart of the HTTP API. Calls authenticate with the same
+// credentials as HTTP, sent as "x-api-key" or "authorization: Bearer ..."
+// metadata, and need the same roles.
+
+package iinpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CheckIINRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Iin           string                 `protobuf:"bytes,1,opt,name=iin,proto3" json:"iin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckIINRequest) Reset() {
+	*x = CheckIINRequest{}
+	mi := &file_iinpb_iin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckIINRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckIINRequest) ProtoMessage() {}
+
+func (x *CheckIINRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckIINRequest.ProtoReflect.Descriptor instead.
+func (*CheckIINRequest) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckIINRequest) GetIin() string {
+	if x != nil {
+		return x.Iin
+	}
+	return ""
+}
+
+// Mirrors the /v1/iin_check response.
+type CheckIINResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Iin     string                 `protobuf:"bytes,1,opt,name=iin,proto3" json:"iin,omitempty"`
+	Correct bool                   `protobuf:"varint,2,opt,name=correct,proto3" json:"correct,omitempty"`
+	Sex     string                 `protobuf:"bytes,3,opt,name=sex,proto3" json:"sex,omitempty"`
+	// YYYY-MM-DD.
+	DateOfBirth  string `protobuf:"bytes,4,opt,name=date_of_birth,json=dateOfBirth,proto3" json:"date_of_birth,omitempty"`
+	Century      int32  `protobuf:"varint,5,opt,name=century,proto3" json:"century,omitempty"`
+	SerialNumber int32  `protobuf:"varint,6,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	Age          int32  `protobuf:"varint,7,opt,name=age,proto3" json:"age,omitempty"`
+	// The metrics reason label, e.g. "checksum".
+	ErrorCode string `protobuf:"bytes,8,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	Error     string `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	// "citizen", or if the server accepts them "foreign" or "unknown", which
+	// have no sex, date of birth, century or age.
+	Category      string `protobuf:"bytes,10,opt,name=category,proto3" json:"category,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckIINResponse) Reset() {
+	*x = CheckIINResponse{}
+	mi := &file_iinpb_iin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckIINResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckIINResponse) ProtoMessage() {}
+
+func (x *CheckIINResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckIINResponse.ProtoReflect.Descriptor instead.
+func (*CheckIINResponse) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckIINResponse) GetIin() string {
+	if x != nil {
+		return x.Iin
+	}
+	return ""
+}
+
+func (x *CheckIINResponse) GetCorrect() bool {
+	if x != nil {
+		return x.Correct
+	}
+	return false
+}
+
+func (x *CheckIINResponse) GetSex() string {
+	if x != nil {
+		return x.Sex
+	}
+	return ""
+}
+
+func (x *CheckIINResponse) GetDateOfBirth() string {
+	if x != nil {
+		return x.DateOfBirth
+	}
+	return ""
+}
+
+func (x *CheckIINResponse) GetCentury() int32 {
+	if x != nil {
+		return x.Century
+	}
+	return 0
+}
+
+func (x *CheckIINResponse) GetSerialNumber() int32 {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return 0
+}
+
+func (x *CheckIINResponse) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *CheckIINResponse) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *CheckIINResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CheckIINResponse) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+type Person struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Iin           string                 `protobuf:"bytes,2,opt,name=iin,proto3" json:"iin,omitempty"`
+	Phone         string                 `protobuf:"bytes,3,opt,name=phone,proto3" json:"phone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Person) Reset() {
+	*x = Person{}
+	mi := &file_iinpb_iin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Person) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Person) ProtoMessage() {}
+
+func (x *Person) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Person.ProtoReflect.Descriptor instead.
+func (*Person) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Person) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Person) GetIin() string {
+	if x != nil {
+		return x.Iin
+	}
+	return ""
+}
+
+func (x *Person) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+type CreatePersonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Person        *Person                `protobuf:"bytes,1,opt,name=person,proto3" json:"person,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePersonRequest) Reset() {
+	*x = CreatePersonRequest{}
+	mi := &file_iinpb_iin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePersonRequest) ProtoMessage() {}
+
+func (x *CreatePersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePersonRequest.ProtoReflect.Descriptor instead.
+func (*CreatePersonRequest) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreatePersonRequest) GetPerson() *Person {
+	if x != nil {
+		return x.Person
+	}
+	return nil
+}
+
+type GetPersonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Iin           string                 `protobuf:"bytes,1,opt,name=iin,proto3" json:"iin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPersonRequest) Reset() {
+	*x = GetPersonRequest{}
+	mi := &file_iinpb_iin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPersonRequest) ProtoMessage() {}
+
+func (x *GetPersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPersonRequest.ProtoReflect.Descriptor instead.
+func (*GetPersonRequest) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetPersonRequest) GetIin() string {
+	if x != nil {
+		return x.Iin
+	}
+	return ""
+}
+
+type SearchPeopleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NamePart      string                 `protobuf:"bytes,1,opt,name=name_part,json=namePart,proto3" json:"name_part,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPeopleRequest) Reset() {
+	*x = SearchPeopleRequest{}
+	mi := &file_iinpb_iin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPeopleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPeopleRequest) ProtoMessage() {}
+
+func (x *SearchPeopleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPeopleRequest.ProtoReflect.Descriptor instead.
+func (*SearchPeopleRequest) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchPeopleRequest) GetNamePart() string {
+	if x != nil {
+		return x.NamePart
+	}
+	return ""
+}
+
+type SearchPeopleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	People        []*Person              `protobuf:"bytes,1,rep,name=people,proto3" json:"people,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPeopleResponse) Reset() {
+	*x = SearchPeopleResponse{}
+	mi := &file_iinpb_iin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPeopleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPeopleResponse) ProtoMessage() {}
+
+func (x *SearchPeopleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_iinpb_iin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPeopleResponse.ProtoReflect.Descriptor instead.
+func (*SearchPeopleResponse) Descriptor() ([]byte, []int) {
+	return file_iinpb_iin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchPeopleResponse) GetPeople() []*Person {
+	if x != nil {
+		return x.People
+	}
+	return nil
+}
+
+var File_iinpb_iin_proto protoreflect.FileDescriptor
+
+const file_iinpb_iin_proto_rawDesc = "" +
+	"\n" +
+	"\x0fiinpb/iin.proto\x12\x06iin.v1\"#\n" +
+	"\x0fCheckIINRequest\x12\x10\n" +
+	"\x03iin\x18\x01 \x01(\tR\x03iin\"\x96\x02\n" +
+	"\x10CheckIINResponse\x12\x10\n" +
+	"\x03iin\x18\x01 \x01(\tR\x03iin\x12\x18\n" +
+	"\acorrect\x18\x02 \x01(\bR\acorrect\x12\x10\n" +
+	"\x03sex\x18\x03 \x01(\tR\x03sex\x12\"\n" +
+	"\rdate_of_birth\x18\x04 \x01(\tR\vdateOfBirth\x12\x18\n" +
+	"\acentury\x18\x05 \x01(\x05R\acentury\x12#\n" +
+	"\rserial_number\x18\x06 \x01(\x05R\fserialNumber\x12\x10\n" +
+	"\x03age\x18\a \x01(\x05R\x03age\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\b \x01(\tR\terrorCode\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x12\x1a\n" +
+	"\bcategory\x18\n" +
+	" \x01(\tR\bcategory\"D\n" +
+	"\x06Person\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03iin\x18\x02 \x01(\tR\x03iin\x12\x14\n" +
+	"\x05phone\x18\x03 \x01(\tR\x05phone\"=\n" +
+	"\x13CreatePersonRequest\x12&\n" +
+	"\x06person\x18\x01 \x01(\v2\x0e.iin.v1.PersonR\x06person\"$\n" +
+	"\x10GetPersonRequest\x12\x10\n" +
+	"\x03iin\x18\x01 \x01(\tR\x03iin\"2\n" +
+	"\x13SearchPeopleRequest\x12\x1b\n" +
+	"\tname_part\x18\x01 \x01(\tR\bnamePart\">\n" +
+	"\x14SearchPeopleResponse\x12&\n" +
+	"\x06people\x18\x01 \x03(\v2\x0e.iin.v1.PersonR\x06people2\xcf\x02\n" +
+	"\n" +
+	"IINService\x12=\n" +
+	"\bCheckIIN\x12\x17.iin.v1.CheckIINRequest\x1a\x18.iin.v1.CheckIINResponse\x12C\n" +
+	"\n" +
+	"BatchCheck\x12\x17.iin.v1.CheckIINRequest\x1a\x18.iin.v1.CheckIINResponse(\x010\x01\x12;\n" +
+	"\fCreatePerson\x12\x1b.iin.v1.CreatePersonRequest\x1a\x0e.iin.v1.Person\x125\n" +
+	"\tGetPerson\x12\x18.iin.v1.GetPersonRequest\x1a\x0e.iin.v1.Person\x12I\n" +
+	"\fSearchPeople\x12\x1b.iin.v1.SearchPeopleRequest\x1a\x1c.iin.v1.SearchPeopleResponseB9Z7github.com/toleubekov/check-iin-kaz/internal/grpc/iinpbb\x06proto3"
+
+var (
+	file_iinpb_iin_proto_rawDescOnce sync.Once
+	file_iinpb_iin_proto_rawDescData []byte
+)
+
+func file_iinpb_iin_proto_rawDescGZIP() []byte {
+	file_iinpb_iin_proto_rawDescOnce.Do(func() {
+		file_iinpb_iin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_iinpb_iin_proto_rawDesc), len(file_iinpb_iin_proto_rawDesc)))
+	})
+	return file_iinpb_iin_proto_rawDescData
+}
+
+var file_iinpb_iin_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_iinpb_iin_proto_goTypes = []any{
+	(*CheckIINRequest)(nil),      // 0: iin.v1.CheckIINRequest
+	(*CheckIINResponse)(nil),     // 1: iin.v1.CheckIINResponse
+	(*Person)(nil),               // 2: iin.v1.Person
+	(*CreatePersonRequest)(nil),  // 3: iin.v1.CreatePersonRequest
+	(*GetPersonRequest)(nil),     // 4: iin.v1.GetPersonRequest
+	(*SearchPeopleRequest)(nil),  // 5: iin.v1.SearchPeopleRequest
+	(*SearchPeopleResponse)(nil), // 6: iin.v1.SearchPeopleResponse
+}
+var file_iinpb_iin_proto_depIdxs = []int32{
+	2, // 0: iin.v1.CreatePersonRequest.person:type_name -> iin.v1.Person
+	2, // 1: iin.v1.SearchPeopleResponse.people:type_name -> iin.v1.Person
+	0, // 2: iin.v1.IINService.CheckIIN:input_type -> iin.v1.CheckIINRequest
+	0, // 3: iin.v1.IINService.BatchCheck:input_type -> iin.v1.CheckIINRequest
+	3, // 4: iin.v1.IINService.CreatePerson:input_type -> iin.v1.CreatePersonRequest
+	4, // 5: iin.v1.IINService.GetPerson:input_type -> iin.v1.GetPersonRequest
+	5, // 6: iin.v1.IINService.SearchPeople:input_type -> iin.v1.SearchPeopleRequest
+	1, // 7: iin.v1.IINService.CheckIIN:output_type -> iin.v1.CheckIINResponse
+	1, // 8: iin.v1.IINService.BatchCheck:output_type -> iin.v1.CheckIINResponse
+	2, // 9: iin.v1.IINService.CreatePerson:output_type -> iin.v1.Person
+	2, // 10: iin.v1.IINService.GetPerson:output_type -> iin.v1.Person
+	6, // 11: iin.v1.IINService.SearchPeople:output_type -> iin.v1.SearchPeopleResponse
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_iinpb_iin_proto_init() }
+func file_iinpb_iin_proto_init() {
+	if File_iinpb_iin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_iinpb_iin_proto_rawDesc), len(file_iinpb_iin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_iinpb_iin_proto_goTypes,
+		DependencyIndexes: file_iinpb_iin_proto_depIdxs,
+		MessageInfos:      file_iinpb_iin_proto_msgTypes,
+	}.Build()
+	File_iinpb_iin_proto = out.File
+	file_iinpb_iin_proto_goTypes = nil
+	file_iinpb_iin_proto_depIdxs = nil
+}
@@ -0,0 +1,288 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: iinpb/iin.proto
+
+// The gRPC counterpart of the HTTP API. Calls authenticate with the same
+// credentials as HTTP, sent as "x-api-key" or "authorization: Bearer ..."
+// metadata, and need the same roles.
+
+package iinpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	IINService_CheckIIN_FullMethodName     = "/iin.v1.IINService/CheckIIN"
+	IINService_BatchCheck_FullMethodName   = "/iin.v1.IINService/BatchCheck"
+	IINService_CreatePerson_FullMethodName = "/iin.v1.IINService/CreatePerson"
+	IINService_GetPerson_FullMethodName    = "/iin.v1.IINService/GetPerson"
+	IINService_SearchPeople_FullMethodName = "/iin.v1.IINService/SearchPeople"
+)
+
+// IINServiceClient is the client API for IINService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IINServiceClient interface {
+	// CheckIIN validates an IIN. An invalid IIN is not an error: the response
+	// has correct set to false and error_code saying why. Requires checker.
+	CheckIIN(ctx context.Context, in *CheckIINRequest, opts ...grpc.CallOption) (*CheckIINResponse, error)
+	// BatchCheck validates a stream of IINs, answering each one in order as
+	// it arrives. Requires checker.
+	BatchCheck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckIINRequest, CheckIINResponse], error)
+	// CreatePerson stores a person; the IIN must be valid and the phone is
+	// normalized. Requires writer.
+	CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// GetPerson returns the person with the given IIN. Requires reader.
+	GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// SearchPeople finds people whose name contains name_part. Requires reader.
+	SearchPeople(ctx context.Context, in *SearchPeopleRequest, opts ...grpc.CallOption) (*SearchPeopleResponse, error)
+}
+
+type iINServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIINServiceClient(cc grpc.ClientConnInterface) IINServiceClient {
+	return &iINServiceClient{cc}
+}
+
+func (c *iINServiceClient) CheckIIN(ctx context.Context, in *CheckIINRequest, opts ...grpc.CallOption) (*CheckIINResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckIINResponse)
+	err := c.cc.Invoke(ctx, IINService_CheckIIN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iINServiceClient) BatchCheck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckIINRequest, CheckIINResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IINService_ServiceDesc.Streams[0], IINService_BatchCheck_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CheckIINRequest, CheckIINResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IINService_BatchCheckClient = grpc.BidiStreamingClient[CheckIINRequest, CheckIINResponse]
+
+func (c *iINServiceClient) CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Person)
+	err := c.cc.Invoke(ctx, IINService_CreatePerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iINServiceClient) GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Person)
+	err := c.cc.Invoke(ctx, IINService_GetPerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iINServiceClient) SearchPeople(ctx context.Context, in *SearchPeopleRequest, opts ...grpc.CallOption) (*SearchPeopleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchPeopleResponse)
+	err := c.cc.Invoke(ctx, IINService_SearchPeople_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IINServiceServer is the server API for IINService service.
+// All implementations must embed UnimplementedIINServiceServer
+// for forward compatibility.
+type IINServiceServer interface {
+	// CheckIIN validates an IIN. An invalid IIN is not an error: the response
+	// has correct set to false and error_code saying why. Requires checker.
+	CheckIIN(context.Context, *CheckIINRequest) (*CheckIINResponse, error)
+	// BatchCheck validates a stream of IINs, answering each one in order as
+	// it arrives. Requires checker.
+	BatchCheck(grpc.BidiStreamingServer[CheckIINRequest, CheckIINResponse]) error
+	// CreatePerson stores a person; the IIN must be valid and the phone is
+	// normalized. Requires writer.
+	CreatePerson(context.Context, *CreatePersonRequest) (*Person, error)
+	// GetPerson returns the person with the given IIN. Requires reader.
+	GetPerson(context.Context, *GetPersonRequest) (*Person, error)
+	// SearchPeople finds people whose name contains name_part. Requires reader.
+	SearchPeople(context.Context, *SearchPeopleRequest) (*SearchPeopleResponse, error)
+	mustEmbedUnimplementedIINServiceServer()
+}
+
+// UnimplementedIINServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIINServiceServer struct{}
+
+func (UnimplementedIINServiceServer) CheckIIN(context.Context, *CheckIINRequest) (*CheckIINResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CheckIIN not implemented")
+}
+func (UnimplementedIINServiceServer) BatchCheck(grpc.BidiStreamingServer[CheckIINRequest, CheckIINResponse]) error {
+	return status.Error(codes.Unimplemented, "method BatchCheck not implemented")
+}
+func (UnimplementedIINServiceServer) CreatePerson(context.Context, *CreatePersonRequest) (*Person, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreatePerson not implemented")
+}
+func (UnimplementedIINServiceServer) GetPerson(context.Context, *GetPersonRequest) (*Person, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetPerson not implemented")
+}
+func (UnimplementedIINServiceServer) SearchPeople(context.Context, *SearchPeopleRequest) (*SearchPeopleResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchPeople not implemented")
+}
+func (UnimplementedIINServiceServer) mustEmbedUnimplementedIINServiceServer() {}
+func (UnimplementedIINServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeIINServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IINServiceServer will
+// result in compilation errors.
+type UnsafeIINServiceServer interface {
+	mustEmbedUnimplementedIINServiceServer()
+}
+
+func RegisterIINServiceServer(s grpc.ServiceRegistrar, srv IINServiceServer) {
+	// If the following call panics, it indicates UnimplementedIINServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IINService_ServiceDesc, srv)
+}
+
+func _IINService_CheckIIN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckIINRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IINServiceServer).CheckIIN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IINService_CheckIIN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IINServiceServer).CheckIIN(ctx, req.(*CheckIINRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IINService_BatchCheck_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IINServiceServer).BatchCheck(&grpc.GenericServerStream[CheckIINRequest, CheckIINResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IINService_BatchCheckServer = grpc.BidiStreamingServer[CheckIINRequest, CheckIINResponse]
+
+func _IINService_CreatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IINServiceServer).CreatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IINService_CreatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IINServiceServer).CreatePerson(ctx, req.(*CreatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IINService_GetPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IINServiceServer).GetPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IINService_GetPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IINServiceServer).GetPerson(ctx, req.(*GetPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IINService_SearchPeople_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchPeopleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IINServiceServer).SearchPeople(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IINService_SearchPeople_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IINServiceServer).SearchPeople(ctx, req.(*SearchPeopleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IINService_ServiceDesc is the grpc.ServiceDesc for IINService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IINService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "iin.v1.IINService",
+	HandlerType: (*IINServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckIIN",
+			Handler:    _IINService_CheckIIN_Handler,
+		},
+		{
+			MethodName: "CreatePerson",
+			Handler:    _IINService_CreatePerson_Handler,
+		},
+		{
+			MethodName: "GetPerson",
+			Handler:    _IINService_GetPerson_Handler,
+		},
+		{
+			MethodName: "SearchPeople",
+			Handler:    _IINService_SearchPeople_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchCheck",
+			Handler:       _IINService_BatchCheck_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "iinpb/iin.proto",
+}
@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/grpc/iinpb"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles mirrors the roles of the equivalent HTTP routes.
+var methodRoles = map[string]string{
+	iinpb.IINService_CheckIIN_FullMethodName:     auth.RoleChecker,
+	iinpb.IINService_BatchCheck_FullMethodName:   auth.RoleChecker,
+	iinpb.IINService_CreatePerson_FullMethodName: auth.RoleWriter,
+	iinpb.IINService_GetPerson_FullMethodName:    auth.RoleReader,
+	iinpb.IINService_SearchPeople_FullMethodName: auth.RoleReader,
+}
+
+// methodGroups puts each method in the rate limit group of its HTTP route,
+// so RATE_LIMITS and the daily quota cover both APIs.
+var methodGroups = map[string]string{
+	iinpb.IINService_CheckIIN_FullMethodName:     "iin_check",
+	iinpb.IINService_BatchCheck_FullMethodName:   "iin_check",
+	iinpb.IINService_CreatePerson_FullMethodName: "people",
+	iinpb.IINService_GetPerson_FullMethodName:    "people",
+	iinpb.IINService_SearchPeople_FullMethodName: "people",
+}
+
+type interceptor struct {
+	authenticator *auth.Authenticator
+	limiter       *ratelimit.Limiter
+	audit         *audit.Logger
+}
+
+func (i *interceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := i.admit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.limit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream admits a streaming call when it opens and then charges the rate
+// limit and quota for every message received, so a stream of checks costs
+// the same as the unary calls it replaces.
+func (i *interceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.admit(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx, interceptor: i, method: info.FullMethod})
+}
+
+// admit applies the per-IP limit and authenticates the caller, like
+// ipRateLimitMiddleware and authMiddleware do for HTTP.
+func (i *interceptor) admit(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if i.limiter != nil {
+		if decision := i.limiter.AllowIP(clientIP(ctx)); !decision.Allowed {
+			log.Printf("Rejected ip:%s: IP rate limit exceeded for %s", clientIP(ctx), method)
+			return nil, rateLimited(ctx, decision)
+		}
+	}
+
+	if i.authenticator != nil {
+		principal, err := i.authenticator.AuthenticateHeaders(first(md, "x-api-key"), first(md, "authorization"))
+		if err != nil {
+			if !errors.Is(err, auth.ErrNoCredentials) && !errors.Is(err, auth.ErrInvalidCredentials) {
+				log.Printf("ERROR: Authentication failed: %v", err)
+				return nil, status.Error(codes.Internal, "Authentication failed")
+			}
+			log.Printf("Rejected unauthenticated gRPC call to %s: %v", method, err)
+			i.auditDenied(ctx, method, codes.Unauthenticated)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				return nil, status.Error(codes.Unauthenticated, "Authentication required")
+			}
+			return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		}
+
+		role := methodRoles[method]
+		if role == "" {
+			role = auth.RoleAdmin
+		}
+		if !principal.HasRole(role) {
+			log.Printf("Rejected %s calling %s: role %s required", principal.Actor(), method, role)
+			i.auditDenied(audit.WithActor(ctx, principal.Actor()), method, codes.PermissionDenied)
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("Role %s is required", role))
+		}
+		ctx = auth.WithPrincipal(ctx, principal)
+		ctx = audit.WithActor(ctx, principal.Actor())
+	}
+
+	return ctx, nil
+}
+
+// limit applies the rate limit and daily quota of the method's group to one
+// request of an admitted call, like rateLimitMiddleware does for HTTP.
+func (i *interceptor) limit(ctx context.Context, method string) error {
+	if i.limiter == nil {
+		return nil
+	}
+	client := "ip:" + clientIP(ctx)
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		client = principal.Actor()
+	}
+	group := methodGroups[method]
+	if group == "" {
+		group = ratelimit.DefaultRoute
+	}
+
+	decision := i.limiter.Allow(client, group)
+	if !decision.Allowed {
+		if decision.QuotaExceeded {
+			log.Printf("Rejected %s: daily quota exceeded", client)
+		}
+		return rateLimited(ctx, decision)
+	}
+	return nil
+}
+
+// rateLimited sets retry-after and returns the ResourceExhausted error of a
+// rejected call. retry-after goes in the trailer: a stream limited in RecvMsg
+// may already have sent its header, but the trailer is still to come.
+func rateLimited(ctx context.Context, decision ratelimit.Decision) error {
+	retryAfter := strconv.Itoa(int((decision.RetryAfter + time.Second - 1) / time.Second))
+	if err := grpc.SetTrailer(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+		log.Printf("ERROR: Failed to set retry-after: %v", err)
+	}
+	if decision.QuotaExceeded {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("Daily quota of %d requests exceeded", decision.DailyQuota))
+	}
+	return status.Error(codes.ResourceExhausted, "Rate limit exceeded")
+}
+
+// auditDenied records a rejected call for person data, like the HTTP
+// authMiddleware does. IIN checks touch no stored data and are not audited.
+func (i *interceptor) auditDenied(ctx context.Context, method string, code codes.Code) {
+	if i.audit == nil || methodRoles[method] == auth.RoleChecker {
+		return
+	}
+	entry := auditEntry(ctx, audit.ActionDenied)
+	entry.Details = map[string]interface{}{"status": code.String(), "method": method}
+	i.audit.Log(entry)
+}
+
+// serverStream replaces the context of a stream with the admitted one and
+// limits every message received.
+type serverStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	interceptor *interceptor
+	method      string
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.interceptor.limit(s.ctx, s.method)
+}
+
+// auditEntry is audit.FromRequest for gRPC calls. The request id is taken
+// from x-request-id metadata if the caller sent one.
+func auditEntry(ctx context.Context, action string) audit.Entry {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return audit.Entry{
+		Actor:     audit.Actor(ctx),
+		Action:    action,
+		RequestID: first(md, "x-request-id"),
+		ClientIP:  clientIP(ctx),
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
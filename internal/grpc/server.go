@@ -0,0 +1,194 @@
+// Package grpc serves the gRPC API defined in iinpb/iin.proto.
+//
+// It is a second front end to the same IIN service, repository,
+// authenticator, rate limiter and audit log as the HTTP handlers, so a
+// person created over gRPC is validated, stored and audited exactly like one
+// created over HTTP.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative iinpb/iin.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/grpc/iinpb"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options holds the optional interceptor dependencies of NewServer; nil
+// values disable the interceptor, as with api.RouterOptions.
+type Options struct {
+	Authenticator *auth.Authenticator
+	Limiter       *ratelimit.Limiter
+}
+
+type server struct {
+	iinpb.UnimplementedIINServiceServer
+
+	iinService *service.IINService
+	repo       repository.PersonStore
+	audit      *audit.Logger
+}
+
+// NewServer creates a gRPC server with the IIN service registered.
+func NewServer(iinService *service.IINService, repo repository.PersonStore, auditLogger *audit.Logger, opts Options) *grpc.Server {
+	i := &interceptor{authenticator: opts.Authenticator, limiter: opts.Limiter, audit: auditLogger}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+	iinpb.RegisterIINServiceServer(s, &server{iinService: iinService, repo: repo, audit: auditLogger})
+	return s
+}
+
+func (s *server) CheckIIN(ctx context.Context, req *iinpb.CheckIINRequest) (*iinpb.CheckIINResponse, error) {
+	return s.check(req.GetIin()), nil
+}
+
+func (s *server) BatchCheck(stream iinpb.IINService_BatchCheckServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(s.check(req.GetIin())); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) check(iin string) *iinpb.CheckIINResponse {
+	result := s.iinService.Check(iin, time.Now())
+	response := &iinpb.CheckIINResponse{
+		Iin:         iin,
+		Correct:     result.Correct,
+		Category:    result.Category,
+		Sex:         result.Sex,
+		DateOfBirth: result.DateOfBirth,
+		Century:     int32(result.Century),
+		ErrorCode:   result.ErrorCode,
+		Error:       result.Error,
+	}
+	if result.SerialNumber != nil {
+		response.SerialNumber = int32(*result.SerialNumber)
+	}
+	if result.Age != nil {
+		response.Age = int32(*result.Age)
+	}
+	return response
+}
+
+func (s *server) CreatePerson(ctx context.Context, req *iinpb.CreatePersonRequest) (*iinpb.Person, error) {
+	if req.GetPerson() == nil {
+		return nil, status.Error(codes.InvalidArgument, "person is required")
+	}
+	person := model.Person{
+		Name:  req.GetPerson().GetName(),
+		IIN:   req.GetPerson().GetIin(),
+		Phone: req.GetPerson().GetPhone(),
+	}
+
+	if err := s.validateIIN(person.IIN); err != nil {
+		return nil, err
+	}
+	normalizedPhone, err := service.NormalizePhone(person.Phone)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid phone: "+err.Error())
+	}
+	person.Phone = normalizedPhone
+
+	if err := s.repo.Create(&person); err != nil {
+		if errors.Is(err, repository.ErrDuplicateIIN) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		log.Printf("ERROR: gRPC CreatePerson failed: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.audit.Log(auditEntry(ctx, audit.ActionCreate), person.IIN)
+	return toProto(person), nil
+}
+
+func (s *server) GetPerson(ctx context.Context, req *iinpb.GetPersonRequest) (*iinpb.Person, error) {
+	if err := s.validateIIN(req.GetIin()); err != nil {
+		s.auditFailure(ctx, audit.ActionRead, codes.InvalidArgument, req.GetIin())
+		return nil, err
+	}
+
+	person, err := s.repo.GetByIIN(req.GetIin())
+	if err != nil {
+		if err.Error() == "person not found" {
+			s.auditFailure(ctx, audit.ActionRead, codes.NotFound, req.GetIin())
+			return nil, status.Error(codes.NotFound, "Person not found")
+		}
+		log.Printf("ERROR: gRPC GetPerson failed: %v", err)
+		s.auditFailure(ctx, audit.ActionRead, codes.Internal, req.GetIin())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.audit.Log(auditEntry(ctx, audit.ActionRead), person.IIN)
+	return toProto(*person), nil
+}
+
+func (s *server) SearchPeople(ctx context.Context, req *iinpb.SearchPeopleRequest) (*iinpb.SearchPeopleResponse, error) {
+	if req.GetNamePart() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name_part is required")
+	}
+
+	people, err := s.repo.FindByNamePart(req.GetNamePart())
+	if err != nil {
+		log.Printf("ERROR: gRPC SearchPeople failed: %v", err)
+		s.auditFailure(ctx, audit.ActionSearch, codes.Internal)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &iinpb.SearchPeopleResponse{People: make([]*iinpb.Person, len(people))}
+	iins := make([]string, len(people))
+	for i, person := range people {
+		response.People[i] = toProto(person)
+		iins[i] = person.IIN
+	}
+
+	entry := auditEntry(ctx, audit.ActionSearch)
+	entry.Details = map[string]interface{}{"results": len(people)}
+	s.audit.Log(entry, iins...)
+	return response, nil
+}
+
+// auditFailure records a failed read with its gRPC status code.
+func (s *server) auditFailure(ctx context.Context, action string, code codes.Code, iins ...string) {
+	entry := auditEntry(ctx, action)
+	entry.Details = map[string]interface{}{"status": code.String()}
+	s.audit.Log(entry, iins...)
+}
+
+// validateIIN returns an InvalidArgument status for an invalid IIN.
+func (s *server) validateIIN(iin string) error {
+	correct, _, _, err := s.iinService.ValidateIIN(iin)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !correct {
+		return status.Error(codes.InvalidArgument, "Invalid IIN")
+	}
+	return nil
+}
+
+func toProto(person model.Person) *iinpb.Person {
+	return &iinpb.Person{Name: person.Name, Iin: person.IIN, Phone: person.Phone}
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/auth/authtest"
+	"github.com/toleubekov/check-iin-kaz/internal/grpc/iinpb"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var testSecret = []byte(strings.Repeat("s", 32))
+
+// dial serves a server without a database, which is enough for the checks
+// and for calls the interceptor rejects.
+func dial(t *testing.T, opts Options) iinpb.IINServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	s := NewServer(service.NewIINService(), nil, nil, opts)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return iinpb.NewIINServiceClient(conn)
+}
+
+func TestCheckIIN(t *testing.T) {
+	client := dial(t, Options{})
+
+	response, err := client.CheckIIN(context.Background(), &iinpb.CheckIINRequest{Iin: "031231500126"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Correct || response.Category != "citizen" || response.DateOfBirth != "2003-12-31" || response.Century != 21 {
+		t.Errorf("response = %v", response)
+	}
+
+	response, err = client.CheckIIN(context.Background(), &iinpb.CheckIINRequest{Iin: "031231500127"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Correct || response.ErrorCode != "checksum" {
+		t.Errorf("response = %v, want a checksum error", response)
+	}
+}
+
+func TestBatchCheck(t *testing.T) {
+	client := dial(t, Options{})
+
+	stream, err := client.BatchCheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	iins := []string{"031231500126", "123", "031231500127"}
+	for _, iin := range iins {
+		if err := stream.Send(&iinpb.CheckIINRequest{Iin: iin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.CloseSend()
+
+	want := []string{"", "length", "checksum"}
+	for i := range iins {
+		response, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Iin != iins[i] || response.ErrorCode != want[i] {
+			t.Errorf("response %d = %v, want error code %q", i, response, want[i])
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	verifier, err := auth.NewJWTVerifier(testSecret, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dial(t, Options{Authenticator: auth.NewAuthenticator(nil, verifier)})
+
+	_, err = client.CheckIIN(context.Background(), &iinpb.CheckIINRequest{Iin: "031231500126"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without credentials: %v, want Unauthenticated", err)
+	}
+
+	token := authtest.SignHS256(t, testSecret, map[string]interface{}{
+		"sub":   "svc",
+		"roles": []string{auth.RoleChecker},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	if _, err := client.CheckIIN(ctx, &iinpb.CheckIINRequest{Iin: "031231500126"}); err != nil {
+		t.Errorf("checker calling CheckIIN: %v", err)
+	}
+	_, err = client.GetPerson(ctx, &iinpb.GetPersonRequest{Iin: "031231500126"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("checker calling GetPerson: %v, want PermissionDenied", err)
+	}
+}
+
+func TestBatchCheckRateLimit(t *testing.T) {
+	quotas := repository.NewMemoryQuotaRepository()
+	limiter := ratelimit.NewLimiter(map[string]ratelimit.Limit{"iin_check": {Rate: 0.01, Burst: 3}}, quotas, 1000)
+	client := dial(t, Options{Limiter: limiter})
+
+	stream, err := client.BatchCheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := stream.Send(&iinpb.CheckIINRequest{Iin: "031231500126"}); err != nil {
+			break
+		}
+	}
+	stream.CloseSend()
+
+	// Every message takes a token: three are answered, the fourth ends the stream.
+	for i := 0; i < 3; i++ {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("response %d: %v", i+1, err)
+		}
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("fourth message: %v, want ResourceExhausted", err)
+	}
+	// The header went out with the first response, so retry-after comes in the trailer.
+	if retryAfter := stream.Trailer().Get("retry-after"); len(retryAfter) != 1 || retryAfter[0] == "0" {
+		t.Errorf("stream retry-after = %v", retryAfter)
+	}
+
+	// The stream used up the bucket that unary checks share.
+	var trailer metadata.MD
+	_, err = client.CheckIIN(context.Background(), &iinpb.CheckIINRequest{Iin: "031231500126"}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("CheckIIN after the stream: %v, want ResourceExhausted", err)
+	}
+	if retryAfter := trailer.Get("retry-after"); len(retryAfter) != 1 || retryAfter[0] == "0" {
+		t.Errorf("CheckIIN retry-after = %v", retryAfter)
+	}
+
+	// Each answered message counts against the daily quota.
+	limiter.Flush()
+	if used, _ := quotas.Add("ip:bufconn", time.Now().UTC(), 0); used != 3 {
+		t.Errorf("quota usage = %d, want 3", used)
+	}
+}
@@ -0,0 +1,501 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+)
+
+const testPhone = "+77011234567"
+
+func TestProbes(t *testing.T) {
+	server := newServer(t, nil)
+
+	server.expect(t, http.StatusOK, "", http.MethodGet, "/healthz", nil)
+
+	var ready model.HealthResponse
+	server.expect(t, http.StatusOK, "", http.MethodGet, "/readyz", nil).decode(t, &ready)
+	if ready.Status != "ok" || ready.Schema == nil || ready.Schema.Version != ready.Schema.Expected {
+		t.Errorf("readyz = %+v", ready)
+	}
+
+	// Make sure there is at least one request to count.
+	server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, "/v1/iin_check/"+validIIN(t, 1), nil)
+	metrics := server.expect(t, http.StatusOK, "", http.MethodGet, "/metrics", nil)
+	if !bytes.Contains(metrics.Body, []byte("http_requests_total")) {
+		t.Errorf("/metrics has no http_requests_total:\n%s", metrics.Body)
+	}
+
+	var spec map[string]interface{}
+	server.expect(t, http.StatusOK, "", http.MethodGet, "/openapi.json", nil).decode(t, &spec)
+	if spec["paths"] == nil {
+		t.Errorf("openapi.json has no paths")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server := newServer(t, nil)
+	personIIN := validIIN(t, 1)
+
+	resp := server.expect(t, http.StatusUnauthorized, "", http.MethodGet, "/v1/iin_check/"+personIIN, nil)
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("401 without WWW-Authenticate")
+	}
+	if msg := resp.errorMessage(t); msg != "Authentication required" {
+		t.Errorf("error = %q", msg)
+	}
+
+	resp = server.expect(t, http.StatusUnauthorized, "", http.MethodGet, "/v1/iin_check/"+personIIN, nil, "X-API-Key", "ik_unknown")
+	if msg := resp.errorMessage(t); msg != "Invalid credentials" {
+		t.Errorf("error = %q", msg)
+	}
+
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		status int
+	}{
+		{auth.RoleChecker, http.MethodGet, "/v1/iin_check/" + personIIN, http.StatusOK},
+		{auth.RoleChecker, http.MethodGet, "/v1/people/info/iin/" + personIIN, http.StatusForbidden},
+		{auth.RoleReader, http.MethodGet, "/v1/people/info/iin/" + personIIN, http.StatusNotFound},
+		{auth.RoleReader, http.MethodPost, "/v1/people/info", http.StatusForbidden},
+		{auth.RoleWriter, http.MethodGet, "/v1/audit", http.StatusForbidden},
+		{auth.RoleAdmin, http.MethodGet, "/v1/audit", http.StatusOK},
+		// Admins have every role.
+		{auth.RoleAdmin, http.MethodGet, "/iin_check/" + personIIN, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			resp := server.do(t, tt.role, tt.method, tt.path, nil)
+			if resp.Status != tt.status {
+				t.Errorf("status = %d, want %d: %s", resp.Status, tt.status, resp.Body)
+			}
+			if tt.status == http.StatusForbidden && !strings.HasPrefix(resp.errorMessage(t), "Role ") {
+				t.Errorf("403 without the required role: %s", resp.Body)
+			}
+		})
+	}
+}
+
+func TestCheckIIN(t *testing.T) {
+	server := newServer(t, nil)
+	valid := validIIN(t, 1)
+
+	var info model.IINInfoResponse
+	server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, "/v1/iin_check/"+valid, nil).decode(t, &info)
+	if !info.Correct || info.Sex != "male" || info.DateOfBirth != "1990-01-01" || info.Century != 20 || info.Age == nil {
+		t.Errorf("v1 check of %s = %+v", valid, info)
+	}
+
+	info = model.IINInfoResponse{}
+	server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, "/v1/iin_check/"+invalidIIN(t, 1), nil).decode(t, &info)
+	if info.Correct || info.ErrorCode == "" {
+		t.Errorf("v1 check of an invalid IIN = %+v", info)
+	}
+
+	resp := server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, "/iin_check/"+valid, nil)
+	if resp.Header.Get("Deprecation") != "true" {
+		t.Errorf("legacy route without Deprecation header")
+	}
+	var legacy model.IINResponse
+	resp.decode(t, &legacy)
+	if !legacy.Correct || legacy.Sex != "male" {
+		t.Errorf("legacy check of %s = %+v", valid, legacy)
+	}
+
+	legacy = model.IINResponse{}
+	server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, "/iin_check/123", nil).decode(t, &legacy)
+	if legacy.Correct {
+		t.Errorf("legacy check of 123 is correct")
+	}
+}
+
+func TestPeople(t *testing.T) {
+	for _, prefix := range []string{"/v1", ""} {
+		t.Run("prefix="+prefix, func(t *testing.T) {
+			server := newServer(t, nil)
+			personIIN := validIIN(t, 1)
+
+			server.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, prefix+"/people/info",
+				personJSON("Aliya Nurlanovna", personIIN, "8 (701) 123-45-67"))
+
+			var person model.Person
+			resp := server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, prefix+"/people/info/iin/"+personIIN, nil)
+			resp.decode(t, &person)
+			if person.Name != "Aliya Nurlanovna" || person.IIN != personIIN || person.Phone != testPhone {
+				t.Errorf("person = %+v", person)
+			}
+			if deprecated := resp.Header.Get("Deprecation") == "true"; deprecated != (prefix == "") {
+				t.Errorf("Deprecation header = %q", resp.Header.Get("Deprecation"))
+			}
+
+			var people []model.Person
+			server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, prefix+"/people/info/name/nurlan", nil).decode(t, &people)
+			if len(people) != 1 || people[0].IIN != personIIN {
+				t.Errorf("search = %+v", people)
+			}
+			people = nil
+			server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, prefix+"/people/info/name/nobody", nil).decode(t, &people)
+			if len(people) != 0 {
+				t.Errorf("search for nobody = %+v", people)
+			}
+
+			errorTests := []struct {
+				name   string
+				method string
+				path   string
+				body   []byte
+				status int
+				error  string
+			}{
+				{"duplicate", http.MethodPost, "/people/info", personJSON("Someone Else", personIIN, testPhone),
+					http.StatusInternalServerError, "a person with this IIN already exists"},
+				{"invalid IIN", http.MethodPost, "/people/info", personJSON("Invalid", invalidIIN(t, 2), testPhone),
+					http.StatusInternalServerError, "некорректная контрольная сумма ИИН"},
+				{"invalid phone", http.MethodPost, "/people/info", personJSON("Invalid", validIIN(t, 2), "12345"),
+					http.StatusBadRequest, "Invalid phone: "},
+				{"malformed JSON", http.MethodPost, "/people/info", []byte("{"),
+					http.StatusBadRequest, "Invalid request format"},
+				{"not found", http.MethodGet, "/people/info/iin/" + validIIN(t, 3), nil,
+					http.StatusNotFound, "Person not found"},
+				{"get invalid IIN", http.MethodGet, "/people/info/iin/" + invalidIIN(t, 3), nil,
+					http.StatusInternalServerError, "некорректная контрольная сумма ИИН"},
+			}
+			for _, tt := range errorTests {
+				resp := server.do(t, auth.RoleAdmin, tt.method, prefix+tt.path, tt.body)
+				if resp.Status != tt.status {
+					t.Errorf("%s: status = %d, want %d: %s", tt.name, resp.Status, tt.status, resp.Body)
+					continue
+				}
+				if msg := resp.errorMessage(t); !strings.HasPrefix(msg, tt.error) {
+					t.Errorf("%s: error = %q, want prefix %q", tt.name, msg, tt.error)
+				}
+			}
+
+			// A phone is optional, as it was before phones were validated.
+			withoutPhone := validIIN(t, 4)
+			server.createPerson(t, "No Phone", withoutPhone, "")
+			person = model.Person{}
+			server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, prefix+"/people/info/iin/"+withoutPhone, nil).decode(t, &person)
+			if person.IIN != withoutPhone || person.Phone != "" {
+				t.Errorf("person without a phone = %+v", person)
+			}
+
+			server.expect(t, http.StatusNotFound, auth.RoleAdmin, http.MethodGet, prefix+"/people/unknown", nil)
+		})
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	server := newServer(t, nil)
+	body := personJSON("Aliya Nurlanovna", validIIN(t, 1), testPhone)
+
+	first := server.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, "/v1/people/info", body, "Idempotency-Key", "create-1")
+	if first.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("first request was replayed")
+	}
+
+	replay := server.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, "/v1/people/info", body, "Idempotency-Key", "create-1")
+	if replay.Header.Get("Idempotent-Replayed") != "true" || !bytes.Equal(replay.Body, first.Body) {
+		t.Errorf("retry = %s %v, want the stored response", replay.Body, replay.Header)
+	}
+	legacy := server.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, "/people/info", body, "Idempotency-Key", "create-1")
+	if legacy.Header.Get("Idempotent-Replayed") != "true" || !bytes.Equal(legacy.Body, first.Body) {
+		t.Errorf("retry on the unversioned route = %s %v, want the stored response", legacy.Body, legacy.Header)
+	}
+
+	other := personJSON("Aliya Nurlanovna", validIIN(t, 2), testPhone)
+	server.expect(t, http.StatusUnprocessableEntity, auth.RoleWriter, http.MethodPost, "/v1/people/info", other, "Idempotency-Key", "create-1")
+	server.expect(t, http.StatusBadRequest, auth.RoleWriter, http.MethodPost, "/v1/people/info", other, "Idempotency-Key", strings.Repeat("k", 256))
+
+	// Keys belong to the client: another API key with the same key runs its own request.
+	fresh := server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodPost, "/v1/people/info", other, "Idempotency-Key", "create-1")
+	if fresh.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("another client got the stored response of create-1")
+	}
+
+	// Server errors are not stored, so the same key can be retried.
+	duplicate := server.expect(t, http.StatusInternalServerError, auth.RoleWriter, http.MethodPost, "/v1/people/info", body, "Idempotency-Key", "create-2")
+	retry := server.expect(t, http.StatusInternalServerError, auth.RoleWriter, http.MethodPost, "/v1/people/info", body, "Idempotency-Key", "create-2")
+	if retry.Header.Get("Idempotent-Replayed") != "" || duplicate.errorMessage(t) != retry.errorMessage(t) {
+		t.Errorf("retry after a server error = %s %v", retry.Body, retry.Header)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	server := newServer(t, nil)
+	first, second := validIIN(t, 1), validIIN(t, 2)
+	csv := fmt.Sprintf("name,iin,phone\nAliya Nurlanovna,%s,%s\nDaniyar Serikovich,%s,%s\n", first, testPhone, second, testPhone)
+
+	var report model.ImportReport
+	server.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, "/v1/people/import", []byte(csv), "Content-Type", "text/csv").decode(t, &report)
+	if !report.Success || report.Created != 2 {
+		t.Errorf("import = %+v", report)
+	}
+
+	report = model.ImportReport{}
+	server.expect(t, http.StatusUnprocessableEntity, auth.RoleWriter, http.MethodPost, "/v1/people/import?mode=fail", []byte(csv), "Content-Type", "text/csv").decode(t, &report)
+	if report.Success {
+		t.Errorf("import of duplicates in fail mode = %+v", report)
+	}
+
+	server.expect(t, http.StatusUnsupportedMediaType, auth.RoleWriter, http.MethodPost, "/v1/people/import", []byte(csv), "Content-Type", "text/plain")
+	server.expect(t, http.StatusBadRequest, auth.RoleWriter, http.MethodPost, "/v1/people/import?mode=merge", []byte(csv), "Content-Type", "text/csv")
+	server.expect(t, http.StatusBadRequest, auth.RoleWriter, http.MethodPost, "/v1/people/import", []byte("name\n"), "Content-Type", "text/csv")
+
+	resp := server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/export?mask_iin=true", nil)
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Errorf("export Content-Disposition = %q", resp.Header.Get("Content-Disposition"))
+	}
+	var exported []string
+	scanner := bufio.NewScanner(bytes.NewReader(resp.Body))
+	for scanner.Scan() {
+		var person model.Person
+		if err := json.Unmarshal(scanner.Bytes(), &person); err != nil {
+			t.Fatalf("export line %q: %v", scanner.Text(), err)
+		}
+		exported = append(exported, person.IIN)
+	}
+	if len(exported) != 2 || exported[0] != iin.Mask(first) && exported[1] != iin.Mask(first) {
+		t.Errorf("masked export = %v", exported)
+	}
+
+	resp = server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/export?format=csv&name=daniyar", nil)
+	if lines := strings.Split(strings.TrimSpace(string(resp.Body)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], second) {
+		t.Errorf("filtered CSV export = %q", resp.Body)
+	}
+
+	server.expect(t, http.StatusBadRequest, auth.RoleReader, http.MethodGet, "/v1/people/export?format=xml", nil)
+	server.expect(t, http.StatusBadRequest, auth.RoleReader, http.MethodGet, "/v1/people/export?mask_iin=maybe", nil)
+}
+
+func TestJobs(t *testing.T) {
+	server := newServer(t, nil)
+	valid, invalid := validIIN(t, 1), invalidIIN(t, 1)
+
+	var job model.Job
+	resp := server.expect(t, http.StatusAccepted, jobClient, http.MethodPost, "/v1/jobs?type=validate", []byte(valid+"\n"+invalid))
+	resp.decode(t, &job)
+	if resp.Header.Get("Location") != fmt.Sprintf("/v1/jobs/%d", job.ID) {
+		t.Errorf("Location = %q", resp.Header.Get("Location"))
+	}
+
+	job = server.waitJob(t, job.ID)
+	if job.Status != model.JobStatusSucceeded || job.Processed != 2 || job.CreatedBy != "api_key:"+jobClient {
+		t.Fatalf("validate job = %+v", job)
+	}
+	result := server.expect(t, http.StatusOK, jobClient, http.MethodGet, fmt.Sprintf("/v1/jobs/%d/result", job.ID), nil)
+	if !bytes.Contains(result.Body, []byte(valid)) || !bytes.Contains(result.Body, []byte(invalid)) {
+		t.Errorf("validate result = %s", result.Body)
+	}
+
+	// Only the client that created a job and admins can see it.
+	for _, path := range []string{"/v1/jobs/%d", "/v1/jobs/%d/result"} {
+		resp = server.expect(t, http.StatusNotFound, auth.RoleReader, http.MethodGet, fmt.Sprintf(path, job.ID), nil)
+		if msg := resp.errorMessage(t); msg != "Job not found" {
+			t.Errorf("job of another client: %q", msg)
+		}
+		server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, fmt.Sprintf(path, job.ID), nil)
+	}
+	server.expect(t, http.StatusNotFound, auth.RoleWriter, http.MethodDelete, fmt.Sprintf("/v1/jobs/%d", job.ID), nil)
+
+	// A finished job can no longer be cancelled.
+	server.expect(t, http.StatusConflict, jobClient, http.MethodDelete, fmt.Sprintf("/v1/jobs/%d", job.ID), nil)
+
+	csv := fmt.Sprintf("name,iin,phone\nAliya Nurlanovna,%s,%s\n", valid, testPhone)
+	server.expect(t, http.StatusAccepted, jobClient, http.MethodPost, "/v1/jobs?type=import", []byte(csv), "Content-Type", "text/csv").decode(t, &job)
+	if job = server.waitJob(t, job.ID); job.Status != model.JobStatusSucceeded {
+		t.Fatalf("import job = %+v", job)
+	}
+	server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/info/iin/"+valid, nil)
+
+	resp = server.expect(t, http.StatusAccepted, jobClient, http.MethodPost, "/jobs?type=export&format=csv", nil)
+	resp.decode(t, &job)
+	if resp.Header.Get("Location") != fmt.Sprintf("/jobs/%d", job.ID) {
+		t.Errorf("Location of a job created without /v1 = %q", resp.Header.Get("Location"))
+	}
+	if job = server.waitJob(t, job.ID); job.Status != model.JobStatusSucceeded {
+		t.Fatalf("export job = %+v", job)
+	}
+	result = server.expect(t, http.StatusOK, jobClient, http.MethodGet, fmt.Sprintf("/jobs/%d/result", job.ID), nil)
+	if !strings.HasPrefix(result.Header.Get("Content-Type"), "text/csv") || !bytes.Contains(result.Body, []byte(valid)) {
+		t.Errorf("export result = %s", result.Body)
+	}
+
+	// An import whose file cannot be parsed fails and has no result.
+	server.expect(t, http.StatusAccepted, jobClient, http.MethodPost, "/v1/jobs?type=import", []byte("name\n"), "Content-Type", "text/csv").decode(t, &job)
+	if job = server.waitJob(t, job.ID); job.Status != model.JobStatusFailed || job.Error == nil {
+		t.Fatalf("broken import job = %+v", job)
+	}
+	resp = server.expect(t, http.StatusConflict, jobClient, http.MethodGet, fmt.Sprintf("/v1/jobs/%d/result", job.ID), nil)
+	if msg := resp.errorMessage(t); msg != "job result not available" {
+		t.Errorf("result of a failed job: %q", msg)
+	}
+
+	server.expect(t, http.StatusBadRequest, jobClient, http.MethodPost, "/v1/jobs?type=compress", []byte("x"))
+	server.expect(t, http.StatusBadRequest, jobClient, http.MethodPost, "/v1/jobs?type=validate", nil)
+	server.expect(t, http.StatusBadRequest, auth.RoleReader, http.MethodGet, "/v1/jobs/abc", nil)
+	resp = server.expect(t, http.StatusNotFound, auth.RoleReader, http.MethodGet, "/v1/jobs/999", nil)
+	if msg := resp.errorMessage(t); msg != "Job not found" {
+		t.Errorf("missing job: %q", msg)
+	}
+	server.expect(t, http.StatusNotFound, jobClient, http.MethodDelete, "/v1/jobs/999", nil)
+	server.expect(t, http.StatusNotFound, auth.RoleReader, http.MethodGet, "/v1/jobs/999/result", nil)
+}
+
+func TestAudit(t *testing.T) {
+	server := newServer(t, nil)
+	personIIN := validIIN(t, 1)
+	server.createPerson(t, "Aliya Nurlanovna", personIIN, testPhone)
+	server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/info/iin/"+personIIN, nil)
+	server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/info/name/aliya", nil)
+
+	var events []model.AuditEvent
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit?iin="+personIIN, nil).decode(t, &events)
+	if len(events) != 3 {
+		t.Fatalf("audit events for the IIN = %+v", events)
+	}
+	for _, event := range events {
+		if event.IINHash == "" || event.IINHash == personIIN || event.RequestID == "" {
+			t.Errorf("audit event = %+v", event)
+		}
+	}
+
+	events = nil
+	query := url.Values{"actor": {"api_key:" + auth.RoleWriter}, "action": {"create"}, "limit": {"1"}}
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit?"+query.Encode(), nil).decode(t, &events)
+	if len(events) != 1 || events[0].Action != "create" {
+		t.Errorf("audit events for the writer = %+v", events)
+	}
+
+	// A search is one event with the hashes of every result.
+	otherIIN := validIIN(t, 2)
+	server.createPerson(t, "Aliya Serikovna", otherIIN, testPhone)
+	server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/info/name/aliya", nil)
+	events = nil
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit?action=search&iin="+otherIIN, nil).decode(t, &events)
+	if len(events) != 1 || len(events[0].IINHashes) != 2 || events[0].IINHash != "" {
+		t.Errorf("search audit events = %+v", events)
+	}
+
+	// Failed and denied reads are audited too.
+	missingIIN := validIIN(t, 3)
+	server.expect(t, http.StatusNotFound, auth.RoleReader, http.MethodGet, "/v1/people/info/iin/"+missingIIN, nil)
+	server.expect(t, http.StatusForbidden, auth.RoleChecker, http.MethodGet, "/v1/people/info/iin/"+missingIIN, nil)
+	server.expect(t, http.StatusUnauthorized, "", http.MethodGet, "/v1/people/info/name/aliya", nil)
+	events = nil
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit?iin="+missingIIN, nil).decode(t, &events)
+	if len(events) != 2 ||
+		events[0].Action != "read" || events[0].Details != `{"status":404}` ||
+		events[1].Action != "denied" || events[1].Actor != "api_key:"+auth.RoleChecker || !strings.Contains(events[1].Details, `"status":403`) {
+		t.Errorf("audit events of failed reads = %+v", events)
+	}
+	events = nil
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit?action=denied&actor=anonymous", nil).decode(t, &events)
+	if len(events) != 1 || !strings.Contains(events[0].Details, `"status":401`) {
+		t.Errorf("audit events of unauthenticated reads = %+v", events)
+	}
+
+	var verification model.AuditVerification
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, "/v1/audit/verify", nil).decode(t, &verification)
+	if !verification.Valid || verification.Checked < 3 {
+		t.Errorf("verification = %+v", verification)
+	}
+
+	server.expect(t, http.StatusBadRequest, auth.RoleAdmin, http.MethodGet, "/v1/audit?limit=0", nil)
+	server.expect(t, http.StatusBadRequest, auth.RoleAdmin, http.MethodGet, "/v1/audit?from=yesterday", nil)
+	server.expect(t, http.StatusBadRequest, auth.RoleAdmin, http.MethodGet, "/v1/audit?to=tomorrow", nil)
+}
+
+func TestRateLimit(t *testing.T) {
+	server := newServer(t, map[string]ratelimit.Limit{ratelimit.DefaultRoute: {Rate: 0.01, Burst: 2}})
+	path := "/v1/iin_check/" + validIIN(t, 1)
+
+	for i := 0; i < 2; i++ {
+		resp := server.expect(t, http.StatusOK, auth.RoleChecker, http.MethodGet, path, nil)
+		if resp.Header.Get("RateLimit-Limit") != "2" {
+			t.Errorf("RateLimit-Limit = %q", resp.Header.Get("RateLimit-Limit"))
+		}
+	}
+	resp := server.expect(t, http.StatusTooManyRequests, auth.RoleChecker, http.MethodGet, path, nil)
+	if resp.Header.Get("Retry-After") == "" || resp.errorMessage(t) != "Rate limit exceeded" {
+		t.Errorf("429 = %s %v", resp.Body, resp.Header)
+	}
+
+	// Buckets are per client, so another API key is not affected.
+	server.expect(t, http.StatusOK, auth.RoleAdmin, http.MethodGet, path, nil)
+}
+
+func TestIPRateLimit(t *testing.T) {
+	server := newServer(t, map[string]ratelimit.Limit{ratelimit.IPRoute: {Rate: 0.01, Burst: 3}})
+	path := "/v1/people/info/iin/" + validIIN(t, 1)
+
+	// Bad credentials use up the IP bucket before authentication rejects them.
+	for i := 0; i < 3; i++ {
+		server.expect(t, http.StatusUnauthorized, "", http.MethodGet, path, nil, "X-API-Key", "ik_test_guess")
+	}
+	resp := server.expect(t, http.StatusTooManyRequests, "", http.MethodGet, path, nil, "X-API-Key", "ik_test_guess")
+	if resp.Header.Get("Retry-After") == "" || resp.errorMessage(t) != "Rate limit exceeded" {
+		t.Errorf("429 = %s %v", resp.Body, resp.Header)
+	}
+	// The limit is per address, so a valid key from it is rejected as well.
+	server.expect(t, http.StatusTooManyRequests, auth.RoleAdmin, http.MethodGet, path, nil)
+}
+
+func TestConcurrentCreatesOfTheSameIIN(t *testing.T) {
+	server := newServer(t, nil)
+	personIIN := validIIN(t, 1)
+
+	const clients = 20
+	statuses := make([]int, clients)
+	errs := make([]error, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := personJSON(fmt.Sprintf("Client %d", i), personIIN, testPhone)
+			resp, err := server.send(auth.RoleWriter, http.MethodPost, "/v1/people/info", body)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			statuses[i] = resp.Status
+			if resp.Status != http.StatusOK && !bytes.Contains(resp.Body, []byte("a person with this IIN already exists")) {
+				errs[i] = fmt.Errorf("client %d: unexpected response %d: %s", i, resp.Status, resp.Body)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i := range statuses {
+		if errs[i] != nil {
+			t.Error(errs[i])
+		}
+		if statuses[i] == http.StatusOK {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d of %d concurrent creates succeeded, want exactly 1", created, clients)
+	}
+
+	var people []model.Person
+	server.expect(t, http.StatusOK, auth.RoleReader, http.MethodGet, "/v1/people/info/name/client", nil).decode(t, &people)
+	if len(people) != 1 {
+		t.Errorf("%d people stored for one IIN", len(people))
+	}
+}
@@ -0,0 +1,276 @@
+// Package integration runs the HTTP API end to end: the whole server is
+// assembled as in cmd/server and served by httptest, on in-memory stores by
+// default or on the Postgres database in TEST_DATABASE_URL.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/api"
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/auth"
+	"github.com/toleubekov/check-iin-kaz/internal/fieldcrypt"
+	"github.com/toleubekov/check-iin-kaz/internal/jobs"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/ratelimit"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+)
+
+// roles get one API key each; the key of a role is "ik_test_" + role.
+var roles = []string{auth.RoleChecker, auth.RoleReader, auth.RoleWriter, auth.RoleAdmin}
+
+// jobClient submits jobs and reads them back, so its key has both the writer
+// and the reader role. Like the role keys, it is sent as the role argument.
+const jobClient = "jobs"
+
+// stores is one set of store implementations for a test server.
+type stores struct {
+	people      repository.PersonStore
+	idempotency repository.IdempotencyStore
+	jobs        repository.JobStore
+	audit       repository.AuditStore
+	quotas      repository.QuotaStore
+	apiKeys     repository.APIKeyStore
+	checkSchema api.SchemaChecker
+}
+
+func memoryStores() *stores {
+	return &stores{
+		people:      repository.NewMemoryPersonRepository(),
+		idempotency: repository.NewMemoryIdempotencyRepository(24 * time.Hour),
+		jobs:        repository.NewMemoryJobRepository(),
+		audit:       repository.NewMemoryAuditRepository(),
+		quotas:      repository.NewMemoryQuotaRepository(),
+		apiKeys:     repository.NewMemoryAPIKeyRepository(),
+		checkSchema: func(ctx context.Context) (*model.SchemaStatus, error) {
+			return &model.SchemaStatus{Version: repository.SchemaVersion, Expected: repository.SchemaVersion}, nil
+		},
+	}
+}
+
+// postgresStores connects to a migrated database and empties every table,
+// so tests using it must not run in parallel.
+func postgresStores(t *testing.T, dsn string) *stores {
+	t.Helper()
+	db, err := repository.ConnectDB(dsn, 1)
+	if err != nil {
+		t.Fatalf("Failed to connect to TEST_DATABASE_URL: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := repository.CheckSchema(context.Background(), db); err != nil {
+		t.Fatalf("TEST_DATABASE_URL is not migrated: %v", err)
+	}
+	if _, err := db.Exec("TRUNCATE people, jobs, idempotency_keys, audit_log, audit_log_head, api_keys, quota_usage RESTART IDENTITY"); err != nil {
+		t.Fatalf("Failed to clean the test database: %v", err)
+	}
+
+	keyring, err := fieldcrypt.NewKeyring(map[string][]byte{"test": bytes.Repeat([]byte("k"), 32)}, "test", bytes.Repeat([]byte("h"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stores{
+		people:      repository.NewPersonRepository(db, keyring),
+		idempotency: repository.NewIdempotencyRepository(db, 24*time.Hour),
+		jobs:        repository.NewJobRepository(db),
+		audit:       repository.NewAuditRepository(db),
+		quotas:      repository.NewQuotaRepository(db),
+		apiKeys:     repository.NewAPIKeyRepository(db),
+		checkSchema: api.PostgresSchemaChecker(db),
+	}
+}
+
+// testServer is a running server and a client for it.
+type testServer struct {
+	URL    string
+	client *http.Client
+}
+
+// newServer starts a server with authentication and the given rate limits,
+// which may be nil to disable them, and stops it when the test ends.
+func newServer(t *testing.T, limits map[string]ratelimit.Limit) *testServer {
+	t.Helper()
+	s := memoryStores()
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		s = postgresStores(t, dsn)
+	}
+
+	for _, role := range roles {
+		if _, err := s.apiKeys.Create(role, auth.HashAPIKey(apiKey(role)), []string{role}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.apiKeys.Create(jobClient, auth.HashAPIKey(apiKey(jobClient)), []string{auth.RoleWriter, auth.RoleReader}); err != nil {
+		t.Fatal(err)
+	}
+
+	iinService := service.NewIINService()
+	auditLogger := audit.NewLogger(s.audit, []byte("integration-test-hash-key"))
+
+	ctx, stopJobs := context.WithCancel(context.Background())
+	jobManager := jobs.NewManager(s.jobs, s.people, iinService, auditLogger, 2)
+	jobManager.Start(ctx)
+
+	handler := api.NewHandler(iinService, s.people, jobManager, s.idempotency, auditLogger)
+	opts := api.RouterOptions{Authenticator: auth.NewAuthenticator(s.apiKeys, nil)}
+	if limits != nil {
+		opts.Limiter = ratelimit.NewLimiter(limits, s.quotas, 0)
+	}
+	router := api.SetupRouter(handler, opts)
+
+	server := httptest.NewServer(api.NewServeMux(router, api.NewHealthHandler(s.checkSchema)))
+	t.Cleanup(func() {
+		server.Close()
+		stopJobs()
+		jobManager.Wait()
+	})
+	return &testServer{URL: server.URL, client: server.Client()}
+}
+
+func apiKey(role string) string {
+	return "ik_test_" + role
+}
+
+// response is a read HTTP response.
+type response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// decode unmarshals the body into v and fails the test if it is not valid JSON.
+func (r *response) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("Response %q is not valid JSON: %v", r.Body, err)
+	}
+}
+
+// errorMessage returns the errors field of an error envelope.
+func (r *response) errorMessage(t *testing.T) string {
+	t.Helper()
+	var envelope model.PersonResponse
+	r.decode(t, &envelope)
+	if envelope.Success {
+		t.Errorf("Error response has success=true: %s", r.Body)
+	}
+	return envelope.Errors
+}
+
+// send performs a request with the API key of role, or without credentials
+// if role is empty. headers are name, value pairs. Unlike do it does not
+// touch t, so it can be called from other goroutines.
+func (s *testServer) send(role, method, path string, body []byte, headers ...string) (*response, error) {
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		req.Header.Set("X-API-Key", apiKey(role))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: failed to read response: %w", method, path, err)
+	}
+	return &response{Status: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+// do is send that fails the test on transport errors.
+func (s *testServer) do(t *testing.T, role, method, path string, body []byte, headers ...string) *response {
+	t.Helper()
+	resp, err := s.send(role, method, path, body, headers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// expect sends a request and fails the test unless it gets status.
+func (s *testServer) expect(t *testing.T, status int, role, method, path string, body []byte, headers ...string) *response {
+	t.Helper()
+	resp := s.do(t, role, method, path, body, headers...)
+	if resp.Status != status {
+		t.Fatalf("%s %s = %d, want %d: %s", method, path, resp.Status, status, resp.Body)
+	}
+	return resp
+}
+
+// createPerson creates a person as a writer and fails the test if that does not succeed.
+func (s *testServer) createPerson(t *testing.T, name, personIIN, phone string) {
+	t.Helper()
+	s.expect(t, http.StatusOK, auth.RoleWriter, http.MethodPost, "/v1/people/info", personJSON(name, personIIN, phone))
+}
+
+// waitJob polls a job jobClient created until it leaves the queued and
+// running states.
+func (s *testServer) waitJob(t *testing.T, id int64) model.Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job model.Job
+		s.expect(t, http.StatusOK, jobClient, http.MethodGet, fmt.Sprintf("/v1/jobs/%d", id), nil).decode(t, &job)
+		if job.Status != model.JobStatusQueued && job.Status != model.JobStatusRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %d is still %s", id, job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func personJSON(name, personIIN, phone string) []byte {
+	data, _ := json.Marshal(model.Person{Name: name, IIN: personIIN, Phone: phone})
+	return data
+}
+
+// validIIN returns a valid IIN of a man born on 1 January 1990 with the given
+// serial number, completed with whichever check digit makes it valid.
+func validIIN(t *testing.T, serial int) string {
+	t.Helper()
+	for {
+		prefix := fmt.Sprintf("9001013%04d", serial)
+		for digit := 0; digit <= 9; digit++ {
+			candidate := fmt.Sprintf("%s%d", prefix, digit)
+			if iin.IsValid(candidate) {
+				return candidate
+			}
+		}
+		// Serial numbers whose checksum comes out as 10 have no valid IIN.
+		serial += 1000
+		if serial > 9999 {
+			t.Fatalf("No valid IIN for serial %d", serial)
+		}
+	}
+}
+
+// invalidIIN returns validIIN(serial) with a wrong check digit.
+func invalidIIN(t *testing.T, serial int) string {
+	t.Helper()
+	valid := validIIN(t, serial)
+	last := (valid[11]-'0'+1)%10 + '0'
+	return valid[:11] + string(last)
+}
@@ -0,0 +1,445 @@
+// Package jobs runs long imports, validations and exports in the background.
+//
+// Jobs are stored in the jobs table, so a job queued or interrupted by a
+// restart is picked up again by the next worker that starts. Workers claim
+// jobs with SELECT ... FOR UPDATE SKIP LOCKED, which lets several server
+// instances share one queue.
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+)
+
+const (
+	pollInterval      = 2 * time.Second
+	heartbeatInterval = 5 * time.Second
+	staleAfter        = 30 * time.Second
+	progressEvery     = 500
+
+	// MaxResultSize caps the result a job builds in memory and stores in the
+	// jobs table. A job whose result grows past it fails instead.
+	MaxResultSize = 64 << 20
+)
+
+// ErrInvalidJob is wrapped by errors caused by a bad job submission rather than a server failure.
+var ErrInvalidJob = errors.New("invalid job")
+
+// ErrResultTooLarge fails a job whose result exceeds MaxResultSize.
+var ErrResultTooLarge = fmt.Errorf("job result exceeds %d MiB, split the job into smaller ones", MaxResultSize>>20)
+
+type Manager struct {
+	jobs       repository.JobStore
+	people     repository.PersonStore
+	importer   *service.PersonImporter
+	iinService *service.IINService
+	audit      *audit.Logger
+	workers    int
+	maxResult  int
+
+	wake chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[int64]context.CancelFunc
+}
+
+func NewManager(jobs repository.JobStore, people repository.PersonStore, iinService *service.IINService, auditLogger *audit.Logger, workers int) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Manager{
+		jobs:       jobs,
+		people:     people,
+		importer:   service.NewPersonImporter(iinService, people),
+		iinService: iinService,
+		audit:      auditLogger,
+		workers:    workers,
+		maxResult:  MaxResultSize,
+		wake:       make(chan struct{}, 1),
+		running:    map[int64]context.CancelFunc{},
+	}
+}
+
+// Start launches the worker pool. Workers stop when ctx is cancelled; jobs
+// they were running are put back in the queue. Use Wait to block until all
+// workers have exited.
+func (m *Manager) Start(ctx context.Context) {
+	log.Printf("Starting %d job workers", m.workers)
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.work(ctx)
+		}()
+	}
+}
+
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Submit validates the job parameters, stores the job on behalf of actor and wakes a worker.
+func (m *Manager) Submit(actor, jobType string, params model.JobParams, input []byte) (*model.Job, error) {
+	if err := validateParams(jobType, &params, input); err != nil {
+		return nil, err
+	}
+
+	job, err := m.jobs.Create(jobType, actor, params, input)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (m *Manager) Get(id int64) (*model.Job, error) {
+	return m.jobs.GetByID(id)
+}
+
+// Result returns the downloadable output of a succeeded job.
+func (m *Manager) Result(id int64) (*model.JobResult, error) {
+	job, err := m.jobs.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != model.JobStatusSucceeded {
+		return nil, errors.New("job result not available")
+	}
+	return m.jobs.GetResult(id)
+}
+
+// Cancel stops a queued or running job. A job running on another server
+// instance notices the cancellation on its next heartbeat.
+func (m *Manager) Cancel(id int64) (*model.Job, error) {
+	cancelled, err := m.jobs.Cancel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := m.jobs.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return job, errors.New("job already finished")
+	}
+
+	m.mu.Lock()
+	if cancel, ok := m.running[id]; ok {
+		cancel()
+	}
+	m.mu.Unlock()
+
+	return job, nil
+}
+
+func (m *Manager) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := m.jobs.Claim(ctx, staleAfter)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("ERROR: Failed to claim job: %v", err)
+		}
+		if job != nil {
+			m.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) run(ctx context.Context, job *model.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m.mu.Lock()
+	m.running[job.ID] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.ID)
+		m.mu.Unlock()
+	}()
+
+	log.Printf("Job %d (%s) started", job.ID, job.Type)
+
+	p := &progress{jobs: m.jobs, id: job.ID, cancel: cancel}
+	stopHeartbeat := p.startHeartbeat(jobCtx)
+	defer stopHeartbeat()
+
+	var params model.JobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		m.finish(ctx, jobCtx, job, p, nil, nil, fmt.Errorf("invalid job params: %w", err))
+		return
+	}
+
+	var summary interface{}
+	var result *model.JobResult
+	var err error
+	switch job.Type {
+	case model.JobTypeImport:
+		summary, result, err = m.runImport(jobCtx, job, params, p)
+	case model.JobTypeValidate:
+		summary, result, err = m.runValidate(jobCtx, job.ID, p)
+	case model.JobTypeExport:
+		summary, result, err = m.runExport(jobCtx, job, params, p)
+	default:
+		err = fmt.Errorf("unknown job type: %s", job.Type)
+	}
+
+	m.finish(ctx, jobCtx, job, p, summary, result, err)
+}
+
+// finish records how a job ended. The job store only moves a job out of
+// running once, so a job cancelled while it was finishing stays cancelled and
+// its result is dropped.
+func (m *Manager) finish(ctx, jobCtx context.Context, job *model.Job, p *progress, summary interface{}, result *model.JobResult, err error) {
+	switch {
+	case err == nil:
+		processed, total := p.get()
+		completed, err := m.jobs.Complete(job.ID, processed, total, summary, result)
+		if err != nil {
+			log.Printf("ERROR: Failed to complete job %d: %v", job.ID, err)
+			return
+		}
+		if !completed {
+			log.Printf("Job %d (%s) finished after it was cancelled, result discarded", job.ID, job.Type)
+			return
+		}
+		log.Printf("Job %d (%s) succeeded", job.ID, job.Type)
+	case ctx.Err() != nil:
+		// The server is shutting down; let the next worker start the job over.
+		requeued, err := m.jobs.Requeue(job.ID)
+		if err != nil {
+			log.Printf("ERROR: Failed to requeue job %d: %v", job.ID, err)
+			return
+		}
+		if requeued {
+			log.Printf("Job %d (%s) interrupted by shutdown and requeued", job.ID, job.Type)
+		}
+	case jobCtx.Err() != nil:
+		log.Printf("Job %d (%s) cancelled", job.ID, job.Type)
+	default:
+		failed, failErr := m.jobs.Fail(job.ID, err.Error())
+		if failErr != nil {
+			log.Printf("ERROR: Failed to mark job %d as failed: %v", job.ID, failErr)
+			return
+		}
+		if failed {
+			log.Printf("ERROR: Job %d (%s) failed: %v", job.ID, job.Type, err)
+		}
+	}
+}
+
+func (m *Manager) runImport(ctx context.Context, job *model.Job, params model.JobParams, p *progress) (interface{}, *model.JobResult, error) {
+	input, err := m.jobs.GetInput(job.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, err := service.ParseImport(bytes.NewReader(input), params.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.set(0, len(records))
+
+	report, err := m.importer.Import(ctx, records, repository.ImportMode(params.Mode), func(done int) {
+		p.set(done, len(records))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	p.set(len(records), len(records))
+	m.audit.LogImport(audit.Entry{Actor: job.CreatedBy, Details: map[string]interface{}{"job_id": job.ID}}, report)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > m.maxResult {
+		return nil, nil, ErrResultTooLarge
+	}
+
+	summary := *report
+	summary.Rows = nil
+	return summary, &model.JobResult{Data: data, ContentType: "application/json"}, nil
+}
+
+func (m *Manager) runValidate(ctx context.Context, id int64, p *progress) (interface{}, *model.JobResult, error) {
+	input, err := m.jobs.GetInput(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iins := parseIINList(input)
+	p.set(0, len(iins))
+
+	buf := &resultBuffer{max: m.maxResult}
+	encoder := json.NewEncoder(buf)
+	valid := 0
+	for i, value := range iins {
+		if i%progressEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			p.set(i, len(iins))
+		}
+
+		result := model.IINCheckResult{IIN: value}
+		correct, sex, dateOfBirth, err := m.iinService.ValidateIIN(value)
+		if correct && err == nil {
+			result.Correct = true
+			result.Sex = sex
+			result.DateOfBirth = dateOfBirth
+			valid++
+		} else if err != nil {
+			result.Error = err.Error()
+		}
+
+		if err := encoder.Encode(result); err != nil {
+			return nil, nil, err
+		}
+	}
+	p.set(len(iins), len(iins))
+
+	summary := map[string]int{
+		"total":   len(iins),
+		"valid":   valid,
+		"invalid": len(iins) - valid,
+	}
+	return summary, &model.JobResult{Data: buf.Bytes(), ContentType: "application/x-ndjson"}, nil
+}
+
+func (m *Manager) runExport(ctx context.Context, job *model.Job, params model.JobParams, p *progress) (interface{}, *model.JobResult, error) {
+	buf := &resultBuffer{max: m.maxResult}
+	writer, err := service.NewExportWriter(buf, params.Format, params.MaskIIN)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := model.PersonFilter{NamePart: params.Name}
+	err = m.people.ExportPeople(ctx, filter, func(person model.Person) error {
+		if err := writer.Write(person); err != nil {
+			return err
+		}
+		if writer.Count()%progressEvery == 0 {
+			p.set(writer.Count(), writer.Count())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, nil, err
+	}
+	p.set(writer.Count(), writer.Count())
+
+	m.audit.Log(audit.Entry{
+		Actor:  job.CreatedBy,
+		Action: audit.ActionExport,
+		Details: map[string]interface{}{
+			"job_id":   job.ID,
+			"format":   params.Format,
+			"name":     params.Name,
+			"mask_iin": params.MaskIIN,
+			"rows":     writer.Count(),
+		},
+	})
+
+	summary := map[string]int{"rows": writer.Count()}
+	return summary, &model.JobResult{Data: buf.Bytes(), ContentType: service.ExportContentType(params.Format)}, nil
+}
+
+// resultBuffer is a bytes.Buffer that refuses to grow past max bytes, so a
+// job cannot run the worker out of memory.
+type resultBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *resultBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, ErrResultTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+func validateParams(jobType string, params *model.JobParams, input []byte) error {
+	switch jobType {
+	case model.JobTypeImport:
+		if params.Mode == "" {
+			params.Mode = string(repository.ImportModeSkip)
+		}
+		switch repository.ImportMode(params.Mode) {
+		case repository.ImportModeSkip, repository.ImportModeUpsert, repository.ImportModeFail:
+		default:
+			return invalidJob("invalid import mode, expected skip, upsert or fail")
+		}
+		if params.Format != service.ImportFormatCSV && params.Format != service.ImportFormatNDJSON {
+			return invalidJob("import must be text/csv or application/x-ndjson")
+		}
+		if len(input) == 0 {
+			return invalidJob("import job requires a request body")
+		}
+	case model.JobTypeValidate:
+		if len(input) == 0 {
+			return invalidJob("validate job requires a list of IINs in the request body")
+		}
+	case model.JobTypeExport:
+		if params.Format == "" {
+			params.Format = service.ExportFormatNDJSON
+		}
+		if params.Format != service.ExportFormatCSV && params.Format != service.ExportFormatNDJSON {
+			return invalidJob("invalid export format, expected csv or ndjson")
+		}
+	default:
+		return invalidJob("invalid job type, expected import, validate or export")
+	}
+	return nil
+}
+
+func invalidJob(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidJob, reason)
+}
+
+// parseIINList reads IINs separated by newlines, commas or whitespace.
+func parseIINList(input []byte) []string {
+	var iins []string
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		for _, value := range strings.Split(scanner.Text(), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				iins = append(iins, value)
+			}
+		}
+	}
+	return iins
+}
@@ -0,0 +1,283 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/audit"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+	"github.com/toleubekov/check-iin-kaz/internal/service"
+)
+
+func newTestManager(store repository.JobStore) *Manager {
+	auditLogger := audit.NewLogger(repository.NewMemoryAuditRepository(), []byte("test"))
+	return NewManager(store, repository.NewMemoryPersonRepository(), service.NewIINService(), auditLogger, 1)
+}
+
+// jobIn creates a job and moves it to status through the store's own transitions.
+func jobIn(t *testing.T, store repository.JobStore, status string) int64 {
+	t.Helper()
+	job, err := store.Create(model.JobTypeValidate, "test", model.JobParams{}, []byte("031231500126"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == model.JobStatusQueued {
+		return job.ID
+	}
+	if _, err := store.Claim(context.Background(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var ok bool
+	switch status {
+	case model.JobStatusRunning:
+		ok = true
+	case model.JobStatusSucceeded:
+		ok, err = store.Complete(job.ID, 1, 1, nil, &model.JobResult{Data: []byte("{}"), ContentType: "application/json"})
+	case model.JobStatusFailed:
+		ok, err = store.Fail(job.ID, "broken")
+	case model.JobStatusCancelled:
+		ok, err = store.Cancel(job.ID)
+	}
+	if err != nil || !ok {
+		t.Fatalf("moving job to %s: %t, %v", status, ok, err)
+	}
+	return job.ID
+}
+
+func TestJobTransitions(t *testing.T) {
+	operations := map[string]func(store repository.JobStore, id int64) (bool, error){
+		"complete": func(store repository.JobStore, id int64) (bool, error) {
+			return store.Complete(id, 1, 1, nil, &model.JobResult{Data: []byte("{}"), ContentType: "application/json"})
+		},
+		"fail":    func(store repository.JobStore, id int64) (bool, error) { return store.Fail(id, "broken") },
+		"requeue": func(store repository.JobStore, id int64) (bool, error) { return store.Requeue(id) },
+		"cancel":  func(store repository.JobStore, id int64) (bool, error) { return store.Cancel(id) },
+		"heartbeat": func(store repository.JobStore, id int64) (bool, error) {
+			return store.Heartbeat(id, 1, 1)
+		},
+	}
+
+	tests := []struct {
+		from      string
+		operation string
+		applied   bool
+		to        string
+	}{
+		{model.JobStatusQueued, "complete", false, model.JobStatusQueued},
+		{model.JobStatusQueued, "fail", false, model.JobStatusQueued},
+		{model.JobStatusQueued, "requeue", false, model.JobStatusQueued},
+		{model.JobStatusQueued, "cancel", true, model.JobStatusCancelled},
+		{model.JobStatusQueued, "heartbeat", false, model.JobStatusQueued},
+
+		{model.JobStatusRunning, "complete", true, model.JobStatusSucceeded},
+		{model.JobStatusRunning, "fail", true, model.JobStatusFailed},
+		{model.JobStatusRunning, "requeue", true, model.JobStatusQueued},
+		{model.JobStatusRunning, "cancel", true, model.JobStatusCancelled},
+		{model.JobStatusRunning, "heartbeat", true, model.JobStatusRunning},
+
+		// A finished job never changes again.
+		{model.JobStatusCancelled, "complete", false, model.JobStatusCancelled},
+		{model.JobStatusCancelled, "fail", false, model.JobStatusCancelled},
+		{model.JobStatusCancelled, "requeue", false, model.JobStatusCancelled},
+		{model.JobStatusCancelled, "cancel", false, model.JobStatusCancelled},
+		{model.JobStatusCancelled, "heartbeat", false, model.JobStatusCancelled},
+		{model.JobStatusSucceeded, "fail", false, model.JobStatusSucceeded},
+		{model.JobStatusSucceeded, "cancel", false, model.JobStatusSucceeded},
+		{model.JobStatusFailed, "complete", false, model.JobStatusFailed},
+		{model.JobStatusFailed, "cancel", false, model.JobStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"/"+tt.operation, func(t *testing.T) {
+			store := repository.NewMemoryJobRepository()
+			id := jobIn(t, store, tt.from)
+
+			applied, err := operations[tt.operation](store, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			job, _ := store.GetByID(id)
+			if applied != tt.applied || job.Status != tt.to {
+				t.Errorf("%s on a %s job = %t, status %s; want %t, %s", tt.operation, tt.from, applied, job.Status, tt.applied, tt.to)
+			}
+		})
+	}
+}
+
+// cancelOnComplete cancels a job just before the worker completes it, which
+// is what happens when a DELETE /jobs/{id} arrives as the job finishes.
+type cancelOnComplete struct {
+	*repository.MemoryJobRepository
+}
+
+func (s cancelOnComplete) Complete(id int64, processed, total int, summary interface{}, result *model.JobResult) (bool, error) {
+	if _, err := s.Cancel(id); err != nil {
+		return false, err
+	}
+	return s.MemoryJobRepository.Complete(id, processed, total, summary, result)
+}
+
+func TestCancelWhileCompleting(t *testing.T) {
+	store := cancelOnComplete{repository.NewMemoryJobRepository()}
+	manager := newTestManager(store)
+
+	submitted, err := manager.Submit("test", model.JobTypeValidate, model.JobParams{}, []byte("031231500126"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := store.Claim(context.Background(), time.Hour)
+	if err != nil || job == nil || job.ID != submitted.ID {
+		t.Fatalf("Claim = %+v, %v", job, err)
+	}
+	manager.run(context.Background(), job)
+
+	job, _ = manager.Get(submitted.ID)
+	if job.Status != model.JobStatusCancelled || job.Result != nil {
+		t.Errorf("job = %+v, want cancelled without a result", job)
+	}
+	if _, err := manager.Result(submitted.ID); err == nil || err.Error() != "job result not available" {
+		t.Errorf("Result error = %v", err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	manager := newTestManager(repository.NewMemoryJobRepository())
+
+	// Cancelled before any worker runs.
+	queued, err := manager.Submit("test", model.JobTypeValidate, model.JobParams{}, []byte("031231500126"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err := manager.Cancel(queued.ID); err != nil || job.Status != model.JobStatusCancelled {
+		t.Fatalf("Cancel = %+v, %v", job, err)
+	}
+	if _, err := manager.Cancel(queued.ID); err == nil || err.Error() != "job already finished" {
+		t.Errorf("second Cancel error = %v", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	manager.Start(ctx)
+	defer func() {
+		stop()
+		manager.Wait()
+	}()
+
+	submitted, err := manager.Submit("test", model.JobTypeValidate, model.JobParams{}, []byte("031231500126, 123\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	job, _ := manager.Get(submitted.ID)
+	for job.Status != model.JobStatusSucceeded && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		job, _ = manager.Get(submitted.ID)
+	}
+	if job.Status != model.JobStatusSucceeded || job.Processed != 2 || job.Total != 2 {
+		t.Fatalf("job = %+v, want succeeded with 2 IINs", job)
+	}
+	if string(*job.Result) != `{"invalid":1,"total":2,"valid":1}` {
+		t.Errorf("summary = %s", *job.Result)
+	}
+
+	result, err := manager.Result(submitted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(result.Data)), "\n")
+	if result.ContentType != "application/x-ndjson" || len(lines) != 2 || !strings.Contains(lines[0], `"correct":true`) {
+		t.Errorf("result = %s %q", result.ContentType, result.Data)
+	}
+	if _, err := manager.Cancel(submitted.ID); err == nil {
+		t.Errorf("cancelled a succeeded job")
+	}
+}
+
+func TestResultTooLarge(t *testing.T) {
+	manager := newTestManager(repository.NewMemoryJobRepository())
+	manager.maxResult = 100
+	ctx, stop := context.WithCancel(context.Background())
+	manager.Start(ctx)
+	defer func() {
+		stop()
+		manager.Wait()
+	}()
+
+	tests := []struct {
+		jobType string
+		params  model.JobParams
+		input   string
+	}{
+		{model.JobTypeValidate, model.JobParams{}, strings.Repeat("031231500126\n", 10)},
+		{model.JobTypeExport, model.JobParams{Format: "csv"}, ""},
+	}
+	for _, tt := range tests {
+		if tt.jobType == model.JobTypeExport {
+			for _, iin := range []string{"031231500126", "900101300007", "850615400128"} {
+				if err := manager.people.Create(&model.Person{Name: "Aliya Nurlanovna Bekova", IIN: iin, Phone: "+77011234567"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		submitted, err := manager.Submit("test", tt.jobType, tt.params, []byte(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		job, _ := manager.Get(submitted.ID)
+		for job.Status != model.JobStatusFailed && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			job, _ = manager.Get(submitted.ID)
+		}
+		if job.Status != model.JobStatusFailed || job.Error == nil || *job.Error != ErrResultTooLarge.Error() {
+			t.Errorf("%s job = %+v, want failed with %q", tt.jobType, job, ErrResultTooLarge)
+		}
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		jobType string
+		params  model.JobParams
+		input   string
+		want    model.JobParams
+		invalid bool
+	}{
+		{model.JobTypeImport, model.JobParams{Format: "csv"}, "name,iin,phone", model.JobParams{Format: "csv", Mode: "skip"}, false},
+		{model.JobTypeImport, model.JobParams{Format: "csv", Mode: "replace"}, "name,iin,phone", model.JobParams{}, true},
+		{model.JobTypeImport, model.JobParams{Format: "xml"}, "<people/>", model.JobParams{}, true},
+		{model.JobTypeImport, model.JobParams{Format: "ndjson"}, "", model.JobParams{}, true},
+		{model.JobTypeValidate, model.JobParams{}, "031231500126", model.JobParams{}, false},
+		{model.JobTypeValidate, model.JobParams{}, "", model.JobParams{}, true},
+		{model.JobTypeExport, model.JobParams{}, "", model.JobParams{Format: "ndjson"}, false},
+		{model.JobTypeExport, model.JobParams{Format: "xlsx"}, "", model.JobParams{}, true},
+		{"reindex", model.JobParams{}, "", model.JobParams{}, true},
+	}
+
+	for _, tt := range tests {
+		params := tt.params
+		err := validateParams(tt.jobType, &params, []byte(tt.input))
+		if tt.invalid {
+			if !errors.Is(err, ErrInvalidJob) {
+				t.Errorf("validateParams(%s, %+v) error = %v, want ErrInvalidJob", tt.jobType, tt.params, err)
+			}
+			continue
+		}
+		if err != nil || params != tt.want {
+			t.Errorf("validateParams(%s, %+v) = %+v, %v; want %+v", tt.jobType, tt.params, params, err, tt.want)
+		}
+	}
+}
+
+func TestParseIINList(t *testing.T) {
+	got := parseIINList([]byte("031231500126,900101300017\n 123 ,\n\n  456"))
+	want := []string{"031231500126", "900101300017", "123", "456"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseIINList = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+// progress tracks how far a running job has got and periodically reports it
+// to the database, which also serves as the job's heartbeat.
+type progress struct {
+	jobs   repository.JobStore
+	id     int64
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	processed int
+	total     int
+}
+
+func (p *progress) set(processed, total int) {
+	p.mu.Lock()
+	p.processed = processed
+	p.total = total
+	p.mu.Unlock()
+}
+
+func (p *progress) get() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.processed, p.total
+}
+
+// startHeartbeat reports progress every heartbeatInterval until the returned
+// function is called. If the job is no longer running in the database, for
+// example because it was cancelled through another server instance, the job
+// context is cancelled.
+func (p *progress) startHeartbeat(ctx context.Context) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processed, total := p.get()
+				running, err := p.jobs.Heartbeat(p.id, processed, total)
+				if err != nil {
+					log.Printf("ERROR: Job %d heartbeat failed: %v", p.id, err)
+					continue
+				}
+				if !running {
+					p.cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,54 @@
+// Package metrics exposes server metrics in the Prometheus text format.
+//
+// The metrics themselves are package-level variables on Default, so any
+// package can record into them without threading a registry through
+// constructors; the server serves Default at /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"time"
+)
+
+var Default = NewRegistry()
+
+var (
+	HTTPRequests = Default.NewCounterVec("http_requests_total",
+		"HTTP requests by route, method and status code.", "route", "method", "status")
+	HTTPRequestDuration = Default.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by route, method and status code.", nil, "route", "method", "status")
+	IINValidations = Default.NewCounterVec("iin_validations_total",
+		"IIN validations by outcome (valid or invalid) and failure reason.", "outcome", "reason")
+	DBQueryDuration = Default.NewHistogramVec("db_query_duration_seconds",
+		"Repository query latency by query name.", nil, "query")
+	AuditEvents = Default.NewCounterVec("audit_events_total",
+		"Audit events by outcome: written to the audit log or dropped.", "outcome")
+	AuditAppendErrors = Default.NewCounterVec("audit_append_errors_total",
+		"Failed attempts to append a batch of audit events, including retried ones.")
+)
+
+// ObserveQuery records the latency of a repository query started at start;
+// call it as defer metrics.ObserveQuery("people.get_by_iin", time.Now()).
+func ObserveQuery(query string, start time.Time) {
+	DBQueryDuration.Observe(time.Since(start).Seconds(), query)
+}
+
+// RegisterDBStats exports the connection pool statistics of db.
+func RegisterDBStats(db *sql.DB) {
+	Default.NewGaugeFunc("db_open_connections", "Established connections, in use and idle.",
+		func() float64 { return float64(db.Stats().OpenConnections) })
+	Default.NewGaugeFunc("db_in_use_connections", "Connections currently in use.",
+		func() float64 { return float64(db.Stats().InUse) })
+	Default.NewGaugeFunc("db_idle_connections", "Idle connections.",
+		func() float64 { return float64(db.Stats().Idle) })
+	Default.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections.",
+		func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	Default.NewCounterFunc("db_wait_count_total", "Connections waited for because the pool was exhausted.",
+		func() float64 { return float64(db.Stats().WaitCount) })
+	Default.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		func() float64 { return db.Stats().WaitDuration.Seconds() })
+	Default.NewCounterFunc("db_max_idle_closed_total", "Connections closed because of the idle pool limit.",
+		func() float64 { return float64(db.Stats().MaxIdleClosed) })
+	Default.NewCounterFunc("db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.",
+		func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Requests.", "route", "status")
+	latency := registry.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	registry.NewGaugeFunc("connections", "Open connections.", func() float64 { return 3 })
+
+	requests.Inc("/iin_check/{iin}", "200")
+	requests.Inc("/iin_check/{iin}", "200")
+	requests.Inc(`/a"b`, "500")
+	latency.Observe(0.05, "/people")
+	latency.Observe(0.5, "/people")
+	latency.Observe(2, "/people")
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="500"} 1
+requests_total{route="/iin_check/{iin}",status="200"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/people",le="0.1"} 1
+latency_seconds_bucket{route="/people",le="1"} 2
+latency_seconds_bucket{route="/people",le="+Inf"} 3
+latency_seconds_sum{route="/people"} 2.55
+latency_seconds_count{route="/people"} 3
+# HELP connections Open connections.
+# TYPE connections gauge
+connections 3
+`
+	if out.String() != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", out.String(), want)
+	}
+}
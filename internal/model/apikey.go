@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKey struct {
+	ID        int            `json:"id" db:"id"`
+	Name      string         `json:"name" db:"name"`
+	KeyHash   string         `json:"-" db:"key_hash"`
+	Roles     pq.StringArray `json:"roles" db:"roles"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+}
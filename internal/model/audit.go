@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type AuditEvent struct {
+	ID         int64     `json:"id" db:"id"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+	Actor      string    `json:"actor" db:"actor"`
+	Action     string    `json:"action" db:"action"`
+	IINHash    string    `json:"iin_hash,omitempty" db:"iin_hash"`
+	// IINHashes holds the hashes of a request that touched several people.
+	IINHashes pq.StringArray `json:"iin_hashes,omitempty" db:"iin_hashes"`
+	RequestID string         `json:"request_id,omitempty" db:"request_id"`
+	ClientIP  string         `json:"client_ip,omitempty" db:"client_ip"`
+	Details   string         `json:"details,omitempty" db:"details"`
+	PrevHash  string         `json:"prev_hash" db:"prev_hash"`
+	Hash      string         `json:"hash" db:"hash"`
+}
+
+type AuditFilter struct {
+	IINHash string
+	Actor   string
+	Action  string
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
+
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenID int64  `json:"broken_id,omitempty"`
+	Errors   string `json:"errors,omitempty"`
+}
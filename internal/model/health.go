@@ -0,0 +1,13 @@
+package model
+
+type SchemaStatus struct {
+	Version  int  `json:"version"`
+	Expected int  `json:"expected"`
+	Dirty    bool `json:"dirty"`
+}
+
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Schema *SchemaStatus `json:"schema,omitempty"`
+	Errors string        `json:"errors,omitempty"`
+}
@@ -0,0 +1,9 @@
+package model
+
+type IdempotencyRecord struct {
+	Client       string `db:"client"`
+	Key          string `db:"key"`
+	RequestHash  string `db:"request_hash"`
+	StatusCode   *int   `db:"status_code"`
+	ResponseBody []byte `db:"response_body"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobTypeImport   = "import"
+	JobTypeValidate = "validate"
+	JobTypeExport   = "export"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+type Job struct {
+	ID         int64            `json:"id" db:"id"`
+	Type       string           `json:"type" db:"type"`
+	Status     string           `json:"status" db:"status"`
+	Params     json.RawMessage  `json:"params" db:"params"`
+	Processed  int              `json:"processed" db:"processed"`
+	Total      int              `json:"total" db:"total"`
+	Result     *json.RawMessage `json:"result,omitempty" db:"result"`
+	Error      *string          `json:"error,omitempty" db:"error"`
+	CreatedBy  string           `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// JobParams holds the options of every job type; each type reads only its own fields.
+type JobParams struct {
+	Mode    string `json:"mode,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Name    string `json:"name,omitempty"`
+	MaskIIN bool   `json:"mask_iin,omitempty"`
+}
+
+type JobResult struct {
+	Data        []byte
+	ContentType string
+}
+
+type IINCheckResult struct {
+	IIN         string `json:"iin"`
+	Correct     bool   `json:"correct"`
+	Sex         string `json:"sex,omitempty"`
+	DateOfBirth string `json:"date_of_birth,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
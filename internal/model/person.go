@@ -6,13 +6,63 @@ type Person struct {
 	Phone string `json:"phone" db:"phone"`
 }
 
+type PersonFilter struct {
+	NamePart string
+}
+
 type IINResponse struct {
 	Correct     bool   `json:"correct"`
 	Sex         string `json:"sex,omitempty"`
 	DateOfBirth string `json:"date_of_birth,omitempty"`
 }
 
+// IINInfoResponse is the /v1 check response: everything the IIN encodes,
+// or why it is invalid.
+type IINInfoResponse struct {
+	Correct bool `json:"correct"`
+	// Category is "citizen", or with IIN_ALLOW_NON_CITIZEN "foreign" or
+	// "unknown", which have no sex, date of birth, century or age.
+	Category    string `json:"category,omitempty"`
+	Sex         string `json:"sex,omitempty"`
+	DateOfBirth string `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	Century     int    `json:"century,omitempty"`
+	// SerialNumber is a pointer because 0000 is a valid serial number.
+	SerialNumber *int   `json:"serial_number,omitempty"`
+	Age          *int   `json:"age,omitempty"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
 type PersonResponse struct {
-	Success bool   `json:"success"`
-	Errors  string `json:"errors,omitempty"`
+	Success   bool   `json:"success"`
+	Errors    string `json:"errors,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
+
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	IIN    string `json:"iin,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Success bool              `json:"success"`
+	Mode    string            `json:"mode"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Invalid int               `json:"invalid"`
+	Rows    []ImportRowResult `json:"rows,omitempty"`
+	Errors  string            `json:"errors,omitempty"`
+}
+
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusUpdated   = "updated"
+	ImportStatusSkipped   = "skipped"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusInvalid   = "invalid"
+	ImportStatusFailed    = "failed"
+)
@@ -0,0 +1,320 @@
+// Package ratelimit throttles API clients.
+//
+// Each client (an API key, JWT subject or IP address) gets a token bucket per
+// route group, refilled at a fixed rate, and a daily request quota counted in
+// the quota_usage table so it survives restarts and is shared between server
+// instances. Buckets are kept in memory and therefore apply per instance.
+// Before authentication every IP address also gets a bucket of its own, so
+// that requests with bad credentials are throttled too.
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+// DefaultRoute is the route group whose limit applies to groups without their own.
+const DefaultRoute = "default"
+
+// IPRoute is the group of the per-IP limit applied before authentication. It
+// has no default: without an IPRoute limit, AllowIP allows every request.
+const IPRoute = "ip"
+
+// Quota usage is counted in memory and added to the quota store in batches:
+// once a client has quotaFlushEvery uncounted requests, or on its first
+// request quotaFlushInterval after the last batch. A client can therefore go
+// over its quota by up to quotaFlushEvery requests per server instance.
+const (
+	quotaFlushEvery    = 20
+	quotaFlushInterval = time.Second
+)
+
+// Limit allows Burst requests at once, refilled at Rate requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimits parses a comma-separated list of route=rate:burst entries, for
+// example "iin_check=5:20,default=20:100".
+func ParseLimits(spec string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(entry, "=")
+		rateStr, burstStr, ok2 := strings.Cut(value, ":")
+		if !ok || !ok2 || route == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected route=rate:burst", entry)
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit %q", entry)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid burst in rate limit %q", entry)
+		}
+		limits[route] = Limit{Rate: rate, Burst: burst}
+	}
+	return limits, nil
+}
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed bool
+	// Limit and Remaining describe the token bucket of the route group.
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is set when the request was rejected.
+	RetryAfter time.Duration
+	// QuotaExceeded is set when the daily quota, not the bucket, rejected the request.
+	QuotaExceeded bool
+	DailyQuota    int
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// quotaUsage is a client's request count for one UTC day.
+type quotaUsage struct {
+	day time.Time
+	// stored is the count in the quota store after the last batch, which
+	// includes requests to other instances.
+	stored int
+	// inflight requests are being added to the store, pending ones are not yet.
+	inflight int
+	pending  int
+	flushed  time.Time
+}
+
+func (u *quotaUsage) used() int {
+	return u.stored + u.inflight + u.pending
+}
+
+// quotaBatch is a number of requests to add to the quota store.
+type quotaBatch struct {
+	client   string
+	day      time.Time
+	requests int
+}
+
+type Limiter struct {
+	limits     map[string]Limit
+	quotas     repository.QuotaStore
+	dailyQuota int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	usage     map[string]*quotaUsage
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter creates a Limiter. Route groups without a limit use
+// limits[DefaultRoute], or are not throttled if there is none. A dailyQuota
+// of zero disables quotas, in which case quotas may be nil.
+func NewLimiter(limits map[string]Limit, quotas repository.QuotaStore, dailyQuota int) *Limiter {
+	return &Limiter{
+		limits:     limits,
+		quotas:     quotas,
+		dailyQuota: dailyQuota,
+		buckets:    map[string]*bucket{},
+		usage:      map[string]*quotaUsage{},
+		now:        time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of client for route and, if that
+// succeeds, counts the request against the client's daily quota. Quota
+// storage errors are logged and the request is allowed.
+func (l *Limiter) Allow(client, route string) Decision {
+	limit, ok := l.limits[route]
+	if !ok {
+		limit, ok = l.limits[DefaultRoute]
+	}
+	decision := Decision{Allowed: true}
+	if ok {
+		decision = l.take(route+"|"+client, limit)
+	}
+	if !decision.Allowed || l.dailyQuota <= 0 {
+		return decision
+	}
+
+	now := l.now().UTC()
+	used := l.count(client, now)
+	if used > l.dailyQuota {
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		decision.Allowed = false
+		decision.QuotaExceeded = true
+		decision.DailyQuota = l.dailyQuota
+		decision.RetryAfter = midnight.Sub(now)
+	}
+	return decision
+}
+
+// AllowIP takes a token from the IPRoute bucket of a client IP address. It
+// does not count against any quota.
+func (l *Limiter) AllowIP(ip string) Decision {
+	limit, ok := l.limits[IPRoute]
+	if !ok {
+		return Decision{Allowed: true}
+	}
+	return l.take(IPRoute+"|ip:"+ip, limit)
+}
+
+// Flush adds all uncounted requests to the quota store, for example before
+// the server exits.
+func (l *Limiter) Flush() {
+	var batches []quotaBatch
+	l.mu.Lock()
+	for client, u := range l.usage {
+		if u.pending > 0 && u.inflight == 0 {
+			batches = append(batches, l.startBatch(client, u, l.now()))
+		}
+	}
+	l.mu.Unlock()
+
+	for _, batch := range batches {
+		l.flush(batch)
+	}
+}
+
+func (l *Limiter) take(key string, limit Limit) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	decision := Decision{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return decision
+}
+
+// count counts a request of client at now and returns the client's usage
+// for the day, adding a batch to the quota store first if one is due.
+func (l *Limiter) count(client string, now time.Time) int {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var batches []quotaBatch
+
+	l.mu.Lock()
+	l.sweep(now)
+	u := l.usage[client]
+	if u != nil && !u.day.Equal(day) {
+		// The previous day's uncounted requests are still stored; a batch
+		// in flight for it finishes without touching the new day.
+		if u.pending > 0 {
+			batches = append(batches, quotaBatch{client: client, day: u.day, requests: u.pending})
+		}
+		u = nil
+	}
+	if u == nil {
+		u = &quotaUsage{day: day}
+		l.usage[client] = u
+	}
+	u.pending++
+	if u.inflight == 0 && (u.pending >= quotaFlushEvery || now.Sub(u.flushed) >= quotaFlushInterval) {
+		batches = append(batches, l.startBatch(client, u, now))
+	}
+	l.mu.Unlock()
+
+	for _, batch := range batches {
+		l.flush(batch)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return u.used()
+}
+
+// startBatch moves the pending requests of u into a batch. The caller holds l.mu.
+func (l *Limiter) startBatch(client string, u *quotaUsage, now time.Time) quotaBatch {
+	batch := quotaBatch{client: client, day: u.day, requests: u.pending}
+	u.inflight, u.pending = u.pending, 0
+	u.flushed = now
+	return batch
+}
+
+// flush adds a batch to the quota store and records the stored total. If
+// that fails, the requests stay pending and are retried with the next batch.
+func (l *Limiter) flush(batch quotaBatch) {
+	total, err := l.quotas.Add(batch.client, batch.day, batch.requests)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.usage[batch.client]
+	if u == nil || !u.day.Equal(batch.day) || u.inflight != batch.requests {
+		return
+	}
+	u.inflight = 0
+	if err != nil {
+		u.pending += batch.requests
+		return
+	}
+	u.stored = total
+}
+
+// sweep drops buckets that have refilled completely, which behave exactly like
+// new ones, and fully stored quota usage of past days, so memory does not
+// grow with every client ever seen.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	for client, u := range l.usage {
+		if u.day.Before(today) && u.pending == 0 && u.inflight == 0 {
+			delete(l.usage, client)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
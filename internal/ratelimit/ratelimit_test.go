@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(map[string]Limit{"iin_check": {Rate: 2, Burst: 3}}, nil, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if d := limiter.Allow("ip:10.0.0.1", "iin_check"); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("request %d: got %+v", i+1, d)
+		}
+	}
+
+	d := limiter.Allow("ip:10.0.0.1", "iin_check")
+	if d.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	if d.RetryAfter != 500*time.Millisecond || d.Reset != 1500*time.Millisecond {
+		t.Errorf("RetryAfter = %s, Reset = %s, want 500ms and 1.5s", d.RetryAfter, d.Reset)
+	}
+
+	if d := limiter.Allow("ip:10.0.0.2", "iin_check"); !d.Allowed {
+		t.Error("another client was limited")
+	}
+	if d := limiter.Allow("ip:10.0.0.1", "people"); !d.Allowed || d.Limit != 0 {
+		t.Errorf("route without a limit: got %+v", d)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d := limiter.Allow("ip:10.0.0.1", "iin_check"); !d.Allowed {
+		t.Error("request after refill was rejected")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("iin_check=5:20, default=0.5:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["iin_check"] != (Limit{Rate: 5, Burst: 20}) || limits[DefaultRoute] != (Limit{Rate: 0.5, Burst: 10}) {
+		t.Errorf("ParseLimits = %+v", limits)
+	}
+
+	for _, spec := range []string{"iin_check=5", "=5:20", "iin_check=0:20", "iin_check=5:0", "iin_check=x:1"} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Errorf("ParseLimits(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestIPLimit(t *testing.T) {
+	limiter := NewLimiter(map[string]Limit{IPRoute: {Rate: 1, Burst: 1}, DefaultRoute: {Rate: 1, Burst: 1}}, nil, 0)
+
+	if d := limiter.AllowIP("10.0.0.1"); !d.Allowed {
+		t.Fatalf("first request: got %+v", d)
+	}
+	if d := limiter.AllowIP("10.0.0.1"); d.Allowed {
+		t.Error("request over the IP burst was allowed")
+	}
+	// The IP bucket is separate from the route buckets of the same address.
+	if d := limiter.Allow("ip:10.0.0.1", "people"); !d.Allowed {
+		t.Errorf("route bucket was taken by the IP limit: %+v", d)
+	}
+
+	// DefaultRoute does not apply to the IP limit.
+	limiter = NewLimiter(map[string]Limit{DefaultRoute: {Rate: 1, Burst: 1}}, nil, 0)
+	for i := 0; i < 3; i++ {
+		if d := limiter.AllowIP("10.0.0.1"); !d.Allowed || d.Limit != 0 {
+			t.Fatalf("request %d without an IP limit: got %+v", i+1, d)
+		}
+	}
+}
+
+// countingQuotas is a quota store that counts its writes.
+type countingQuotas struct {
+	*repository.MemoryQuotaRepository
+	adds int
+}
+
+func (q *countingQuotas) Add(client string, day time.Time, requests int) (int, error) {
+	q.adds++
+	return q.MemoryQuotaRepository.Add(client, day, requests)
+}
+
+func TestQuotaBatches(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	quotas := &countingQuotas{MemoryQuotaRepository: repository.NewMemoryQuotaRepository()}
+	limiter := NewLimiter(nil, quotas, 100)
+	limiter.now = func() time.Time { return now }
+
+	// Another instance has already counted 50 requests today.
+	quotas.MemoryQuotaRepository.Add("api_key:a", now, 50)
+
+	// The first request reads the stored count, then requests are written
+	// every quotaFlushEvery.
+	for i := 1; i <= 1+2*quotaFlushEvery; i++ {
+		if d := limiter.Allow("api_key:a", "people"); !d.Allowed {
+			t.Fatalf("request %d: got %+v", i, d)
+		}
+	}
+	if quotas.adds != 3 {
+		t.Errorf("quota store written %d times, want 3", quotas.adds)
+	}
+
+	// Within quotaFlushInterval nothing is written until the batch is full.
+	limiter.Allow("api_key:a", "people")
+	if quotas.adds != 3 {
+		t.Errorf("quota store written %d times, want 3", quotas.adds)
+	}
+	now = now.Add(quotaFlushInterval)
+	limiter.Allow("api_key:a", "people")
+	if quotas.adds != 4 {
+		t.Errorf("quota store written %d times after quotaFlushInterval, want 4", quotas.adds)
+	}
+
+	// 50 + 43 requests so far; the quota of 100 runs out after 7 more.
+	for i := 0; i < 7; i++ {
+		if d := limiter.Allow("api_key:a", "people"); !d.Allowed {
+			t.Fatalf("request %d under the quota: got %+v", 94+i, d)
+		}
+	}
+	d := limiter.Allow("api_key:a", "people")
+	if d.Allowed || !d.QuotaExceeded || d.RetryAfter != 12*time.Hour-quotaFlushInterval {
+		t.Errorf("request over the quota: got %+v", d)
+	}
+
+	limiter.Flush()
+	if used, _ := quotas.MemoryQuotaRepository.Add("api_key:a", now, 0); used != 101 {
+		t.Errorf("stored usage after Flush = %d, want 101", used)
+	}
+
+	// A new day starts from the stored count of that day.
+	now = now.Add(24 * time.Hour)
+	if d := limiter.Allow("api_key:a", "people"); !d.Allowed {
+		t.Errorf("first request of the next day: got %+v", d)
+	}
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+const apiKeyColumns = `id, name, key_hash, roles, created_at, revoked_at`
+
+type APIKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(name, keyHash string, roles []string) (*model.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys.create", time.Now())
+
+	var key model.APIKey
+	query := `INSERT INTO api_keys (name, key_hash, roles) VALUES ($1, $2, $3) RETURNING ` + apiKeyColumns
+	err := r.db.Get(&key, query, name, keyHash, pq.StringArray(roles))
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("an API key with this name already exists")
+		}
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &key, nil
+}
+
+// GetActiveByHash returns the API key with the given hash unless it has been revoked.
+func (r *APIKeyRepository) GetActiveByHash(keyHash string) (*model.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys.get_active_by_hash", time.Now())
+
+	var key model.APIKey
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	err := r.db.Get(&key, query, keyHash)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, errors.New("API key not found")
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) List() ([]model.APIKey, error) {
+	defer metrics.ObserveQuery("api_keys.list", time.Now())
+
+	keys := []model.APIKey{}
+	err := r.db.Select(&keys, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Revoke(name string) error {
+	defer metrics.ObserveQuery("api_keys.revoke", time.Now())
+
+	result, err := r.db.Exec(`UPDATE api_keys SET revoked_at = now() WHERE name = $1 AND revoked_at IS NULL`, name)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if affected == 0 {
+		return errors.New("API key not found")
+	}
+	return nil
+}
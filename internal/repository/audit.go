@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// auditLockID serializes appends to audit_log so every event sees the hash of
+// the one before it, also across server instances. audit.Logger appends in
+// batches from a single goroutine, so the lock is not taken per request.
+const auditLockID = 7_246_001
+
+// auditGenesisHash is the prev_hash of the first event in the chain.
+var auditGenesisHash = strings.Repeat("0", 64)
+
+const auditColumns = `id, occurred_at, actor, action, iin_hash, iin_hashes, request_id, client_ip, details, prev_hash, hash`
+
+// auditHead is the last event of the chain, kept in audit_log_head next to the
+// log. Deleting events from the end leaves a chain that is valid on its own,
+// so Verify also checks that the chain still ends at the head.
+type auditHead struct {
+	LastID int64  `db:"last_id"`
+	Hash   string `db:"hash"`
+	// IDHashedFrom is the first event whose hash covers its id. Events written
+	// before audit_log_head existed were hashed without it.
+	IDHashedFrom int64 `db:"id_hashed_from"`
+}
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Append adds events to the end of the hash chain and moves the head to the
+// last of them. Each event's hash covers its id, its own fields and the hash
+// of the previous event, so editing or deleting any row breaks every hash
+// after it.
+func (r *AuditRepository) Append(events []model.AuditEvent) error {
+	defer metrics.ObserveQuery("audit_log.append", time.Now())
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockID)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+
+	head := auditHead{Hash: auditGenesisHash}
+	err = tx.Get(&head, `SELECT last_id, hash, id_hashed_from FROM audit_log_head`)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read audit log head: %w", err)
+	}
+
+	// The id is part of the hash, so take the ids before inserting.
+	var ids []int64
+	err = tx.Select(&ids, `SELECT nextval(pg_get_serial_sequence('audit_log', 'id')) FROM generate_series(1, $1)`, len(events))
+	if err != nil {
+		return fmt.Errorf("failed to allocate audit event ids: %w", err)
+	}
+	slices.Sort(ids)
+
+	prevHash := head.Hash
+	query := `INSERT INTO audit_log (id, occurred_at, actor, action, iin_hash, iin_hashes, request_id, client_ip, details, prev_hash, hash)
+		VALUES (:id, :occurred_at, :actor, :action, :iin_hash, :iin_hashes, :request_id, :client_ip, :details, :prev_hash, :hash)`
+	for i := range events {
+		event := &events[i]
+		if event.IINHashes == nil {
+			event.IINHashes = pq.StringArray{}
+		}
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now()
+		}
+		// Postgres keeps microseconds, so hash exactly what will be read back.
+		event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+		event.ID = ids[i]
+		event.PrevHash = prevHash
+		event.Hash = auditHash(*event, head.IDHashedFrom)
+
+		_, err = tx.NamedExec(query, event)
+		if err != nil {
+			return fmt.Errorf("failed to append audit event: %w", err)
+		}
+		prevHash = event.Hash
+	}
+
+	_, err = tx.Exec(`INSERT INTO audit_log_head (singleton, last_id, hash, id_hashed_from) VALUES (TRUE, $1, $2, $3)
+		ON CONFLICT (singleton) DO UPDATE SET last_id = EXCLUDED.last_id, hash = EXCLUDED.hash`,
+		events[len(events)-1].ID, prevHash, head.IDHashedFrom)
+	if err != nil {
+		return fmt.Errorf("failed to move audit log head: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit audit events: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) Find(filter model.AuditFilter) ([]model.AuditEvent, error) {
+	defer metrics.ObserveQuery("audit_log.find", time.Now())
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.IINHash != "" {
+		addCondition("(iin_hash = $%[1]d OR iin_hashes @> ARRAY[$%[1]d]::text[])", filter.IINHash)
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		addCondition("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("occurred_at < $%d", filter.To)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY id LIMIT $%d`, len(args))
+
+	events := []model.AuditEvent{}
+	err := r.db.Select(&events, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit events: %w", err)
+	}
+	return events, nil
+}
+
+// Verify walks the whole chain in id order and reports the first event whose
+// stored hashes do not match its contents or its predecessor, or a chain that
+// does not end at the head. Head and events are read from one snapshot, so
+// concurrent appends do not look like a broken chain.
+func (r *AuditRepository) Verify() (*model.AuditVerification, error) {
+	defer metrics.ObserveQuery("audit_log.verify", time.Now())
+
+	tx, err := r.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var head *auditHead
+	var stored auditHead
+	err = tx.Get(&stored, `SELECT last_id, hash, id_hashed_from FROM audit_log_head`)
+	switch {
+	case err == nil:
+		head = &stored
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to read audit log head: %w", err)
+	}
+
+	rows, err := tx.Queryx(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer rows.Close()
+
+	chain := newAuditChain(head)
+	for rows.Next() {
+		var event model.AuditEvent
+		if err := rows.StructScan(&event); err != nil {
+			return nil, fmt.Errorf("failed to read audit event: %w", err)
+		}
+		if !chain.add(event) {
+			return chain.result(), nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return chain.end(), nil
+}
+
+// auditChain checks a chain one event at a time, in id order.
+type auditChain struct {
+	head         *auditHead
+	idHashedFrom int64
+	prevHash     string
+	lastID       int64
+	verification model.AuditVerification
+}
+
+// newAuditChain starts checking a chain whose head is head, or that has no
+// head because nothing was ever appended.
+func newAuditChain(head *auditHead) *auditChain {
+	c := &auditChain{head: head, prevHash: auditGenesisHash, verification: model.AuditVerification{Valid: true}}
+	if head != nil {
+		c.idHashedFrom = head.IDHashedFrom
+	}
+	return c
+}
+
+// add checks the next event and reports whether the chain is still intact.
+func (c *auditChain) add(event model.AuditEvent) bool {
+	c.verification.Checked++
+	switch {
+	case event.PrevHash != c.prevHash:
+		c.fail(event.ID, "prev_hash does not match the previous event")
+		return false
+	case event.Hash != auditHash(event, c.idHashedFrom):
+		c.fail(event.ID, "hash does not match the event contents")
+		return false
+	}
+	c.prevHash = event.Hash
+	c.lastID = event.ID
+	return true
+}
+
+// end checks that the chain ends at the head and returns the result.
+func (c *auditChain) end() *model.AuditVerification {
+	switch {
+	case c.head == nil && c.lastID != 0:
+		c.fail(c.lastID, "audit log head is missing")
+	case c.head != nil && (c.lastID != c.head.LastID || c.prevHash != c.head.Hash):
+		c.fail(c.head.LastID, fmt.Sprintf("the log ends at event %d, but its head is event %d", c.lastID, c.head.LastID))
+	}
+	return c.result()
+}
+
+func (c *auditChain) fail(id int64, reason string) {
+	c.verification.Valid = false
+	c.verification.BrokenID = id
+	c.verification.Errors = reason
+}
+
+func (c *auditChain) result() *model.AuditVerification {
+	return &c.verification
+}
+
+// auditHash hashes an event. Events from idHashedFrom on include their id.
+func auditHash(event model.AuditEvent, idHashedFrom int64) string {
+	hash := sha256.New()
+	for _, field := range []string{
+		event.PrevHash,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.Actor,
+		event.Action,
+		event.IINHash,
+		event.RequestID,
+		event.ClientIP,
+		event.Details,
+	} {
+		fmt.Fprintf(hash, "%d:%s|", len(field), field)
+	}
+	// Only hashed when present, so events written before iin_hashes existed
+	// still verify.
+	if len(event.IINHashes) > 0 {
+		joined := strings.Join(event.IINHashes, ",")
+		fmt.Fprintf(hash, "%d:%s|", len(joined), joined)
+	}
+	if event.ID >= idHashedFrom {
+		fmt.Fprintf(hash, "id:%d|", event.ID)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
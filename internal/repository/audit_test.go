@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+func TestAuditVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(r *MemoryAuditRepository)
+		brokenID int64
+		errors   string
+	}{
+		{"untouched", func(r *MemoryAuditRepository) {}, 0, ""},
+		{"edited", func(r *MemoryAuditRepository) { r.events[1].Actor = "someone" }, 2, "hash does not match the event contents"},
+		{"renumbered", func(r *MemoryAuditRepository) { r.events[2].ID = 4; r.head.LastID = 4 }, 4, "hash does not match the event contents"},
+		{"deleted in the middle", func(r *MemoryAuditRepository) { r.events = append(r.events[:1], r.events[2:]...) }, 3, "prev_hash does not match the previous event"},
+		{"deleted from the end", func(r *MemoryAuditRepository) { r.events = r.events[:2] }, 3, "the log ends at event 2, but its head is event 3"},
+		{"head deleted", func(r *MemoryAuditRepository) { r.head = nil }, 3, "audit log head is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMemoryAuditRepository()
+			events := []model.AuditEvent{{Actor: "a", Action: "create"}, {Actor: "a", Action: "read"}, {Actor: "b", Action: "read"}}
+			if err := r.Append(events); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(r)
+
+			verification, err := r.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verification.Valid != (tt.errors == "") || verification.BrokenID != tt.brokenID || verification.Errors != tt.errors {
+				t.Errorf("verification = %+v, want broken at %d with %q", verification, tt.brokenID, tt.errors)
+			}
+		})
+	}
+}
+
+func TestAuditHashOfEventsBeforeTheHead(t *testing.T) {
+	event := model.AuditEvent{ID: 7, Actor: "a", Action: "read", PrevHash: auditGenesisHash}
+	// Events written before audit_log_head existed were hashed without their id.
+	if auditHash(event, 8) == auditHash(event, 7) {
+		t.Error("the hash of an event after id_hashed_from does not cover its id")
+	}
+	renumbered := event
+	renumbered.ID = 3
+	if auditHash(event, 8) != auditHash(renumbered, 8) {
+		t.Error("the hash of an event before id_hashed_from depends on its id")
+	}
+}
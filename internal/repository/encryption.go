@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// Field names bound to the ciphertexts as additional data.
+const (
+	fieldIIN   = "people.iin"
+	fieldPhone = "people.phone"
+)
+
+const personColumns = `name, iin, phone, iin_enc, phone_enc, key_id`
+
+// personRow is a people row as stored: IIN and phone are encrypted, except in
+// rows written before encryption was enabled, which still hold plaintext.
+type personRow struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"name"`
+	IIN      sql.NullString `db:"iin"`
+	Phone    sql.NullString `db:"phone"`
+	IINEnc   []byte         `db:"iin_enc"`
+	PhoneEnc []byte         `db:"phone_enc"`
+	KeyID    sql.NullString `db:"key_id"`
+}
+
+type encryptedPerson struct {
+	IINHMAC  []byte
+	IINEnc   []byte
+	PhoneEnc []byte
+	KeyID    string
+}
+
+func (r *PersonRepository) encrypt(person model.Person) (encryptedPerson, error) {
+	iinEnc, err := r.keyring.Encrypt(fieldIIN, person.IIN)
+	if err != nil {
+		return encryptedPerson{}, fmt.Errorf("failed to encrypt IIN: %w", err)
+	}
+	phoneEnc, err := r.keyring.Encrypt(fieldPhone, person.Phone)
+	if err != nil {
+		return encryptedPerson{}, fmt.Errorf("failed to encrypt phone: %w", err)
+	}
+
+	return encryptedPerson{
+		IINHMAC:  r.keyring.LookupHash(person.IIN),
+		IINEnc:   iinEnc,
+		PhoneEnc: phoneEnc,
+		KeyID:    r.keyring.ActiveKeyID(),
+	}, nil
+}
+
+func (r *PersonRepository) decrypt(row personRow) (model.Person, error) {
+	person := model.Person{Name: row.Name, IIN: row.IIN.String, Phone: row.Phone.String}
+	if row.IINEnc == nil {
+		return person, nil
+	}
+
+	var err error
+	person.IIN, err = r.keyring.Decrypt(row.KeyID.String, fieldIIN, row.IINEnc)
+	if err != nil {
+		return model.Person{}, err
+	}
+	person.Phone, err = r.keyring.Decrypt(row.KeyID.String, fieldPhone, row.PhoneEnc)
+	if err != nil {
+		return model.Person{}, err
+	}
+	return person, nil
+}
+
+// CountUnencrypted returns the number of rows that still hold a plaintext IIN.
+func (r *PersonRepository) CountUnencrypted() (int, error) {
+	defer metrics.ObserveQuery("people.count_unencrypted", time.Now())
+
+	var count int
+	err := r.db.Get(&count, `SELECT count(*) FROM people WHERE iin_enc IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unencrypted people: %w", err)
+	}
+	return count, nil
+}
+
+// Reencrypt re-encrypts up to limit rows with id greater than afterID using
+// the active key, also encrypting legacy plaintext rows and recomputing the
+// IIN hash. Unless all is set, rows already on the active key are skipped.
+// It returns the number of rows processed and the last id seen, which is the
+// afterID of the next batch; zero rows means there is nothing left.
+func (r *PersonRepository) Reencrypt(ctx context.Context, afterID int64, limit int, all bool) (int, int64, error) {
+	defer metrics.ObserveQuery("people.reencrypt", time.Now())
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to begin re-encryption transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []personRow
+	query := `SELECT id, ` + personColumns + ` FROM people
+		WHERE id > $1 AND ($2 OR key_id IS DISTINCT FROM $3)
+		ORDER BY id LIMIT $4 FOR UPDATE`
+	err = tx.SelectContext(ctx, &rows, query, afterID, all, r.keyring.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to read people for re-encryption: %w", err)
+	}
+
+	update := `UPDATE people SET iin = NULL, phone = NULL, iin_hmac = $2, iin_enc = $3, phone_enc = $4, key_id = $5
+		WHERE id = $1`
+	for _, row := range rows {
+		person, err := r.decrypt(row)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("person %d: %w", row.ID, err)
+		}
+		encrypted, err := r.encrypt(person)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("person %d: %w", row.ID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, update, row.ID, encrypted.IINHMAC, encrypted.IINEnc, encrypted.PhoneEnc, encrypted.KeyID)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("failed to re-encrypt person %d: %w", row.ID, err)
+		}
+		afterID = row.ID
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to commit re-encryption: %w", err)
+	}
+	return len(rows), afterID, nil
+}
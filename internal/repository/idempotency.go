@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+type IdempotencyRepository struct {
+	db  *sqlx.DB
+	ttl time.Duration
+}
+
+func NewIdempotencyRepository(db *sqlx.DB, ttl time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, ttl: ttl}
+}
+
+// Reserve claims the client's key for a new request. If the client already
+// uses the key and it has not expired, it returns the stored record and false
+// instead. Keys of different clients never conflict.
+func (r *IdempotencyRepository) Reserve(client, key, requestHash string) (*model.IdempotencyRecord, bool, error) {
+	defer metrics.ObserveQuery("idempotency_keys.reserve", time.Now())
+
+	query := `INSERT INTO idempotency_keys (client, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (client, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_body = NULL,
+				created_at = now(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < now()`
+	result, err := r.db.Exec(query, client, key, requestHash, r.ttl.Seconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if affected > 0 {
+		return nil, true, nil
+	}
+
+	var record model.IdempotencyRecord
+	query = `SELECT client, key, request_hash, status_code, response_body FROM idempotency_keys WHERE client = $1 AND key = $2`
+	err = r.db.Get(&record, query, client, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &record, false, nil
+}
+
+// Save stores the response of the request that reserved the key.
+func (r *IdempotencyRepository) Save(client, key string, statusCode int, body []byte) error {
+	defer metrics.ObserveQuery("idempotency_keys.save", time.Now())
+
+	query := `UPDATE idempotency_keys SET status_code = $3, response_body = $4 WHERE client = $1 AND key = $2`
+	_, err := r.db.Exec(query, client, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees a reserved key so the request can be retried, used when the
+// original request failed on the server side.
+func (r *IdempotencyRepository) Release(client, key string) error {
+	defer metrics.ObserveQuery("idempotency_keys.release", time.Now())
+
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE client = $1 AND key = $2 AND status_code IS NULL`, client, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes keys whose TTL has passed.
+func (r *IdempotencyRepository) PurgeExpired() (int64, error) {
+	defer metrics.ObserveQuery("idempotency_keys.purge_expired", time.Now())
+
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at < now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+const jobColumns = `id, type, status, params, processed, total, result, error, created_by, created_at, started_at, finished_at`
+
+type JobRepository struct {
+	db *sqlx.DB
+}
+
+func NewJobRepository(db *sqlx.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) Create(jobType, createdBy string, params model.JobParams, input []byte) (*model.Job, error) {
+	defer metrics.ObserveQuery("jobs.create", time.Now())
+
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job params: %w", err)
+	}
+
+	var job model.Job
+	query := `INSERT INTO jobs (type, created_by, params, input) VALUES ($1, $2, $3, $4) RETURNING ` + jobColumns
+	err = r.db.Get(&job, query, jobType, createdBy, encodedParams, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) GetByID(id int64) (*model.Job, error) {
+	defer metrics.ObserveQuery("jobs.get_by_id", time.Now())
+
+	var job model.Job
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+	err := r.db.Get(&job, query, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, errors.New("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetResult returns the downloadable result of a finished job.
+func (r *JobRepository) GetResult(id int64) (*model.JobResult, error) {
+	defer metrics.ObserveQuery("jobs.get_result", time.Now())
+
+	var row struct {
+		Data        []byte  `db:"result_data"`
+		ContentType *string `db:"result_content_type"`
+	}
+	query := `SELECT result_data, result_content_type FROM jobs WHERE id = $1`
+	err := r.db.Get(&row, query, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, errors.New("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job result: %w", err)
+	}
+	if row.ContentType == nil {
+		return nil, errors.New("job result not available")
+	}
+	return &model.JobResult{Data: row.Data, ContentType: *row.ContentType}, nil
+}
+
+// GetInput returns the payload the job was submitted with.
+func (r *JobRepository) GetInput(id int64) ([]byte, error) {
+	defer metrics.ObserveQuery("jobs.get_input", time.Now())
+
+	var input []byte
+	err := r.db.Get(&input, `SELECT input FROM jobs WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job input: %w", err)
+	}
+	return input, nil
+}
+
+// Claim atomically takes the oldest queued job, or a running job whose worker
+// stopped sending heartbeats for longer than staleAfter, and marks it running.
+// It returns nil when there is nothing to do.
+func (r *JobRepository) Claim(ctx context.Context, staleAfter time.Duration) (*model.Job, error) {
+	defer metrics.ObserveQuery("jobs.claim", time.Now())
+
+	var job model.Job
+	query := `UPDATE jobs SET status = 'running', started_at = now(), heartbeat_at = now(), processed = 0
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'queued'
+			   OR (status = 'running' AND heartbeat_at < now() - make_interval(secs => $1))
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + jobColumns
+	err := r.db.GetContext(ctx, &job, query, staleAfter.Seconds())
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return &job, nil
+}
+
+// Heartbeat records progress of a running job. It returns false when the job
+// is no longer running, for example because it was cancelled.
+func (r *JobRepository) Heartbeat(id int64, processed, total int) (bool, error) {
+	defer metrics.ObserveQuery("jobs.heartbeat", time.Now())
+
+	query := `UPDATE jobs SET heartbeat_at = now(), processed = $2, total = $3 WHERE id = $1 AND status = 'running'`
+	result, err := r.db.Exec(query, id, processed, total)
+	if err != nil {
+		return false, fmt.Errorf("failed to update job progress: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update job progress: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Complete marks a running job as succeeded and stores its result. It
+// returns false, storing nothing, if the job is no longer running, so a job
+// cancelled while it ran stays cancelled.
+func (r *JobRepository) Complete(id int64, processed, total int, summary interface{}, result *model.JobResult) (bool, error) {
+	defer metrics.ObserveQuery("jobs.complete", time.Now())
+
+	encodedSummary, err := json.Marshal(summary)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode job result: %w", err)
+	}
+
+	query := `UPDATE jobs SET status = 'succeeded', processed = $2, total = $3, result = $4,
+		result_data = $5, result_content_type = $6, finished_at = now()
+		WHERE id = $1 AND status = 'running'`
+	updated, err := r.updateRunning(query, id, processed, total, encodedSummary, result.Data, result.ContentType)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete job: %w", err)
+	}
+	return updated, nil
+}
+
+// Fail marks a running job as failed and returns false if it is no longer running.
+func (r *JobRepository) Fail(id int64, reason string) (bool, error) {
+	defer metrics.ObserveQuery("jobs.fail", time.Now())
+
+	query := `UPDATE jobs SET status = 'failed', error = $2, finished_at = now() WHERE id = $1 AND status = 'running'`
+	updated, err := r.updateRunning(query, id, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark job as failed: %w", err)
+	}
+	return updated, nil
+}
+
+// Requeue puts a running job back in the queue, used when the server shuts
+// down before the job finishes. It returns false if the job is no longer running.
+func (r *JobRepository) Requeue(id int64) (bool, error) {
+	defer metrics.ObserveQuery("jobs.requeue", time.Now())
+
+	query := `UPDATE jobs SET status = 'queued', processed = 0, started_at = NULL, heartbeat_at = NULL
+		WHERE id = $1 AND status = 'running'`
+	updated, err := r.updateRunning(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue job: %w", err)
+	}
+	return updated, nil
+}
+
+// updateRunning runs a status transition that only applies to a running job
+// and reports whether it did.
+func (r *JobRepository) updateRunning(query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Cancel marks a queued or running job as cancelled and returns false if the
+// job has already finished.
+func (r *JobRepository) Cancel(id int64) (bool, error) {
+	defer metrics.ObserveQuery("jobs.cancel", time.Now())
+
+	query := `UPDATE jobs SET status = 'cancelled', finished_at = now()
+		WHERE id = $1 AND status IN ('queued', 'running')`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,595 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// MemoryPersonRepository keeps people in memory, in insertion order like the
+// ids of the people table. It is meant for tests and local runs: nothing is
+// encrypted or persisted.
+type MemoryPersonRepository struct {
+	mu     sync.Mutex
+	people []model.Person
+	byIIN  map[string]int
+}
+
+func NewMemoryPersonRepository() *MemoryPersonRepository {
+	return &MemoryPersonRepository{byIIN: map[string]int{}}
+}
+
+func (r *MemoryPersonRepository) Create(person *model.Person) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byIIN[person.IIN]; ok {
+		return ErrDuplicateIIN
+	}
+	r.byIIN[person.IIN] = len(r.people)
+	r.people = append(r.people, *person)
+	return nil
+}
+
+func (r *MemoryPersonRepository) GetByIIN(iin string) (*model.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.byIIN[iin]
+	if !ok {
+		return nil, errors.New("person not found")
+	}
+	person := r.people[i]
+	return &person, nil
+}
+
+func (r *MemoryPersonRepository) FindByNamePart(namePart string) ([]model.Person, error) {
+	return r.matching(namePart), nil
+}
+
+// matching returns the people whose name contains namePart, ignoring case,
+// as ILIKE '%namePart%' does.
+func (r *MemoryPersonRepository) matching(namePart string) []model.Person {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	namePart = strings.ToLower(namePart)
+	people := []model.Person{}
+	for _, person := range r.people {
+		if strings.Contains(strings.ToLower(person.Name), namePart) {
+			people = append(people, person)
+		}
+	}
+	return people
+}
+
+func (r *MemoryPersonRepository) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(model.Person) error) error {
+	for _, person := range r.matching(filter.NamePart) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportPeople applies all people or none, like the transaction of
+// PersonRepository.ImportPeople, and reports progress per importBatchSize.
+func (r *MemoryPersonRepository) ImportPeople(ctx context.Context, people []model.Person, mode ImportMode, progress func(done int)) (map[string]string, error) {
+	statuses := make(map[string]string, len(people))
+	if len(people) == 0 {
+		return statuses, nil
+	}
+
+	r.mu.Lock()
+	if mode == ImportModeFail {
+		for _, person := range people {
+			if _, ok := r.byIIN[person.IIN]; ok {
+				statuses[person.IIN] = model.ImportStatusDuplicate
+			}
+		}
+		if len(statuses) > 0 {
+			r.mu.Unlock()
+			return statuses, ErrDuplicateIIN
+		}
+	}
+
+	// Work on copies so that a failure leaves the repository untouched.
+	updated := append([]model.Person(nil), r.people...)
+	byIIN := make(map[string]int, len(r.byIIN)+len(people))
+	for iin, i := range r.byIIN {
+		byIIN[iin] = i
+	}
+	for _, person := range people {
+		i, exists := byIIN[person.IIN]
+		switch {
+		case !exists:
+			byIIN[person.IIN] = len(updated)
+			updated = append(updated, person)
+			statuses[person.IIN] = model.ImportStatusCreated
+		case mode == ImportModeUpsert:
+			updated[i] = person
+			if statuses[person.IIN] != model.ImportStatusCreated {
+				statuses[person.IIN] = model.ImportStatusUpdated
+			}
+		case mode == ImportModeSkip:
+			if _, ok := statuses[person.IIN]; !ok {
+				statuses[person.IIN] = model.ImportStatusSkipped
+			}
+		default:
+			r.mu.Unlock()
+			return nil, ErrDuplicateIIN
+		}
+	}
+	r.people, r.byIIN = updated, byIIN
+	r.mu.Unlock()
+
+	if progress != nil {
+		for done := importBatchSize; done < len(people); done += importBatchSize {
+			progress(done)
+		}
+		progress(len(people))
+	}
+	return statuses, nil
+}
+
+type memoryIdempotencyRecord struct {
+	record    model.IdempotencyRecord
+	expiresAt time.Time
+}
+
+// memoryIdempotencyKey is the primary key of idempotency_keys.
+type memoryIdempotencyKey struct {
+	client string
+	key    string
+}
+
+type MemoryIdempotencyRepository struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	records map[memoryIdempotencyKey]*memoryIdempotencyRecord
+}
+
+func NewMemoryIdempotencyRepository(ttl time.Duration) *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{ttl: ttl, records: map[memoryIdempotencyKey]*memoryIdempotencyRecord{}}
+}
+
+func (r *MemoryIdempotencyRepository) Reserve(client, key, requestHash string) (*model.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	id := memoryIdempotencyKey{client, key}
+	if existing, ok := r.records[id]; ok && !existing.expiresAt.Before(now) {
+		record := existing.record
+		return &record, false, nil
+	}
+	r.records[id] = &memoryIdempotencyRecord{
+		record:    model.IdempotencyRecord{Client: client, Key: key, RequestHash: requestHash},
+		expiresAt: now.Add(r.ttl),
+	}
+	return nil, true, nil
+}
+
+func (r *MemoryIdempotencyRepository) Save(client, key string, statusCode int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[memoryIdempotencyKey{client, key}]; ok {
+		existing.record.StatusCode = &statusCode
+		existing.record.ResponseBody = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Release(client, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := memoryIdempotencyKey{client, key}
+	if existing, ok := r.records[id]; ok && existing.record.StatusCode == nil {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) PurgeExpired() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	now := time.Now()
+	for key, existing := range r.records {
+		if existing.expiresAt.Before(now) {
+			delete(r.records, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+type memoryJob struct {
+	job         model.Job
+	input       []byte
+	result      *model.JobResult
+	heartbeatAt time.Time
+}
+
+type MemoryJobRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*memoryJob
+}
+
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{nextID: 1, jobs: map[int64]*memoryJob{}}
+}
+
+func (r *MemoryJobRepository) Create(jobType, createdBy string, params model.JobParams, input []byte) (*model.Job, error) {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job params: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := &memoryJob{
+		job: model.Job{
+			ID:        r.nextID,
+			Type:      jobType,
+			Status:    model.JobStatusQueued,
+			Params:    encodedParams,
+			CreatedBy: createdBy,
+			CreatedAt: time.Now(),
+		},
+		input: input,
+	}
+	r.jobs[job.job.ID] = job
+	r.nextID++
+	copied := job.job
+	return &copied, nil
+}
+
+func (r *MemoryJobRepository) GetByID(id int64) (*model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	copied := job.job
+	return &copied, nil
+}
+
+func (r *MemoryJobRepository) GetResult(id int64) (*model.JobResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	if job.result == nil {
+		return nil, errors.New("job result not available")
+	}
+	result := *job.result
+	return &result, nil
+}
+
+func (r *MemoryJobRepository) GetInput(id int64) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, errors.New("failed to get job input: job not found")
+	}
+	return job.input, nil
+}
+
+func (r *MemoryJobRepository) Claim(ctx context.Context, staleAfter time.Duration) (*model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int64, 0, len(r.jobs))
+	for id := range r.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	now := time.Now()
+	for _, id := range ids {
+		job := r.jobs[id]
+		stale := job.job.Status == model.JobStatusRunning && job.heartbeatAt.Before(now.Add(-staleAfter))
+		if job.job.Status != model.JobStatusQueued && !stale {
+			continue
+		}
+		job.job.Status = model.JobStatusRunning
+		job.job.StartedAt = &now
+		job.job.Processed = 0
+		job.heartbeatAt = now
+		copied := job.job
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// running returns the job if it exists and is running.
+func (r *MemoryJobRepository) running(id int64) *memoryJob {
+	job, ok := r.jobs[id]
+	if !ok || job.job.Status != model.JobStatusRunning {
+		return nil
+	}
+	return job
+}
+
+func (r *MemoryJobRepository) Heartbeat(id int64, processed, total int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.running(id)
+	if job == nil {
+		return false, nil
+	}
+	job.heartbeatAt = time.Now()
+	job.job.Processed, job.job.Total = processed, total
+	return true, nil
+}
+
+func (r *MemoryJobRepository) Complete(id int64, processed, total int, summary interface{}, result *model.JobResult) (bool, error) {
+	encodedSummary, err := json.Marshal(summary)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode job result: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.running(id)
+	if job == nil {
+		return false, nil
+	}
+	now := time.Now()
+	raw := json.RawMessage(encodedSummary)
+	job.job.Status = model.JobStatusSucceeded
+	job.job.Processed, job.job.Total = processed, total
+	job.job.Result = &raw
+	job.job.FinishedAt = &now
+	stored := *result
+	job.result = &stored
+	return true, nil
+}
+
+func (r *MemoryJobRepository) Fail(id int64, reason string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.running(id)
+	if job == nil {
+		return false, nil
+	}
+	now := time.Now()
+	job.job.Status = model.JobStatusFailed
+	job.job.Error = &reason
+	job.job.FinishedAt = &now
+	return true, nil
+}
+
+func (r *MemoryJobRepository) Requeue(id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job := r.running(id)
+	if job == nil {
+		return false, nil
+	}
+	job.job.Status = model.JobStatusQueued
+	job.job.Processed = 0
+	job.job.StartedAt = nil
+	job.heartbeatAt = time.Time{}
+	return true, nil
+}
+
+func (r *MemoryJobRepository) Cancel(id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok || (job.job.Status != model.JobStatusQueued && job.job.Status != model.JobStatusRunning) {
+		return false, nil
+	}
+	now := time.Now()
+	job.job.Status = model.JobStatusCancelled
+	job.job.FinishedAt = &now
+	return true, nil
+}
+
+// MemoryAuditRepository keeps the same hash chain and head as
+// AuditRepository, so Verify works the same.
+type MemoryAuditRepository struct {
+	mu     sync.Mutex
+	events []model.AuditEvent
+	head   *auditHead
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Append(events []model.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	head := auditHead{Hash: auditGenesisHash}
+	if r.head != nil {
+		head = *r.head
+	}
+
+	prevHash := head.Hash
+	for i := range events {
+		event := &events[i]
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now()
+		}
+		event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+		event.ID = head.LastID + 1
+		event.PrevHash = prevHash
+		event.Hash = auditHash(*event, head.IDHashedFrom)
+		r.events = append(r.events, *event)
+		prevHash = event.Hash
+		head.LastID = event.ID
+	}
+	head.Hash = prevHash
+	r.head = &head
+	return nil
+}
+
+func (r *MemoryAuditRepository) Find(filter model.AuditFilter) ([]model.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []model.AuditEvent{}
+	for _, event := range r.events {
+		if len(events) >= filter.Limit {
+			break
+		}
+		switch {
+		case filter.IINHash != "" && event.IINHash != filter.IINHash && !slices.Contains(event.IINHashes, filter.IINHash),
+			filter.Actor != "" && event.Actor != filter.Actor,
+			filter.Action != "" && event.Action != filter.Action,
+			!filter.From.IsZero() && event.OccurredAt.Before(filter.From),
+			!filter.To.IsZero() && !event.OccurredAt.Before(filter.To):
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *MemoryAuditRepository) Verify() (*model.AuditVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chain := newAuditChain(r.head)
+	for _, event := range r.events {
+		if !chain.add(event) {
+			return chain.result(), nil
+		}
+	}
+	return chain.end(), nil
+}
+
+type MemoryQuotaRepository struct {
+	mu    sync.Mutex
+	usage map[string]map[string]int
+}
+
+func NewMemoryQuotaRepository() *MemoryQuotaRepository {
+	return &MemoryQuotaRepository{usage: map[string]map[string]int{}}
+}
+
+func (r *MemoryQuotaRepository) Add(client string, day time.Time, requests int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := day.Format("2006-01-02")
+	if r.usage[key] == nil {
+		r.usage[key] = map[string]int{}
+	}
+	r.usage[key][client] += requests
+	return r.usage[key][client], nil
+}
+
+func (r *MemoryQuotaRepository) PurgeBefore(day time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	cutoff := day.Format("2006-01-02")
+	for key, clients := range r.usage {
+		if key < cutoff {
+			purged += int64(len(clients))
+			delete(r.usage, key)
+		}
+	}
+	return purged, nil
+}
+
+type MemoryAPIKeyRepository struct {
+	mu   sync.Mutex
+	keys []model.APIKey
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{}
+}
+
+func (r *MemoryAPIKeyRepository) Create(name, keyHash string, roles []string) (*model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.Name == name || key.KeyHash == keyHash {
+			return nil, errors.New("an API key with this name already exists")
+		}
+	}
+	key := model.APIKey{
+		ID:        len(r.keys) + 1,
+		Name:      name,
+		KeyHash:   keyHash,
+		Roles:     pq.StringArray(append([]string(nil), roles...)),
+		CreatedAt: time.Now(),
+	}
+	r.keys = append(r.keys, key)
+	return &key, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetActiveByHash(keyHash string) (*model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil {
+			return &key, nil
+		}
+	}
+	return nil, errors.New("API key not found")
+}
+
+func (r *MemoryAPIKeyRepository) List() ([]model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]model.APIKey{}, r.keys...), nil
+}
+
+func (r *MemoryAPIKeyRepository) Revoke(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.keys {
+		if r.keys[i].Name == name && r.keys[i].RevokedAt == nil {
+			now := time.Now()
+			r.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return errors.New("API key not found")
+}
@@ -1,59 +1,382 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/toleubekov/check-iin-kaz/internal/fieldcrypt"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
 	"github.com/toleubekov/check-iin-kaz/internal/model"
 )
 
 type PersonRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	keyring *fieldcrypt.Keyring
 }
 
-func NewPersonRepository(db *sqlx.DB) *PersonRepository {
-	return &PersonRepository{db: db}
+func NewPersonRepository(db *sqlx.DB, keyring *fieldcrypt.Keyring) *PersonRepository {
+	return &PersonRepository{db: db, keyring: keyring}
 }
 
 func (r *PersonRepository) Create(person *model.Person) error {
-	query := `INSERT INTO people (name, iin, phone) VALUES ($1, $2, $3)`
-	_, err := r.db.Exec(query, person.Name, person.IIN, person.Phone)
+	defer metrics.ObserveQuery("people.create", time.Now())
+
+	encrypted, err := r.encrypt(*person)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin create transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The unique index on iin_hmac does not cover rows written before
+	// encryption, so those are checked by their plaintext IIN.
+	legacy, err := r.lockLegacy(context.Background(), tx, []string{person.IIN})
+	if err != nil {
+		return err
+	}
+	if legacy[person.IIN] {
+		return ErrDuplicateIIN
+	}
+
+	query := `INSERT INTO people (name, iin_hmac, iin_enc, phone_enc, key_id) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(query, person.Name, encrypted.IINHMAC, encrypted.IINEnc, encrypted.PhoneEnc, encrypted.KeyID)
 	if err != nil {
 
 		if strings.Contains(err.Error(), "duplicate key") {
-			return errors.New("a person with this IIN already exists")
+			return ErrDuplicateIIN
 		}
 		return fmt.Errorf("failed to create person: %w", err)
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit create transaction: %w", err)
+	}
 	return nil
 }
 
+// lockLegacy returns which of the IINs are stored in a row written before
+// encryption was enabled, locking those rows until the transaction ends. A row
+// being re-encrypted at the same time is not returned once it has its hash,
+// and the insert then conflicts on iin_hmac instead.
+func (r *PersonRepository) lockLegacy(ctx context.Context, tx *sqlx.Tx, iins []string) (map[string]bool, error) {
+	var found []string
+	query := `SELECT iin FROM people WHERE iin_hmac IS NULL AND iin = ANY($1) FOR UPDATE`
+	err := tx.SelectContext(ctx, &found, query, pq.StringArray(iins))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check unencrypted people: %w", err)
+	}
+
+	legacy := make(map[string]bool, len(found))
+	for _, iin := range found {
+		legacy[iin] = true
+	}
+	return legacy, nil
+}
+
+// GetByIIN looks a person up by the keyed hash of the IIN. Rows written before
+// encryption was enabled are still found by their plaintext IIN until they are
+// re-encrypted.
 func (r *PersonRepository) GetByIIN(iin string) (*model.Person, error) {
-	var person model.Person
-	query := `SELECT name, iin, phone FROM people WHERE iin = $1`
-	err := r.db.Get(&person, query, iin)
+	defer metrics.ObserveQuery("people.get_by_iin", time.Now())
+
+	var row personRow
+	query := `SELECT ` + personColumns + ` FROM people
+		WHERE iin_hmac = $1 OR (iin_hmac IS NULL AND iin = $2)`
+	err := r.db.Get(&row, query, r.keyring.LookupHash(iin), iin)
 	if err != nil {
 		if strings.Contains(err.Error(), "no rows") {
 			return nil, errors.New("person not found")
 		}
 		return nil, fmt.Errorf("failed to get person by IIN: %w", err)
 	}
+
+	person, err := r.decrypt(row)
+	if err != nil {
+		return nil, err
+	}
 	return &person, nil
 }
 
 func (r *PersonRepository) FindByNamePart(namePart string) ([]model.Person, error) {
-	var people []model.Person
-	query := `SELECT name, iin, phone FROM people WHERE name ILIKE $1`
-	err := r.db.Select(&people, query, "%"+namePart+"%")
+	defer metrics.ObserveQuery("people.find_by_name_part", time.Now())
+
+	var rows []personRow
+	query := `SELECT ` + personColumns + ` FROM people WHERE name ILIKE $1`
+	err := r.db.Select(&rows, query, "%"+namePart+"%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to find people by name part: %w", err)
 	}
+
+	people := make([]model.Person, 0, len(rows))
+	for _, row := range rows {
+		person, err := r.decrypt(row)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, person)
+	}
 	return people, nil
 }
 
+const exportFetchSize = 500
+
+// ExportPeople streams people matching the filter through a server-side cursor,
+// fetching exportFetchSize rows at a time, and calls fn for each of them in id order.
+func (r *PersonRepository) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(model.Person) error) error {
+	defer metrics.ObserveQuery("people.export", time.Now())
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DECLARE people_export NO SCROLL CURSOR FOR
+		SELECT ` + personColumns + ` FROM people
+		WHERE $1::text = '' OR name ILIKE '%' || $1::text || '%'
+		ORDER BY id`
+	_, err = tx.ExecContext(ctx, query, filter.NamePart)
+	if err != nil {
+		return fmt.Errorf("failed to open export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM people_export`, exportFetchSize)
+	for {
+		var batch []personRow
+		err = tx.SelectContext(ctx, &batch, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch people for export: %w", err)
+		}
+
+		for _, row := range batch {
+			person, err := r.decrypt(row)
+			if err != nil {
+				return err
+			}
+			if err := fn(person); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+type ImportMode string
+
+const (
+	ImportModeSkip   ImportMode = "skip"
+	ImportModeUpsert ImportMode = "upsert"
+	ImportModeFail   ImportMode = "fail"
+)
+
+const importBatchSize = 500
+
+// ImportPeople inserts people with multi-row INSERTs inside a single transaction
+// and returns the import status of every IIN. In ImportModeFail nothing is
+// committed if any IIN already exists; the existing IINs are reported as duplicates.
+// If progress is not nil it is called with the number of people written after each batch.
+func (r *PersonRepository) ImportPeople(ctx context.Context, people []model.Person, mode ImportMode, progress func(done int)) (map[string]string, error) {
+	defer metrics.ObserveQuery("people.import", time.Now())
+
+	statuses := make(map[string]string, len(people))
+	if len(people) == 0 {
+		return statuses, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Results come back keyed by the IIN hash, so keep a way back to the IIN.
+	iinsByHash := make(map[string]string, len(people))
+	hashes := make([][]byte, len(people))
+	for i, person := range people {
+		hashes[i] = r.keyring.LookupHash(person.IIN)
+		iinsByHash[string(hashes[i])] = person.IIN
+	}
+
+	iins := make([]string, len(people))
+	for i, person := range people {
+		iins[i] = person.IIN
+	}
+	legacy, err := r.lockLegacy(ctx, tx, iins)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == ImportModeFail {
+		var existing [][]byte
+		err = tx.SelectContext(ctx, &existing, `SELECT iin_hmac FROM people WHERE iin_hmac = ANY($1)`, pq.ByteaArray(hashes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing people: %w", err)
+		}
+		if len(existing) > 0 || len(legacy) > 0 {
+			for _, hash := range existing {
+				statuses[iinsByHash[string(hash)]] = model.ImportStatusDuplicate
+			}
+			for iin := range legacy {
+				statuses[iin] = model.ImportStatusDuplicate
+			}
+			return statuses, ErrDuplicateIIN
+		}
+	}
+
+	// Legacy rows are not seen by ON CONFLICT (iin_hmac): they are skipped,
+	// or encrypted in place with the imported values on upsert.
+	pending := people
+	if len(legacy) > 0 {
+		pending = make([]model.Person, 0, len(people))
+		for _, person := range people {
+			if !legacy[person.IIN] {
+				pending = append(pending, person)
+				continue
+			}
+			if mode == ImportModeUpsert {
+				err = r.replaceLegacy(ctx, tx, person)
+				if err != nil {
+					return nil, err
+				}
+				statuses[person.IIN] = model.ImportStatusUpdated
+			}
+		}
+	}
+	done := len(people) - len(pending)
+
+	for start := 0; start < len(pending); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		err = r.insertBatch(ctx, tx, pending[start:end], mode, iinsByHash, statuses)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(done + end)
+		}
+	}
+
+	for _, person := range people {
+		if _, ok := statuses[person.IIN]; !ok {
+			statuses[person.IIN] = model.ImportStatusSkipped
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit import transaction: %w", err)
+	}
+	return statuses, nil
+}
+
+// replaceLegacy overwrites an unencrypted row with the person, encrypted.
+func (r *PersonRepository) replaceLegacy(ctx context.Context, tx *sqlx.Tx, person model.Person) error {
+	encrypted, err := r.encrypt(person)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE people SET name = $2, iin = NULL, phone = NULL, iin_hmac = $3, iin_enc = $4, phone_enc = $5, key_id = $6
+		WHERE iin_hmac IS NULL AND iin = $1`
+	_, err = tx.ExecContext(ctx, query, person.IIN, person.Name, encrypted.IINHMAC, encrypted.IINEnc, encrypted.PhoneEnc, encrypted.KeyID)
+	if err != nil {
+		return fmt.Errorf("failed to import people: %w", err)
+	}
+	return nil
+}
+
+func (r *PersonRepository) insertBatch(ctx context.Context, tx *sqlx.Tx, batch []model.Person, mode ImportMode, iinsByHash, statuses map[string]string) error {
+	const columns = 5
+	placeholders := make([]string, len(batch))
+	args := make([]interface{}, 0, len(batch)*columns)
+	for i, person := range batch {
+		encrypted, err := r.encrypt(person)
+		if err != nil {
+			return err
+		}
+
+		n := i * columns
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+		args = append(args, person.Name, encrypted.IINHMAC, encrypted.IINEnc, encrypted.PhoneEnc, encrypted.KeyID)
+	}
+
+	query := `INSERT INTO people (name, iin_hmac, iin_enc, phone_enc, key_id) VALUES ` + strings.Join(placeholders, ", ")
+	switch mode {
+	case ImportModeSkip:
+		query += ` ON CONFLICT (iin_hmac) DO NOTHING RETURNING iin_hmac, true`
+	case ImportModeUpsert:
+		query += ` ON CONFLICT (iin_hmac) DO UPDATE SET name = EXCLUDED.name, iin_enc = EXCLUDED.iin_enc,
+			phone_enc = EXCLUDED.phone_enc, key_id = EXCLUDED.key_id
+			RETURNING iin_hmac, (xmax = 0)`
+	default:
+		query += ` RETURNING iin_hmac, true`
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return ErrDuplicateIIN
+		}
+		return fmt.Errorf("failed to import people: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash []byte
+		var inserted bool
+		if err := rows.Scan(&hash, &inserted); err != nil {
+			return fmt.Errorf("failed to read import result: %w", err)
+		}
+		iin := iinsByHash[string(hash)]
+		if inserted {
+			statuses[iin] = model.ImportStatusCreated
+		} else {
+			statuses[iin] = model.ImportStatusUpdated
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return ErrDuplicateIIN
+		}
+		return fmt.Errorf("failed to import people: %w", err)
+	}
+	return nil
+}
+
+// ConnectDB calls InitDB up to attempts times, waiting 1s, 2s, 4s and so on
+// (at most 30s) between attempts, so the server can start before Postgres is up.
+func ConnectDB(connectionString string, attempts int) (*sqlx.DB, error) {
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		db, err := InitDB(connectionString)
+		if err == nil || attempt >= attempts {
+			return db, err
+		}
+
+		log.Printf("Database is not available (attempt %d/%d), retrying in %s: %v", attempt, attempts, delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
 func InitDB(connectionString string) (*sqlx.DB, error) {
 	db, err := sqlx.Connect("postgres", connectionString)
 	if err != nil {
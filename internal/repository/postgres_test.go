@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/toleubekov/check-iin-kaz/internal/fieldcrypt"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// newTestPersonRepository connects to the migrated database in
+// TEST_DATABASE_URL and empties the people table.
+func newTestPersonRepository(t *testing.T) *PersonRepository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := ConnectDB(dsn, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("TRUNCATE people RESTART IDENTITY"); err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := fieldcrypt.NewKeyring(map[string][]byte{"test": bytes.Repeat([]byte("k"), 32)}, "test", bytes.Repeat([]byte("h"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPersonRepository(db, keyring)
+}
+
+func TestLegacyRowIsNotDuplicated(t *testing.T) {
+	const legacyIIN = "900101300017"
+	legacy := model.Person{Name: "Legacy", IIN: legacyIIN, Phone: "+77011234567"}
+	imported := model.Person{Name: "Imported", IIN: legacyIIN, Phone: "+77017654321"}
+
+	tests := []struct {
+		name   string
+		write  func(r *PersonRepository) (string, error)
+		status string
+		// duplicate is whether the write must fail as a duplicate.
+		duplicate bool
+		want      model.Person
+	}{
+		{
+			name: "create",
+			write: func(r *PersonRepository) (string, error) {
+				person := imported
+				return "", r.Create(&person)
+			},
+			duplicate: true,
+			want:      legacy,
+		},
+		{
+			name: "import skip",
+			write: func(r *PersonRepository) (string, error) {
+				statuses, err := r.ImportPeople(context.Background(), []model.Person{imported}, ImportModeSkip, nil)
+				return statuses[legacyIIN], err
+			},
+			status: model.ImportStatusSkipped,
+			want:   legacy,
+		},
+		{
+			name: "import fail",
+			write: func(r *PersonRepository) (string, error) {
+				statuses, err := r.ImportPeople(context.Background(), []model.Person{imported}, ImportModeFail, nil)
+				return statuses[legacyIIN], err
+			},
+			status:    model.ImportStatusDuplicate,
+			duplicate: true,
+			want:      legacy,
+		},
+		{
+			name: "import upsert",
+			write: func(r *PersonRepository) (string, error) {
+				statuses, err := r.ImportPeople(context.Background(), []model.Person{imported}, ImportModeUpsert, nil)
+				return statuses[legacyIIN], err
+			},
+			status: model.ImportStatusUpdated,
+			want:   imported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestPersonRepository(t)
+			_, err := r.db.Exec(`INSERT INTO people (name, iin, phone) VALUES ($1, $2, $3)`, legacy.Name, legacy.IIN, legacy.Phone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := tt.write(r)
+			duplicate := err != nil && errors.Is(err, ErrDuplicateIIN)
+			if status != tt.status || duplicate != tt.duplicate || (err != nil && !duplicate) {
+				t.Errorf("status = %q, error = %v", status, err)
+			}
+
+			var count int
+			if err := r.db.Get(&count, `SELECT count(*) FROM people`); err != nil {
+				t.Fatal(err)
+			}
+			person, err := r.GetByIIN(legacyIIN)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 || *person != tt.want {
+				t.Errorf("people = %d, stored %+v; want one row %+v", count, *person, tt.want)
+			}
+		})
+	}
+}
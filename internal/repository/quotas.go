@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+)
+
+type QuotaRepository struct {
+	db *sqlx.DB
+}
+
+func NewQuotaRepository(db *sqlx.DB) *QuotaRepository {
+	return &QuotaRepository{db: db}
+}
+
+// Add counts requests for client on day and returns the day's total.
+func (r *QuotaRepository) Add(client string, day time.Time, requests int) (int, error) {
+	defer metrics.ObserveQuery("quota_usage.add", time.Now())
+
+	var total int
+	query := `INSERT INTO quota_usage (client, day, requests) VALUES ($1, $2::date, $3)
+		ON CONFLICT (client, day) DO UPDATE SET requests = quota_usage.requests + EXCLUDED.requests
+		RETURNING requests`
+	err := r.db.Get(&total, query, client, day.Format("2006-01-02"), requests)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count requests against quota: %w", err)
+	}
+	return total, nil
+}
+
+// PurgeBefore deletes usage counters for days before day.
+func (r *QuotaRepository) PurgeBefore(day time.Time) (int64, error) {
+	defer metrics.ObserveQuery("quota_usage.purge_before", time.Now())
+
+	result, err := r.db.Exec(`DELETE FROM quota_usage WHERE day < $1::date`, day.Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge quota usage: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// SchemaVersion is the migration version this build needs: the number of the
+// latest schema/NNNNNN_*.up.sql file. Bump it with every new migration.
+const SchemaVersion = 10
+
+// CheckSchema pings the database and checks that golang-migrate has applied
+// at least SchemaVersion and is not stuck in a failed (dirty) migration.
+func CheckSchema(ctx context.Context, db *sqlx.DB) (*model.SchemaStatus, error) {
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	status := &model.SchemaStatus{Expected: SchemaVersion}
+	err := db.QueryRowxContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&status.Version, &status.Dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "does not exist") {
+			return status, errors.New("database migrations have not been applied")
+		}
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if status.Dirty {
+		return status, fmt.Errorf("migration %d failed and left the schema dirty", status.Version)
+	}
+	if status.Version < SchemaVersion {
+		return status, fmt.Errorf("schema version %d is older than the required %d", status.Version, SchemaVersion)
+	}
+	return status, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	files, err := filepath.Glob("../../schema/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latest := 0
+	for _, file := range files {
+		number, _, _ := strings.Cut(filepath.Base(file), "_")
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			t.Fatalf("migration %s has no numeric version", file)
+		}
+		latest = max(latest, version)
+	}
+
+	if latest != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, but the latest migration is %d", SchemaVersion, latest)
+	}
+}
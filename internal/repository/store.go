@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// The store interfaces are what the rest of the service needs from the
+// database. The Postgres repositories implement them for production and the
+// Memory* types in memory.go for tests and local runs without a database;
+// both return the same error messages, which handlers compare against.
+
+// ErrDuplicateIIN is returned by PersonStore writes when a person with the
+// IIN already exists. Match it with errors.Is rather than by message.
+var ErrDuplicateIIN = errors.New("a person with this IIN already exists")
+
+type PersonStore interface {
+	Create(person *model.Person) error
+	GetByIIN(iin string) (*model.Person, error)
+	FindByNamePart(namePart string) ([]model.Person, error)
+	ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(model.Person) error) error
+	ImportPeople(ctx context.Context, people []model.Person, mode ImportMode, progress func(done int)) (map[string]string, error)
+}
+
+type IdempotencyStore interface {
+	Reserve(client, key, requestHash string) (*model.IdempotencyRecord, bool, error)
+	Save(client, key string, statusCode int, body []byte) error
+	Release(client, key string) error
+	PurgeExpired() (int64, error)
+}
+
+type JobStore interface {
+	Create(jobType, createdBy string, params model.JobParams, input []byte) (*model.Job, error)
+	GetByID(id int64) (*model.Job, error)
+	GetResult(id int64) (*model.JobResult, error)
+	GetInput(id int64) ([]byte, error)
+	Claim(ctx context.Context, staleAfter time.Duration) (*model.Job, error)
+	Heartbeat(id int64, processed, total int) (bool, error)
+	Complete(id int64, processed, total int, summary interface{}, result *model.JobResult) (bool, error)
+	Fail(id int64, reason string) (bool, error)
+	Requeue(id int64) (bool, error)
+	Cancel(id int64) (bool, error)
+}
+
+type AuditStore interface {
+	Append(events []model.AuditEvent) error
+	Find(filter model.AuditFilter) ([]model.AuditEvent, error)
+	Verify() (*model.AuditVerification, error)
+}
+
+type QuotaStore interface {
+	Add(client string, day time.Time, requests int) (int, error)
+	PurgeBefore(day time.Time) (int64, error)
+}
+
+type APIKeyStore interface {
+	Create(name, keyHash string, roles []string) (*model.APIKey, error)
+	GetActiveByHash(keyHash string) (*model.APIKey, error)
+	List() ([]model.APIKey, error)
+	Revoke(name string) error
+}
+
+var (
+	_ PersonStore      = (*PersonRepository)(nil)
+	_ IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ JobStore         = (*JobRepository)(nil)
+	_ AuditStore       = (*AuditRepository)(nil)
+	_ QuotaStore       = (*QuotaRepository)(nil)
+	_ APIKeyStore      = (*APIKeyRepository)(nil)
+
+	_ PersonStore      = (*MemoryPersonRepository)(nil)
+	_ IdempotencyStore = (*MemoryIdempotencyRepository)(nil)
+	_ JobStore         = (*MemoryJobRepository)(nil)
+	_ AuditStore       = (*MemoryAuditRepository)(nil)
+	_ QuotaStore       = (*MemoryQuotaRepository)(nil)
+	_ APIKeyStore      = (*MemoryAPIKeyRepository)(nil)
+)
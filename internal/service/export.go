@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportWriter encodes people one at a time as CSV or NDJSON, optionally
+// masking IINs, so exports never have to be held in memory.
+type ExportWriter struct {
+	format  string
+	maskIIN bool
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+	count   int
+}
+
+func NewExportWriter(w io.Writer, format string, maskIIN bool) (*ExportWriter, error) {
+	buf := bufio.NewWriter(w)
+	ew := &ExportWriter{
+		format:  format,
+		maskIIN: maskIIN,
+		buf:     buf,
+	}
+
+	switch format {
+	case ExportFormatCSV:
+		ew.csv = csv.NewWriter(buf)
+		if err := ew.csv.Write([]string{"name", "iin", "phone"}); err != nil {
+			return nil, err
+		}
+	case ExportFormatNDJSON:
+		ew.json = json.NewEncoder(buf)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	return ew, nil
+}
+
+func (ew *ExportWriter) Write(person model.Person) error {
+	if ew.maskIIN {
+		person.IIN = iin.Mask(person.IIN)
+	}
+	ew.count++
+
+	if ew.csv != nil {
+		return ew.csv.Write([]string{person.Name, person.IIN, person.Phone})
+	}
+	return ew.json.Encode(person)
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (ew *ExportWriter) Flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return ew.buf.Flush()
+}
+
+// Count returns the number of people written so far.
+func (ew *ExportWriter) Count() int {
+	return ew.count
+}
+
+// ExportContentType returns the MIME type of the given export format.
+func ExportContentType(format string) string {
+	if format == ExportFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
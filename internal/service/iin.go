@@ -1,21 +1,121 @@
 package service
 
 import (
+	"errors"
+	"time"
+
 	"github.com/toleubekov/check-iin-kaz/iin"
+	"github.com/toleubekov/check-iin-kaz/internal/metrics"
+	"github.com/toleubekov/check-iin-kaz/internal/model"
 )
 
-type IINService struct{}
+// IINService проверяет ИИН. Все проверки, от /iin_check до создания
+// и импорта людей, используют одни и те же настройки.
+type IINService struct {
+	options iin.Options
+}
 
 func NewIINService() *IINService {
 	return &IINService{}
 }
 
-// ValidateIIN оборачивает функцию из пакета iin для совместимости с существующим API
+// NewIINServiceWithOptions создает сервис, который проверяет ИИН
+// с настройками opts, например принимает номера иностранцев.
+func NewIINServiceWithOptions(opts iin.Options) *IINService {
+	return &IINService{options: opts}
+}
+
+// ValidateIIN возвращает результат проверки в формате существующего API:
+// валидность, пол и дату рождения DD.MM.YYYY. У номеров иностранцев и старых
+// номеров, если они разрешены, пол и дата пустые.
 func (s *IINService) ValidateIIN(iinStr string) (bool, string, string, error) {
-	return iin.ValidateAndExtract(iinStr)
+	info, err := iin.ValidateWithOptions(iinStr, s.options)
+	observeValidation(err)
+	if err != nil {
+		return false, "", "", err
+	}
+	return info.Valid, info.Sex, info.DateOfBirth, nil
 }
 
-// GetFullInfo возвращает полную информацию об ИИН
+func observeValidation(err error) {
+	if err != nil {
+		metrics.IINValidations.Inc("invalid", ValidationReason(err))
+	} else {
+		metrics.IINValidations.Inc("valid", "")
+	}
+}
+
+// ValidationReason возвращает короткий код причины ошибки валидации. Он
+// используется как метка метрик и как error_code в ответах API.
+func ValidationReason(err error) string {
+	switch {
+	case errors.Is(err, iin.ErrInvalidLength):
+		return "length"
+	case errors.Is(err, iin.ErrNotDigits):
+		return "not_digits"
+	case errors.Is(err, iin.ErrInvalidChecksum):
+		return "checksum"
+	case errors.Is(err, iin.ErrInvalidCentury):
+		return "century"
+	case errors.Is(err, iin.ErrInvalidMonth):
+		return "month"
+	case errors.Is(err, iin.ErrInvalidDay):
+		return "day"
+	case errors.Is(err, iin.ErrFutureBirthDate):
+		return "future_date"
+	case errors.Is(err, iin.ErrInvalidSerial), errors.Is(err, iin.ErrInvalidSerialRange):
+		return "serial"
+	default:
+		return "other"
+	}
+}
+
+// GetFullInfo возвращает полную информацию об ИИН с учетом настроек сервиса
 func (s *IINService) GetFullInfo(iinStr string) (*iin.IINInfo, error) {
-	return iin.Validate(iinStr)
+	info, err := iin.ValidateWithOptions(iinStr, s.options)
+	observeValidation(err)
+	return info, err
+}
+
+// Check возвращает все, что закодировано в ИИН (дата рождения в формате
+// YYYY-MM-DD, возраст на момент now), а для некорректного ИИН — код и текст
+// причины. Используется проверкой /v1 и gRPC.
+func (s *IINService) Check(iinStr string, now time.Time) model.IINInfoResponse {
+	info, err := s.GetFullInfo(iinStr)
+	if err != nil {
+		return model.IINInfoResponse{ErrorCode: ValidationReason(err), Error: err.Error()}
+	}
+	// В номерах иностранцев и старых номерах век не указан, поэтому нет ни
+	// пола, ни даты рождения, ни возраста.
+	if info.Category != iin.CategoryCitizen {
+		return model.IINInfoResponse{
+			Correct:      true,
+			Category:     string(info.Category),
+			SerialNumber: &info.SerialNumber,
+		}
+	}
+
+	birthDate, err := time.Parse("02.01.2006", info.DateOfBirth)
+	if err != nil {
+		return model.IINInfoResponse{ErrorCode: ValidationReason(err), Error: err.Error()}
+	}
+	age := ageAt(birthDate, now)
+	return model.IINInfoResponse{
+		Correct:      true,
+		Category:     string(info.Category),
+		Sex:          info.Sex,
+		DateOfBirth:  birthDate.Format("2006-01-02"),
+		Century:      info.Century,
+		SerialNumber: &info.SerialNumber,
+		Age:          &age,
+	}
+}
+
+// ageAt возвращает число полных лет на дату now
+func ageAt(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
 }
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/iin"
+)
+
+func TestAgeAt(t *testing.T) {
+	date := func(value string) time.Time {
+		d, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		birth string
+		now   string
+		age   int
+	}{
+		{"2003-12-31", "2003-12-31", 0},
+		{"2003-12-31", "2004-12-30", 0},
+		{"2003-12-31", "2004-12-31", 1},
+		{"1990-06-15", "2025-06-14", 34},
+		{"1990-06-15", "2025-06-15", 35},
+		{"1990-06-15", "2025-07-01", 35},
+		{"1990-06-15", "2025-05-31", 34},
+		// Someone born on 29 February turns a year older on 1 March in
+		// common years and on 29 February in leap years.
+		{"2000-02-29", "2001-02-28", 0},
+		{"2000-02-29", "2001-03-01", 1},
+		{"2000-02-29", "2004-02-28", 3},
+		{"2000-02-29", "2004-02-29", 4},
+		{"2000-02-29", "2100-02-28", 99},
+		{"2000-02-29", "2100-03-01", 100},
+		{"1899-01-01", "2025-01-01", 126},
+	}
+
+	for _, tt := range tests {
+		if age := ageAt(date(tt.birth), date(tt.now)); age != tt.age {
+			t.Errorf("ageAt(%s, %s) = %d, want %d", tt.birth, tt.now, age, tt.age)
+		}
+	}
+}
+
+func TestCheckAge(t *testing.T) {
+	service := NewIINService()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	result := service.Check("031231500126", now)
+	if !result.Correct || result.DateOfBirth != "2003-12-31" || result.Age == nil || *result.Age != 21 {
+		t.Errorf("Check = %+v, want born 2003-12-31 and 21 years old", result)
+	}
+
+	result = service.Check("031231500127", now)
+	if result.Correct || result.Age != nil || result.ErrorCode != "checksum" {
+		t.Errorf("Check of a bad checksum = %+v", result)
+	}
+}
+
+func TestCheckNonCitizen(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		iin      string
+		category string
+	}{
+		{"900101000107", "foreign"},
+		{"900101700101", "unknown"},
+	}
+
+	for _, tt := range tests {
+		// By default the service keeps rejecting these numbers.
+		result := NewIINService().Check(tt.iin, now)
+		if result.Correct || result.ErrorCode != "century" {
+			t.Errorf("Check(%s) = %+v, want a century error", tt.iin, result)
+		}
+
+		result = NewIINServiceWithOptions(iin.Options{AllowNonCitizen: true}).Check(tt.iin, now)
+		if !result.Correct || result.Category != tt.category || result.SerialNumber == nil || *result.SerialNumber != 10 {
+			t.Errorf("Check(%s) = %+v, want a correct %s IIN", tt.iin, result, tt.category)
+		}
+		if result.Sex != "" || result.DateOfBirth != "" || result.Century != 0 || result.Age != nil {
+			t.Errorf("Check(%s) = %+v, want no sex, date of birth or age", tt.iin, result)
+		}
+
+		// The checks behind create, import and the legacy routes agree.
+		if correct, sex, _, err := NewIINServiceWithOptions(iin.Options{AllowNonCitizen: true}).ValidateIIN(tt.iin); !correct || sex != "" || err != nil {
+			t.Errorf("ValidateIIN(%s) = %t, %q, %v, want correct without sex", tt.iin, correct, sex, err)
+		}
+	}
+}
+
+func TestValidationReasonSerial(t *testing.T) {
+	_, err := iin.ValidateWithOptions("900101300017", iin.Options{SerialRanges: []iin.SerialRange{{Min: 1000, Max: 9999}}})
+	if reason := ValidationReason(err); reason != "serial" {
+		t.Errorf("ValidationReason(%v) = %q, want serial", err, reason)
+	}
+	_, err = iin.ValidateWithOptions("900101300017", iin.Options{SerialRanges: []iin.SerialRange{{Min: 9, Max: 1}}})
+	if reason := ValidationReason(err); reason != "serial" {
+		t.Errorf("ValidationReason(%v) = %q, want serial", err, reason)
+	}
+}
+
+func TestCheckSerialZero(t *testing.T) {
+	result := NewIINService().Check("900101300007", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Correct || !strings.Contains(string(data), `"serial_number":0`) {
+		t.Errorf("Check = %s, want serial number 0", data)
+	}
+}
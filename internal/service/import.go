@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+	"github.com/toleubekov/check-iin-kaz/internal/repository"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// ImportRecord is a single parsed row of an import file. Row is 1-based and
+// counts data rows only, so a CSV header is not included.
+type ImportRecord struct {
+	Row    int
+	Person model.Person
+	Err    error
+}
+
+// ParseImport reads people from CSV (with a name,iin,phone header in any order)
+// or NDJSON. Malformed rows are returned with Err set instead of failing the whole file.
+func ParseImport(r io.Reader, format string) ([]ImportRecord, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCSV(r)
+	case ImportFormatNDJSON:
+		return parseNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+func parseCSV(r io.Reader) ([]ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("import file is empty")
+		}
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "iin", "phone"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must contain column %q", required)
+		}
+	}
+
+	var records []ImportRecord
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		record := ImportRecord{Row: row}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			record.Err = fmt.Errorf("malformed CSV row: %v", parseErr.Err)
+		} else if len(fields) != len(header) {
+			record.Err = fmt.Errorf("expected %d columns, got %d", len(header), len(fields))
+		} else {
+			record.Person = model.Person{
+				Name:  strings.TrimSpace(fields[columns["name"]]),
+				IIN:   strings.TrimSpace(fields[columns["iin"]]),
+				Phone: strings.TrimSpace(fields[columns["phone"]]),
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseNDJSON(r io.Reader) ([]ImportRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []ImportRecord
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row++
+
+		record := ImportRecord{Row: row}
+		if err := json.Unmarshal([]byte(line), &record.Person); err != nil {
+			record.Err = fmt.Errorf("malformed JSON row: %v", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("import file is empty")
+	}
+	return records, nil
+}
+
+type PersonImporter struct {
+	iinService *IINService
+	repo       repository.PersonStore
+}
+
+func NewPersonImporter(iinService *IINService, repo repository.PersonStore) *PersonImporter {
+	return &PersonImporter{
+		iinService: iinService,
+		repo:       repo,
+	}
+}
+
+// Import validates every record's IIN and phone and stores the valid ones using
+// the given mode. In ImportModeFail any invalid or duplicate row aborts the whole
+// import; otherwise invalid rows are reported and the rest are stored.
+// If progress is not nil it receives the number of valid rows written so far.
+func (i *PersonImporter) Import(ctx context.Context, records []ImportRecord, mode repository.ImportMode, progress func(done int)) (*model.ImportReport, error) {
+	report := &model.ImportReport{
+		Mode:  string(mode),
+		Total: len(records),
+		Rows:  make([]model.ImportRowResult, len(records)),
+	}
+
+	var valid []model.Person
+	validRows := map[string]int{}
+	for idx, record := range records {
+		result := model.ImportRowResult{Row: record.Row, IIN: record.Person.IIN}
+
+		err := record.Err
+		if err == nil {
+			err = i.validate(&record.Person)
+		}
+
+		switch {
+		case err != nil:
+			result.Status = model.ImportStatusInvalid
+			result.Error = err.Error()
+			report.Invalid++
+		case validRows[record.Person.IIN] != 0:
+			result.Status = model.ImportStatusDuplicate
+			result.Error = fmt.Sprintf("IIN already appears in row %d", validRows[record.Person.IIN])
+			report.Skipped++
+		default:
+			validRows[record.Person.IIN] = record.Row
+			valid = append(valid, record.Person)
+		}
+		report.Rows[idx] = result
+	}
+
+	if mode == repository.ImportModeFail && report.Invalid+report.Skipped > 0 {
+		return abortImport(report, "import contains invalid or duplicate rows"), nil
+	}
+
+	statuses, err := i.repo.ImportPeople(ctx, valid, mode, progress)
+	if err != nil {
+		if mode == repository.ImportModeFail && errors.Is(err, repository.ErrDuplicateIIN) {
+			for idx, row := range report.Rows {
+				if statuses[row.IIN] == model.ImportStatusDuplicate {
+					report.Rows[idx].Status = model.ImportStatusDuplicate
+					report.Rows[idx].Error = err.Error()
+				}
+			}
+			return abortImport(report, err.Error()), nil
+		}
+		return nil, err
+	}
+
+	for idx, row := range report.Rows {
+		if row.Status != "" {
+			continue
+		}
+
+		status := statuses[row.IIN]
+		report.Rows[idx].Status = status
+		switch status {
+		case model.ImportStatusCreated:
+			report.Created++
+		case model.ImportStatusUpdated:
+			report.Updated++
+		case model.ImportStatusSkipped:
+			report.Rows[idx].Error = repository.ErrDuplicateIIN.Error()
+			report.Skipped++
+		}
+	}
+
+	report.Success = true
+	return report, nil
+}
+
+func (i *PersonImporter) validate(person *model.Person) error {
+	if strings.TrimSpace(person.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	correct, _, _, err := i.iinService.ValidateIIN(person.IIN)
+	if !correct || err != nil {
+		if err != nil {
+			return fmt.Errorf("invalid IIN: %v", err)
+		}
+		return errors.New("invalid IIN")
+	}
+
+	normalizedPhone, err := NormalizePhone(person.Phone)
+	if err != nil {
+		return fmt.Errorf("invalid phone: %v", err)
+	}
+	person.Phone = normalizedPhone
+
+	return nil
+}
+
+// abortImport marks every row that was not already rejected as failed, since
+// nothing from an aborted import is stored.
+func abortImport(report *model.ImportReport, reason string) *model.ImportReport {
+	for idx, row := range report.Rows {
+		if row.Status == "" {
+			report.Rows[idx].Status = model.ImportStatusFailed
+		}
+	}
+	report.Success = false
+	report.Errors = reason
+	return report
+}
@@ -0,0 +1,13 @@
+package service
+
+import "github.com/toleubekov/check-iin-kaz/phone"
+
+// NormalizePhone приводит телефон человека к формату E.164. Телефон
+// необязателен: пустая строка возвращается как есть, как это было до
+// появления проверки телефонов.
+func NormalizePhone(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return phone.Normalize(value)
+}
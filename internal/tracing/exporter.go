@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const scopeName = "github.com/toleubekov/check-iin-kaz"
+
+// JSONExporter writes each span as one OTLP/JSON ExportTraceServiceRequest
+// per line.
+type JSONExporter struct {
+	serviceName string
+	mu          sync.Mutex
+	w           io.Writer
+}
+
+func NewJSONExporter(w io.Writer, serviceName string) *JSONExporter {
+	return &JSONExporter{w: w, serviceName: serviceName}
+}
+
+// OpenFileExporter appends spans to the file at path; close the returned
+// file on shutdown.
+func OpenFileExporter(path, serviceName string) (*JSONExporter, *os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return NewJSONExporter(file, serviceName), file, nil
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+func (e *JSONExporter) Export(span *Span) {
+	out := otlpSpan{
+		TraceID:           hex.EncodeToString(span.Context.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.Context.SpanID[:]),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        attributes(span.Attributes),
+	}
+	if span.Parent != [8]byte{} {
+		out.ParentSpanID = hex.EncodeToString(span.Parent[:])
+	}
+	if span.Error != "" {
+		out.Status = otlpStatus{Code: 2, Message: span.Error}
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": attributes(map[string]interface{}{"service.name": e.serviceName}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": scopeName},
+				"spans": []otlpSpan{out},
+			}},
+		}},
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode span: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		log.Printf("ERROR: Failed to export span: %v", err)
+	}
+}
+
+// attributes converts a map to OTLP key-values, sorted by key.
+func attributes(m map[string]interface{}) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(m))
+	for key, value := range m {
+		var v map[string]interface{}
+		switch value := value.(type) {
+		case string:
+			v = map[string]interface{}{"stringValue": value}
+		case bool:
+			v = map[string]interface{}{"boolValue": value}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": value}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+		}
+		result = append(result, otlpKeyValue{Key: key, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,164 @@
+// Package tracing propagates W3C Trace Context and records spans.
+//
+// Incoming traceparent headers are parsed so that the server's spans join
+// the caller's trace. Finished spans are written by an Exporter; the one
+// provided here writes OTLP/JSON lines, the format read by the OpenTelemetry
+// Collector's otlpjsonfile receiver, to stdout or a file.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// ParseTraceparent parses a W3C traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(header string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, errors.New("malformed traceparent")
+	}
+	// Version ff is forbidden; newer versions may append fields, older ones may not.
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, errors.New("unsupported traceparent version")
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, errors.New("malformed trace id")
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, errors.New("malformed span id")
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, errors.New("malformed trace flags")
+	}
+	if sc.TraceID == [16]byte{} || sc.SpanID == [8]byte{} {
+		return sc, errors.New("trace id and span id must not be zero")
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// Traceparent formats the span context as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceIDString(), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+func (sc SpanContext) TraceIDString() string {
+	return hex.EncodeToString(sc.TraceID[:])
+}
+
+// Span kinds, numbered as in OTLP.
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+)
+
+// Span is a timed operation. Its fields are read by exporters after Finish.
+type Span struct {
+	Name       string
+	Kind       int
+	Context    SpanContext
+	Parent     [8]byte // zero for a root span
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Error      string
+
+	tracer *Tracer
+	once   sync.Once
+}
+
+// SetAttribute records a key-value pair on the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.Error = message
+}
+
+// Finish ends the span and hands it to the exporter if it is sampled.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.End = time.Now()
+		if s.Context.Sampled && s.tracer.exporter != nil {
+			s.tracer.exporter.Export(s)
+		}
+	})
+}
+
+// Exporter receives finished spans.
+type Exporter interface {
+	Export(span *Span)
+}
+
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer creates a Tracer. With a nil exporter, spans still carry ids for
+// propagation and log correlation but are not recorded.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+type spanKey struct{}
+
+// Start begins a span as a child of the span in ctx or, failing that, of
+// remote, the context received from the caller (zero if there was none).
+func (t *Tracer) Start(ctx context.Context, name string, remote SpanContext) (context.Context, *Span) {
+	span := &Span{Name: name, Kind: SpanKindInternal, Start: time.Now(), Attributes: map[string]interface{}{}, tracer: t}
+
+	parent := remote
+	if current := SpanFromContext(ctx); current != nil {
+		parent = current.Context
+	}
+
+	if parent.TraceID != [16]byte{} {
+		span.Context.TraceID = parent.TraceID
+		span.Context.Sampled = parent.Sampled
+		span.Parent = parent.SpanID
+	} else {
+		rand.Read(span.Context.TraceID[:])
+		span.Context.Sampled = t.exporter != nil
+	}
+	rand.Read(span.Context.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
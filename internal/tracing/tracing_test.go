@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled || sc.TraceIDString() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("ParseTraceparent = %+v", sc)
+	}
+	if sc.Traceparent() != header {
+		t.Errorf("Traceparent = %q, want %q", sc.Traceparent(), header)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("ParseTraceparent(%q) succeeded", invalid)
+		}
+	}
+
+	// Later versions may append fields.
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"); err != nil {
+		t.Errorf("ParseTraceparent with a future version: %v", err)
+	}
+}
+
+func TestSpanJoinsRemoteTrace(t *testing.T) {
+	var out bytes.Buffer
+	tracer := NewTracer(NewJSONExporter(&out, "test-service"))
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span := tracer.Start(context.Background(), "GET /iin_check/{iin}", remote)
+	_, child := tracer.Start(ctx, "validate", SpanContext{})
+	child.Finish()
+	span.SetAttribute("http.response.status_code", 200)
+	span.Finish()
+
+	if child.Context.TraceID != remote.TraceID || child.Parent != span.Context.SpanID || span.Parent != remote.SpanID {
+		t.Error("spans are not linked to the remote parent")
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("exported %d lines, want 2", len(lines))
+	}
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(lines[1], &request); err != nil {
+		t.Fatal(err)
+	}
+	exported := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if exported.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || exported.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("exported span = %+v", exported)
+	}
+	if len(exported.Attributes) != 1 || exported.Attributes[0].Value["intValue"] != "200" {
+		t.Errorf("exported attributes = %+v", exported.Attributes)
+	}
+}
+
+func TestUnsampledParentIsNotExported(t *testing.T) {
+	var out bytes.Buffer
+	tracer := NewTracer(NewJSONExporter(&out, "test-service"))
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(context.Background(), "request", remote)
+	span.Finish()
+
+	if out.Len() != 0 {
+		t.Errorf("exported an unsampled span: %s", out.String())
+	}
+}
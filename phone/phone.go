@@ -0,0 +1,226 @@
+// Package phone предоставляет функции для валидации и нормализации телефонных номеров Республики Казахстан.
+//
+// Пакет принимает номера в международном (+7 701 123 45 67), внутреннем
+// (8 701 123 45 67) и национальном (701 123 45 67) форматах, приводит их
+// к формату E.164 и определяет оператора мобильной связи или город.
+//
+// Основные функции:
+//
+//	Validate(phone)  - полная валидация с извлечением всей информации
+//	IsValid(phone)   - быстрая проверка корректности номера
+//	Normalize(phone) - приведение номера к формату E.164
+//
+// Пример использования:
+//
+//	info, err := phone.Validate("8 (701) 123-45-67")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("Номер: %s, Оператор: %s\n", info.E164, info.Operator)
+//
+// Формат национального номера: 7XXNNNNNNN где:
+//   - 7XX: код оператора мобильной связи (700, 701, 702, 705, ...)
+//   - 7XXX или 7XX: код города для стационарных номеров (7172, 727, ...)
+//   - остальные цифры: номер абонента
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// Типы телефонных номеров.
+const (
+	TypeMobile   = "mobile"
+	TypeLandline = "landline"
+)
+
+// PhoneInfo содержит информацию, извлеченную из телефонного номера.
+//
+// Все поля заполняются только при успешной валидации номера.
+type PhoneInfo struct {
+	Valid    bool   `json:"valid"`
+	E164     string `json:"e164,omitempty"`      // формат +7XXXXXXXXXX
+	Type     string `json:"type,omitempty"`      // "mobile" или "landline"
+	Operator string `json:"operator,omitempty"`  // оператор мобильной связи
+	AreaCode string `json:"area_code,omitempty"` // код оператора или города
+	City     string `json:"city,omitempty"`      // город для стационарных номеров
+}
+
+// mobileOperators сопоставляет коды мобильных операторов Казахстана с названиями операторов.
+var mobileOperators = map[string]string{
+	"700": "Altel",
+	"701": "Kcell",
+	"702": "Kcell",
+	"705": "Beeline",
+	"706": "Beeline",
+	"707": "Tele2",
+	"708": "Altel",
+	"747": "Tele2",
+	"771": "Beeline",
+	"775": "Kcell",
+	"776": "Beeline",
+	"777": "Beeline",
+	"778": "Kcell",
+}
+
+// cityCodes сопоставляет коды городов Казахстана с названиями городов.
+var cityCodes = map[string]string{
+	"7102": "Жезказган",
+	"7112": "Уральск",
+	"7122": "Атырау",
+	"7132": "Актобе",
+	"7142": "Костанай",
+	"7152": "Петропавловск",
+	"7162": "Кокшетау",
+	"7172": "Астана",
+	"7182": "Павлодар",
+	"7212": "Караганда",
+	"7213": "Темиртау",
+	"7222": "Семей",
+	"7232": "Усть-Каменогорск",
+	"7242": "Кызылорда",
+	"7252": "Шымкент",
+	"7262": "Тараз",
+	"727":  "Алматы",
+	"7282": "Талдыкорган",
+	"7292": "Актау",
+}
+
+// Validate проверяет корректность телефонного номера Казахстана и извлекает всю доступную информацию.
+//
+// Функция выполняет:
+//   - Удаление пробелов, дефисов, точек и скобок
+//   - Распознавание префикса (+7, 7, 8 или без префикса)
+//   - Проверку длины национального номера (10 цифр)
+//   - Определение оператора мобильной связи или кода города
+//
+// Возвращает указатель на PhoneInfo и ошибку. При успешной валидации
+// поле Valid будет true, а поле E164 содержит нормализованный номер.
+//
+// Пример:
+//
+//	info, err := phone.Validate("+7 (777) 123-45-67")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Printf("Номер: %s, Оператор: %s\n", info.E164, info.Operator)
+func Validate(phone string) (*PhoneInfo, error) {
+	info := &PhoneInfo{}
+
+	national, err := nationalNumber(phone)
+	if err != nil {
+		return info, err
+	}
+
+	if national[0] != '7' {
+		return info, errors.New("номер телефона не принадлежит Казахстану")
+	}
+
+	switch national[1] {
+	case '0', '4', '7':
+		code := national[:3]
+		operator, ok := mobileOperators[code]
+		if !ok {
+			return info, errors.New("неизвестный код оператора мобильной связи")
+		}
+		info.Type = TypeMobile
+		info.Operator = operator
+		info.AreaCode = code
+	case '1', '2':
+		code, city, ok := lookupCityCode(national)
+		if !ok {
+			return info, errors.New("неизвестный код города")
+		}
+		info.Type = TypeLandline
+		info.City = city
+		info.AreaCode = code
+	default:
+		return info, errors.New("номер телефона не принадлежит Казахстану")
+	}
+
+	info.Valid = true
+	info.E164 = "+7" + national
+
+	return info, nil
+}
+
+// IsValid выполняет быструю проверку корректности телефонного номера Казахстана.
+//
+// Пример:
+//
+//	if phone.IsValid("87011234567") {
+//	    fmt.Println("Номер корректный")
+//	}
+func IsValid(phone string) bool {
+	info, err := Validate(phone)
+	return err == nil && info.Valid
+}
+
+// Normalize проверяет телефонный номер и возвращает его в формате E.164 (+7XXXXXXXXXX).
+//
+// Пример:
+//
+//	normalized, err := phone.Normalize("8 (701) 123-45-67")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(normalized) // Выведет: +77011234567
+func Normalize(phone string) (string, error) {
+	info, err := Validate(phone)
+	if err != nil {
+		return "", err
+	}
+
+	return info.E164, nil
+}
+
+// nationalNumber удаляет форматирование и префикс страны, возвращая 10-значный национальный номер
+func nationalNumber(phone string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.', '\t':
+			return -1
+		}
+		return r
+	}, phone)
+
+	if cleaned == "" {
+		return "", errors.New("номер телефона не может быть пустым")
+	}
+
+	international := strings.HasPrefix(cleaned, "+")
+	digits := strings.TrimPrefix(cleaned, "+")
+
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return "", errors.New("номер телефона должен состоять только из цифр")
+		}
+	}
+
+	switch {
+	case international:
+		if len(digits) != 11 || digits[0] != '7' {
+			return "", errors.New("номер телефона должен начинаться с +7 и содержать 11 цифр")
+		}
+		return digits[1:], nil
+	case len(digits) == 11 && (digits[0] == '8' || digits[0] == '7'):
+		return digits[1:], nil
+	case len(digits) == 10:
+		return digits, nil
+	default:
+		return "", errors.New("некорректная длина номера телефона")
+	}
+}
+
+// lookupCityCode ищет код города по самому длинному совпадающему префиксу
+func lookupCityCode(national string) (string, string, bool) {
+	for _, length := range []int{4, 3} {
+		code := national[:length]
+		if city, ok := cityCodes[code]; ok {
+			return code, city, true
+		}
+	}
+
+	return "", "", false
+}
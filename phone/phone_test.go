@@ -0,0 +1,89 @@
+package phone_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/toleubekov/check-iin-kaz/phone"
+)
+
+// ExampleValidate демонстрирует основное использование функции Validate
+func ExampleValidate() {
+	info, err := phone.Validate("+7 (701) 123-45-67")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Валидный: %t\n", info.Valid)
+	fmt.Printf("Номер: %s\n", info.E164)
+	fmt.Printf("Тип: %s\n", info.Type)
+	fmt.Printf("Оператор: %s\n", info.Operator)
+	// Output:
+	// Валидный: true
+	// Номер: +77011234567
+	// Тип: mobile
+	// Оператор: Kcell
+}
+
+// ExampleValidate_landline показывает валидацию стационарного номера
+func ExampleValidate_landline() {
+	info, err := phone.Validate("8 (7172) 55-12-34")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Номер: %s\n", info.E164)
+	fmt.Printf("Тип: %s\n", info.Type)
+	fmt.Printf("Город: %s\n", info.City)
+	// Output:
+	// Номер: +77172551234
+	// Тип: landline
+	// Город: Астана
+}
+
+// ExampleNormalize показывает приведение номеров к формату E.164
+func ExampleNormalize() {
+	for _, number := range []string{"87771234567", "+7 727 123 45 67", "705 123 45 67"} {
+		normalized, err := phone.Normalize(number)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(normalized)
+	}
+	// Output:
+	// +77771234567
+	// +77271234567
+	// +77051234567
+}
+
+// ExampleIsValid демонстрирует быструю проверку номера
+func ExampleIsValid() {
+	// Казахстанский мобильный номер
+	fmt.Println(phone.IsValid("+77071234567"))
+
+	// Российский мобильный номер
+	fmt.Println(phone.IsValid("+79161234567"))
+
+	// Output:
+	// true
+	// false
+}
+
+// ExampleValidate_errorHandling показывает обработку ошибок
+func ExampleValidate_errorHandling() {
+	// Недостаточно цифр
+	_, err := phone.Validate("+7123456789")
+	if err != nil {
+		fmt.Printf("Ошибка: %s\n", err.Error())
+	}
+
+	// Неизвестный код оператора
+	_, err = phone.Validate("+77031234567")
+	if err != nil {
+		fmt.Printf("Ошибка: %s\n", err.Error())
+	}
+
+	// Output:
+	// Ошибка: номер телефона должен начинаться с +7 и содержать 11 цифр
+	// Ошибка: неизвестный код оператора мобильной связи
+}
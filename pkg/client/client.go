@@ -0,0 +1,401 @@
+// Package client is a typed Go client for the check-iin-kaz HTTP API.
+//
+// Its methods follow the operations of the OpenAPI description served by
+// the server at /openapi.json:
+//
+//	c := client.New("http://localhost:8080", os.Getenv("API_KEY"))
+//	result, err := c.CheckIIN(ctx, "030812550926")
+//
+// Every non-2xx response is returned as an *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toleubekov/check-iin-kaz/internal/model"
+)
+
+// The request and response types are those of the server.
+type (
+	Person            = model.Person
+	IINInfoResponse   = model.IINInfoResponse
+	PersonResponse    = model.PersonResponse
+	ImportReport      = model.ImportReport
+	ImportRowResult   = model.ImportRowResult
+	Job               = model.Job
+	JobParams         = model.JobParams
+	IINCheckResult    = model.IINCheckResult
+	AuditEvent        = model.AuditEvent
+	AuditVerification = model.AuditVerification
+)
+
+const (
+	apiVersionPrefix = "/v1"
+	defaultTimeout   = 30 * time.Second
+)
+
+type Client struct {
+	// BaseURL is the server address, e.g. "http://localhost:8080".
+	BaseURL string
+	// APIKey is sent as X-API-Key.
+	APIKey string
+	// Token, if set instead of an API key, is sent as a JWT bearer token.
+	Token string
+	// HTTPClient sends the requests. New sets one with a 30s timeout;
+	// exports and job results can take longer and may need their own.
+	HTTPClient *http.Client
+}
+
+// New creates a client that authenticates with apiKey; leave it empty for a
+// server running with AUTH_ENABLED=false.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Error is a non-2xx response.
+type Error struct {
+	StatusCode int
+	// Message is the errors field of the response body, or the status text
+	// if the body had none.
+	Message   string
+	RequestID string
+	// RetryAfter is set on 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%d %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+// CheckIIN validates an IIN. An invalid IIN is not an error: the result has
+// Correct set to false and ErrorCode saying why.
+func (c *Client) CheckIIN(ctx context.Context, iin string) (*IINInfoResponse, error) {
+	var result IINInfoResponse
+	if err := c.getJSON(ctx, "/iin_check/"+url.PathEscape(iin), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreatePerson stores a person. With a non-empty idempotencyKey, retrying
+// the same call is safe: the server replays the first response.
+func (c *Client) CreatePerson(ctx context.Context, person Person, idempotencyKey string) error {
+	body, err := json.Marshal(person)
+	if err != nil {
+		return fmt.Errorf("failed to marshal person: %w", err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/people/info", nil, header, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+func (c *Client) GetPersonByIIN(ctx context.Context, iin string) (*Person, error) {
+	var person Person
+	if err := c.getJSON(ctx, "/people/info/iin/"+url.PathEscape(iin), nil, &person); err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+func (c *Client) FindPeopleByNamePart(ctx context.Context, namePart string) ([]Person, error) {
+	var people []Person
+	if err := c.getJSON(ctx, "/people/info/name/"+url.PathEscape(namePart), nil, &people); err != nil {
+		return nil, err
+	}
+	return people, nil
+}
+
+// ImportPeople uploads CSV or NDJSON (format "csv" or "ndjson") in the given
+// mode ("skip", "upsert" or "fail", empty for the server default). A report
+// with invalid rows is returned without an error; check its Success field.
+func (c *Client) ImportPeople(ctx context.Context, data io.Reader, format, mode string) (*ImportReport, error) {
+	query := url.Values{"format": {format}}
+	if mode != "" {
+		query.Set("mode", mode)
+	}
+	header := http.Header{"Content-Type": {contentType(format)}}
+
+	resp, err := c.do(ctx, http.MethodPost, "/people/import", query, header, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 422 carries a report of the invalid rows rather than an error envelope.
+	var report ImportReport
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		err = json.NewDecoder(resp.Body).Decode(&report)
+		if err != nil {
+			err = fmt.Errorf("failed to decode response: %w", err)
+		}
+	} else {
+		err = decodeResponse(resp, &report)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ExportOptions selects what ExportPeople returns.
+type ExportOptions struct {
+	// Format is "csv" or "ndjson"; empty means ndjson.
+	Format string
+	// Name keeps only people whose name contains it.
+	Name    string
+	MaskIIN bool
+}
+
+// ExportPeople streams the export; the caller must close the returned body.
+func (c *Client) ExportPeople(ctx context.Context, opts ExportOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	if opts.MaskIIN {
+		query.Set("mask_iin", "true")
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/people/export", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// CreateJob queues a job of the given type ("import", "validate" or
+// "export"). Import and validate jobs read their input from data, which is
+// nil for exports.
+func (c *Client) CreateJob(ctx context.Context, jobType string, params JobParams, data io.Reader) (*Job, error) {
+	query := url.Values{"type": {jobType}}
+	if params.Mode != "" {
+		query.Set("mode", params.Mode)
+	}
+	if params.Format != "" {
+		query.Set("format", params.Format)
+	}
+	if params.Name != "" {
+		query.Set("name", params.Name)
+	}
+	if params.MaskIIN {
+		query.Set("mask_iin", "true")
+	}
+	var header http.Header
+	if data != nil {
+		header = http.Header{"Content-Type": {contentType(params.Format)}}
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/jobs", query, header, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var job Job
+	if err := decodeResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id int64) (*Job, error) {
+	var job Job
+	if err := c.getJSON(ctx, "/jobs/"+strconv.FormatInt(id, 10), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobResult downloads the result of a finished job together with its
+// content type, which depends on the job type.
+func (c *Client) GetJobResult(ctx context.Context, id int64) ([]byte, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/jobs/"+strconv.FormatInt(id, 10)+"/result", nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, "", err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read job result: %w", err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+func (c *Client) CancelJob(ctx context.Context, id int64) (*Job, error) {
+	resp, err := c.do(ctx, http.MethodDelete, "/jobs/"+strconv.FormatInt(id, 10), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var job Job
+	if err := decodeResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// AuditQuery filters FindAuditEvents; zero fields are not applied.
+type AuditQuery struct {
+	IIN    string
+	Actor  string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+func (c *Client) FindAuditEvents(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	query := url.Values{}
+	if q.IIN != "" {
+		query.Set("iin", q.IIN)
+	}
+	if q.Actor != "" {
+		query.Set("actor", q.Actor)
+	}
+	if q.Action != "" {
+		query.Set("action", q.Action)
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	var events []AuditEvent
+	if err := c.getJSON(ctx, "/audit", query, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (c *Client) VerifyAuditLog(ctx context.Context) (*AuditVerification, error) {
+	var result AuditVerification
+	if err := c.getJSON(ctx, "/audit/verify", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, v)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader) (*http.Response, error) {
+	target := c.BaseURL + apiVersionPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	switch {
+	case c.APIKey != "":
+		req.Header.Set("X-API-Key", c.APIKey)
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+func decodeResponse(resp *http.Response, v interface{}) error {
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// checkResponse turns a non-2xx response into an *Error, reading the
+// message from the PersonResponse envelope the server uses for errors.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	var envelope PersonResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err == nil {
+		if envelope.Errors != "" {
+			apiErr.Message = envelope.Errors
+		}
+		if envelope.RequestID != "" {
+			apiErr.RequestID = envelope.RequestID
+		}
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+func contentType(format string) string {
+	if format == "csv" {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckIIN(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/iin_check/030812550926" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("X-API-Key") != "ik_test" {
+			t.Errorf("X-API-Key = %q", r.Header.Get("X-API-Key"))
+		}
+		json.NewEncoder(w).Encode(IINInfoResponse{Correct: true, Sex: "male", DateOfBirth: "2003-12-08"})
+	}))
+	defer server.Close()
+
+	result, err := New(server.URL, "ik_test").CheckIIN(context.Background(), "030812550926")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Correct || result.Sex != "male" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Idempotency-Key") != "retry-1" {
+			t.Errorf("Idempotency-Key = %q", r.Header.Get("Idempotency-Key"))
+		}
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(PersonResponse{Errors: "Rate limit exceeded", RequestID: "abc"})
+	}))
+	defer server.Close()
+
+	err := New(server.URL, "").CreatePerson(context.Background(), Person{Name: "Test"}, "retry-1")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "Rate limit exceeded" ||
+		apiErr.RequestID != "abc" || apiErr.RetryAfter != 3*time.Second {
+		t.Errorf("err = %+v", apiErr)
+	}
+}
@@ -0,0 +1,3 @@
+ALTER TABLE jobs DROP COLUMN IF EXISTS created_by;
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
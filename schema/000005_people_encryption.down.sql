@@ -0,0 +1,13 @@
+-- Rows created after encryption was enabled have no plaintext IIN, so
+-- restoring NOT NULL fails until they are decrypted back into iin/phone.
+ALTER TABLE people
+    ALTER COLUMN iin SET NOT NULL,
+    ALTER COLUMN phone SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_people_iin_hmac;
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS iin_hmac,
+    DROP COLUMN IF EXISTS iin_enc,
+    DROP COLUMN IF EXISTS phone_enc,
+    DROP COLUMN IF EXISTS key_id;
@@ -0,0 +1,12 @@
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS iin_hmac BYTEA,
+    ADD COLUMN IF NOT EXISTS iin_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS phone_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+-- Existing rows keep their plaintext until cmd/reencrypt encrypts them.
+ALTER TABLE people
+    ALTER COLUMN iin DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_people_iin_hmac ON people(iin_hmac);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS quota_usage (
+    client VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (client, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_usage_day ON quota_usage(day);
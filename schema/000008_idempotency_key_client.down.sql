@@ -0,0 +1,6 @@
+-- Keys of different clients may collide once the client is dropped; they are
+-- only a retry cache, so they are discarded.
+DELETE FROM idempotency_keys;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS client;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
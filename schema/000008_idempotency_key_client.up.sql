@@ -0,0 +1,7 @@
+-- Idempotency keys are chosen by clients, so the same key from two clients
+-- must not share a stored response. Existing keys get an empty client that
+-- no caller has and simply expire.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS client VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN client DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (client, key);
@@ -0,0 +1,5 @@
+-- A request that touches several people (a search, for example) is recorded
+-- as one event with all their IIN hashes instead of one event per person.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS iin_hashes TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_iin_hashes ON audit_log USING GIN (iin_hashes);
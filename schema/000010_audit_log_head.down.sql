@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_log_head;
+DROP FUNCTION IF EXISTS audit_log_head_keep();
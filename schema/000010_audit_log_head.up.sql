@@ -0,0 +1,29 @@
+-- audit_log_head records the last event of the hash chain. Deleting events
+-- from the end of audit_log leaves a chain that verifies on its own; checking
+-- that it still ends at the head catches that.
+CREATE TABLE IF NOT EXISTS audit_log_head (
+    singleton BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (singleton),
+    last_id BIGINT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    -- Events from this id on include their id in the hash; the ones written
+    -- before this migration do not.
+    id_hashed_from BIGINT NOT NULL
+);
+
+INSERT INTO audit_log_head (last_id, hash, id_hashed_from)
+(SELECT id, hash, id + 1 FROM audit_log ORDER BY id DESC LIMIT 1)
+ON CONFLICT (singleton) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION audit_log_head_keep() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log_head cannot be deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_head_no_delete
+    BEFORE DELETE ON audit_log_head
+    FOR EACH ROW EXECUTE FUNCTION audit_log_head_keep();
+
+CREATE TRIGGER audit_log_head_no_truncate
+    BEFORE TRUNCATE ON audit_log_head
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_head_keep();